@@ -153,9 +153,12 @@ type RenameColumn struct {
 	NewName   string
 }
 
-// Clause returns a CHANGE COLUMN clause of an ALTER TABLE statement.
-func (rc RenameColumn) Clause(_ StatementModifiers) string {
-	panic(fmt.Errorf("Rename Column not yet supported"))
+// Clause returns a CHANGE COLUMN clause of an ALTER TABLE statement. The
+// column's definition is otherwise unchanged.
+func (rc RenameColumn) Clause(mods StatementModifiers) string {
+	newCol := *rc.OldColumn
+	newCol.Name = rc.NewName
+	return fmt.Sprintf("CHANGE COLUMN %s %s", EscapeIdentifier(rc.OldColumn.Name), newCol.Definition(mods.Flavor, nil))
 }
 
 // Unsafe returns true if this clause is potentially destructive of data.
@@ -422,6 +425,23 @@ func (ccs ChangeCharSet) Clause(_ StatementModifiers) string {
 
 ///// ChangeCreateOptions //////////////////////////////////////////////////////
 
+// createOptionDefaults maps create options to their known default values,
+// which cause the option to no longer show up in create_options or SHOW CREATE
+// TABLE.
+var createOptionDefaults = map[string]string{
+	"MIN_ROWS":           "0",
+	"MAX_ROWS":           "0",
+	"AVG_ROW_LENGTH":     "0",
+	"PACK_KEYS":          "DEFAULT",
+	"STATS_PERSISTENT":   "DEFAULT",
+	"STATS_AUTO_RECALC":  "DEFAULT",
+	"STATS_SAMPLE_PAGES": "DEFAULT",
+	"CHECKSUM":           "0",
+	"DELAY_KEY_WRITE":    "0",
+	"ROW_FORMAT":         "DEFAULT",
+	"KEY_BLOCK_SIZE":     "0",
+}
+
 // ChangeCreateOptions represents a difference in the create options
 // (row_format, stats_persistent, stats_auto_recalc, etc) between two versions
 // of a table. It satisfies the TableAlterClause interface.
@@ -433,22 +453,6 @@ type ChangeCreateOptions struct {
 // Clause returns a clause of an ALTER TABLE statement that sets one or more
 // create options.
 func (cco ChangeCreateOptions) Clause(_ StatementModifiers) string {
-	// Map of known defaults that make options no longer show up in create_options
-	// or SHOW CREATE TABLE.
-	knownDefaults := map[string]string{
-		"MIN_ROWS":           "0",
-		"MAX_ROWS":           "0",
-		"AVG_ROW_LENGTH":     "0",
-		"PACK_KEYS":          "DEFAULT",
-		"STATS_PERSISTENT":   "DEFAULT",
-		"STATS_AUTO_RECALC":  "DEFAULT",
-		"STATS_SAMPLE_PAGES": "DEFAULT",
-		"CHECKSUM":           "0",
-		"DELAY_KEY_WRITE":    "0",
-		"ROW_FORMAT":         "DEFAULT",
-		"KEY_BLOCK_SIZE":     "0",
-	}
-
 	splitOpts := func(full string) map[string]string {
 		result := make(map[string]string)
 		for _, kv := range strings.Split(full, " ") {
@@ -462,14 +466,14 @@ func (cco ChangeCreateOptions) Clause(_ StatementModifiers) string {
 
 	oldOpts := splitOpts(cco.OldCreateOptions)
 	newOpts := splitOpts(cco.NewCreateOptions)
-	subclauses := make([]string, 0, len(knownDefaults))
+	subclauses := make([]string, 0, len(createOptionDefaults))
 
 	// Determine which oldOpts changed in newOpts or are no longer present
 	for k, v := range oldOpts {
 		if newValue, ok := newOpts[k]; ok && newValue != v {
 			subclauses = append(subclauses, fmt.Sprintf("%s=%s", k, newValue))
 		} else if !ok {
-			def, known := knownDefaults[k]
+			def, known := createOptionDefaults[k]
 			if !known {
 				def = "DEFAULT"
 			}
@@ -105,3 +105,15 @@ func TestModifyColumnUnsafe(t *testing.T) {
 		t.Error("For changing collation but not character set, expected unsafe=false, instead found unsafe=true")
 	}
 }
+
+func TestRenameColumnClause(t *testing.T) {
+	table := aTable(1)
+	rc := RenameColumn{OldColumn: table.Columns[4], NewName: "social"}
+	expected := "CHANGE COLUMN `ssn` `social` char(10) CHARACTER SET utf8 NOT NULL"
+	if actual := rc.Clause(StatementModifiers{}); actual != expected {
+		t.Errorf("Expected clause %q, instead found %q", expected, actual)
+	}
+	if table.Columns[4].Name != "ssn" {
+		t.Error("Clause unexpectedly modified the original column")
+	}
+}
@@ -0,0 +1,1226 @@
+package tengo
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AlterTableStatement represents a parsed ALTER TABLE statement. Its clauses
+// use the same types generated by Table.Diff, permitting hand-written ALTERs to
+// be inspected for safety, rewritten using StatementModifiers, or applied to an
+// in-memory Table.
+type AlterTableStatement struct {
+	SchemaName      string // blank if the statement did not qualify the table name
+	TableName       string
+	Table           *Table // table being altered, as supplied to ParseAlterTable
+	Clauses         []TableAlterClause
+	LockClause      string // value of LOCK clause, or blank if none
+	AlgorithmClause string // value of ALGORITHM clause, or blank if none
+}
+
+// Unsafe returns true if any of the statement's clauses are potentially
+// destructive of data.
+func (ats *AlterTableStatement) Unsafe() bool {
+	for _, clause := range ats.Clauses {
+		if unsafer, ok := clause.(Unsafer); ok && unsafer.Unsafe() {
+			return true
+		}
+	}
+	return false
+}
+
+// Modifiers returns a copy of mods, with LockClause and AlgorithmClause set to
+// the values present in the statement, if any.
+func (ats *AlterTableStatement) Modifiers(mods StatementModifiers) StatementModifiers {
+	if ats.LockClause != "" {
+		mods.LockClause = ats.LockClause
+	}
+	if ats.AlgorithmClause != "" {
+		mods.AlgorithmClause = ats.AlgorithmClause
+	}
+	return mods
+}
+
+// TableDiff returns a TableDiff representing the statement. Its To side is
+// obtained by applying the statement's clauses to the original table, using
+// the supplied flavor. The TableDiff's Statement method may then be used to
+// enforce StatementModifiers policies, such as forbidding unsafe changes.
+func (ats *AlterTableStatement) TableDiff(flavor Flavor) (*TableDiff, error) {
+	to, err := ats.Table.Apply(flavor, ats.Clauses...)
+	if err != nil {
+		return nil, err
+	}
+	return &TableDiff{
+		Type:         DiffTypeAlter,
+		From:         ats.Table,
+		To:           to,
+		alterClauses: ats.Clauses,
+		supported:    true,
+	}, nil
+}
+
+// ParseAlterTable parses an ALTER TABLE statement which modifies table,
+// returning its clauses as TableAlterClause values. The flavor is used to
+// determine how column types and defaults are normalized, so that they match
+// the output of SHOW CREATE TABLE.
+//
+// Supported clauses include ADD/DROP/MODIFY/CHANGE/RENAME/ALTER COLUMN,
+// ADD/DROP of indexes, primary keys, and foreign keys, and table options
+// such as ENGINE, CHARACTER SET, COLLATE, COMMENT, AUTO_INCREMENT, ROW_FORMAT,
+// and other create options, as well as LOCK and ALGORITHM. An error is
+// returned if the statement cannot be parsed, refers to nonexistent columns or
+// indexes, or uses features not supported by this package, such as
+// partitioning, table renames, or CHANGE COLUMN that both renames and
+// redefines a column.
+func ParseAlterTable(statement string, table *Table, flavor Flavor) (*AlterTableStatement, error) {
+	tokens, err := tokenizeSQL(statement)
+	if err != nil {
+		return nil, err
+	}
+	p := &alterParser{
+		tokens:             tokens,
+		table:              table,
+		flavor:             flavor,
+		charSet:            table.CharSet,
+		collation:          table.Collation,
+		collationIsDefault: table.CollationIsDefault,
+		newColumns:         make(map[string]*Column),
+		newIndexNames:      make(map[string]bool),
+		omitIntWidth:       intDisplayWidthOmitted(table, flavor),
+		stmt:               &AlterTableStatement{Table: table},
+	}
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	return p.stmt, nil
+}
+
+// alterParser holds the state of a single ParseAlterTable call.
+type alterParser struct {
+	tokens             []sqlToken
+	pos                int
+	table              *Table
+	flavor             Flavor
+	charSet            string // table default charset, as of the current clause
+	collation          string // table default collation, as of the current clause
+	collationIsDefault bool
+	newColumns         map[string]*Column // lowercased name => columns added or redefined in this statement
+	newIndexNames      map[string]bool    // lowercased names of indexes added in this statement
+	newForeignKeys     int                // number of foreign keys added in this statement without a name
+	createOptions      []string           // pending create option changes, as KEY=VALUE pairs
+	omitIntWidth       bool               // whether SHOW CREATE TABLE omits int display widths
+	stmt               *AlterTableStatement
+	input              string // description of what is being parsed, for errors; defaults to "ALTER TABLE statement"
+}
+
+// peek returns the token n positions after the current one, or a blank
+// symbol token if past the end of the statement.
+func (p *alterParser) peek(n int) sqlToken {
+	if p.pos+n >= len(p.tokens) {
+		return sqlToken{typ: sqlTokenSymbol}
+	}
+	return p.tokens[p.pos+n]
+}
+
+// atEnd returns true if there are no more tokens, ignoring a trailing
+// semicolon.
+func (p *alterParser) atEnd() bool {
+	return p.pos >= len(p.tokens) || (p.pos == len(p.tokens)-1 && p.tokens[p.pos].isSymbol(";"))
+}
+
+// atClauseEnd returns true if the current clause has no more tokens.
+func (p *alterParser) atClauseEnd() bool {
+	return p.atEnd() || p.peek(0).isSymbol(",")
+}
+
+// peekKeywords returns true if the next tokens match the supplied sequence of
+// keywords.
+func (p *alterParser) peekKeywords(keywords ...string) bool {
+	for n, kw := range keywords {
+		if !p.peek(n).isKeyword(kw) {
+			return false
+		}
+	}
+	return true
+}
+
+// acceptKeywords advances past the supplied sequence of keywords and returns
+// true if they are next; otherwise it returns false without advancing.
+func (p *alterParser) acceptKeywords(keywords ...string) bool {
+	if !p.peekKeywords(keywords...) {
+		return false
+	}
+	p.pos += len(keywords)
+	return true
+}
+
+// acceptAnyKeyword advances past the next token and returns its uppercased
+// value if it matches any of the supplied keywords; otherwise it returns a
+// blank string without advancing.
+func (p *alterParser) acceptAnyKeyword(keywords ...string) string {
+	for _, kw := range keywords {
+		if p.acceptKeywords(kw) {
+			return strings.ToUpper(kw)
+		}
+	}
+	return ""
+}
+
+// acceptSymbol advances past the supplied symbol and returns true if it is
+// next; otherwise it returns false without advancing.
+func (p *alterParser) acceptSymbol(sym string) bool {
+	if p.peek(0).isSymbol(sym) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// unexpected returns an error describing the current token.
+func (p *alterParser) unexpected(expected string) error {
+	input := p.input
+	if input == "" {
+		input = "ALTER TABLE statement"
+	}
+	if p.atEnd() {
+		return fmt.Errorf("Unexpected end of %s: expected %s", input, expected)
+	}
+	return fmt.Errorf("Unexpected %s in %s: expected %s", p.peek(0), input, expected)
+}
+
+func (p *alterParser) expectKeywords(keywords ...string) error {
+	if !p.acceptKeywords(keywords...) {
+		return p.unexpected(strings.Join(keywords, " "))
+	}
+	return nil
+}
+
+func (p *alterParser) expectSymbol(sym string) error {
+	if !p.acceptSymbol(sym) {
+		return p.unexpected(sym)
+	}
+	return nil
+}
+
+// identifier consumes and returns an identifier, which may be quoted or
+// unquoted.
+func (p *alterParser) identifier() (string, error) {
+	tok := p.peek(0)
+	if tok.typ != sqlTokenWord && tok.typ != sqlTokenIdentifier {
+		return "", p.unexpected("identifier")
+	}
+	p.pos++
+	return tok.val, nil
+}
+
+// optionValue consumes an optional equals sign followed by a word or string,
+// as used by table options.
+func (p *alterParser) optionValue() (string, error) {
+	p.acceptSymbol("=")
+	tok := p.peek(0)
+	if tok.typ == sqlTokenSymbol {
+		return "", p.unexpected("value")
+	}
+	p.pos++
+	return tok.val, nil
+}
+
+// unsupportedAlter returns an error indicating the described feature is not
+// supported by the parser.
+func unsupportedAlter(feature string) error {
+	return fmt.Errorf("ALTER TABLE with %s is not supported", feature)
+}
+
+func (p *alterParser) parse() error {
+	if err := p.expectKeywords("ALTER"); err != nil {
+		return err
+	}
+	p.acceptAnyKeyword("ONLINE", "OFFLINE")
+	p.acceptKeywords("IGNORE")
+	if err := p.expectKeywords("TABLE"); err != nil {
+		return err
+	}
+	name, err := p.identifier()
+	if err != nil {
+		return err
+	}
+	if p.acceptSymbol(".") {
+		p.stmt.SchemaName = name
+		if name, err = p.identifier(); err != nil {
+			return err
+		}
+	}
+	p.stmt.TableName = name
+	if name != p.table.Name {
+		return fmt.Errorf("ALTER TABLE statement refers to table %s, but table %s was supplied", EscapeIdentifier(name), EscapeIdentifier(p.table.Name))
+	}
+
+	for {
+		if err := p.parseClause(); err != nil {
+			return err
+		}
+		if p.atEnd() {
+			break
+		} else if !p.acceptSymbol(",") {
+			return p.unexpected("comma or end of statement")
+		}
+	}
+	if len(p.createOptions) > 0 {
+		p.stmt.Clauses = append(p.stmt.Clauses, ChangeCreateOptions{
+			OldCreateOptions: p.table.CreateOptions,
+			NewCreateOptions: mergeCreateOptions(p.table.CreateOptions, p.createOptions),
+		})
+	}
+	return nil
+}
+
+func (p *alterParser) parseClause() error {
+	switch {
+	case p.acceptKeywords("ADD"):
+		return p.parseAdd()
+	case p.acceptKeywords("DROP"):
+		return p.parseDrop()
+	case p.acceptKeywords("MODIFY"):
+		p.acceptKeywords("COLUMN")
+		return p.parseModify("")
+	case p.acceptKeywords("CHANGE"):
+		p.acceptKeywords("COLUMN")
+		oldName, err := p.identifier()
+		if err != nil {
+			return err
+		}
+		return p.parseModify(oldName)
+	case p.acceptKeywords("RENAME"):
+		if !p.acceptKeywords("COLUMN") {
+			return unsupportedAlter("RENAME of table or index")
+		}
+		return p.parseRenameColumn()
+	case p.peekKeywords("ALTER"):
+		p.pos++
+		p.acceptKeywords("COLUMN")
+		return p.parseAlterColumn()
+	case p.peekKeywords("CONVERT"):
+		return unsupportedAlter("CONVERT TO CHARACTER SET")
+	case p.peekKeywords("PARTITION") || p.peekKeywords("REMOVE", "PARTITIONING"):
+		return unsupportedAlter("partitioning")
+	}
+	if p.atClauseEnd() {
+		return p.unexpected("ALTER TABLE clause")
+	}
+	for !p.atClauseEnd() {
+		if err := p.parseTableOption(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *alterParser) parseTableOption() error {
+	switch {
+	case p.acceptKeywords("ENGINE"):
+		engine, err := p.optionValue()
+		if err != nil {
+			return err
+		}
+		p.stmt.Clauses = append(p.stmt.Clauses, ChangeStorageEngine{NewStorageEngine: engine})
+	case p.acceptKeywords("LOCK"):
+		val, err := p.optionValue()
+		p.stmt.LockClause = strings.ToUpper(val)
+		return err
+	case p.acceptKeywords("ALGORITHM"):
+		val, err := p.optionValue()
+		p.stmt.AlgorithmClause = strings.ToUpper(val)
+		return err
+	case p.acceptKeywords("COMMENT"):
+		p.acceptSymbol("=")
+		if p.peek(0).typ != sqlTokenString {
+			return p.unexpected("string")
+		}
+		p.stmt.Clauses = append(p.stmt.Clauses, ChangeComment{NewComment: p.peek(0).val})
+		p.pos++
+	case p.acceptKeywords("AUTO_INCREMENT"):
+		val, err := p.optionValue()
+		if err != nil {
+			return err
+		}
+		next, err := strconv.ParseUint(val, 10, 64)
+		if err != nil {
+			return fmt.Errorf("Invalid AUTO_INCREMENT value %s in ALTER TABLE statement", val)
+		}
+		p.stmt.Clauses = append(p.stmt.Clauses, ChangeAutoIncrement{
+			OldNextAutoIncrement: p.table.NextAutoIncrement,
+			NewNextAutoIncrement: next,
+		})
+	case p.peekKeywords("DEFAULT") || p.peekKeywords("CHARACTER") || p.peekKeywords("CHARSET") || p.peekKeywords("COLLATE"):
+		p.acceptKeywords("DEFAULT")
+		return p.parseTableCharSet()
+	default:
+		for option := range createOptionDefaults {
+			if p.acceptKeywords(option) {
+				val, err := p.optionValue()
+				p.createOptions = append(p.createOptions, fmt.Sprintf("%s=%s", option, strings.ToUpper(val)))
+				return err
+			}
+		}
+		return p.unexpected("ALTER TABLE clause")
+	}
+	return nil
+}
+
+// parseTableCharSet parses a table-level CHARACTER SET and/or COLLATE option.
+func (p *alterParser) parseTableCharSet() error {
+	var charSet, collation string
+	var err error
+	for {
+		if p.acceptKeywords("CHARACTER", "SET") || p.acceptKeywords("CHARSET") {
+			if charSet, err = p.optionValue(); err != nil {
+				return err
+			}
+		} else if p.acceptKeywords("COLLATE") {
+			if collation, err = p.optionValue(); err != nil {
+				return err
+			}
+		} else if charSet == "" && collation == "" {
+			return p.unexpected("CHARACTER SET or COLLATE")
+		} else {
+			break
+		}
+		p.acceptKeywords("DEFAULT")
+	}
+	charSet, collation = strings.ToLower(charSet), strings.ToLower(collation)
+	if charSet == "" {
+		charSet = charSetForCollation(collation)
+	}
+	if collation == "" {
+		collation = p.flavor.DefaultCollation(charSet)
+	}
+	p.charSet, p.collation = charSet, collation
+	p.collationIsDefault = (collation == p.flavor.DefaultCollation(charSet))
+	p.stmt.Clauses = append(p.stmt.Clauses, ChangeCharSet{CharSet: charSet, Collation: collation})
+	return nil
+}
+
+// charSetForCollation returns the character set of the supplied collation.
+func charSetForCollation(collation string) string {
+	if underscore := strings.IndexByte(collation, '_'); underscore > 0 {
+		return collation[0:underscore]
+	}
+	return collation
+}
+
+// mergeCreateOptions returns a create options string reflecting the supplied
+// KEY=VALUE changes applied to oldOptions. Options set to their default
+// values are removed. Options not already present in oldOptions are appended
+// in alphabetical order, since the order of clauses in the ALTER is arbitrary.
+func mergeCreateOptions(oldOptions string, changes []string) string {
+	var keys, newKeys []string
+	values := make(map[string]string)
+	for _, kv := range strings.Fields(oldOptions) {
+		if tokens := strings.SplitN(kv, "=", 2); len(tokens) == 2 {
+			keys = append(keys, tokens[0])
+			values[tokens[0]] = tokens[1]
+		}
+	}
+	for _, kv := range changes {
+		tokens := strings.SplitN(kv, "=", 2)
+		if len(tokens) != 2 {
+			continue
+		}
+		if _, already := values[tokens[0]]; !already {
+			newKeys = append(newKeys, tokens[0])
+		}
+		values[tokens[0]] = tokens[1]
+	}
+	sort.Strings(newKeys)
+	keys = append(keys, newKeys...)
+	result := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if v := values[k]; v != "DEFAULT" && v != createOptionDefaults[k] {
+			result = append(result, fmt.Sprintf("%s=%s", k, v))
+		}
+	}
+	return strings.Join(result, " ")
+}
+
+func (p *alterParser) parseAdd() error {
+	if p.acceptKeywords("COLUMN") {
+		return p.parseAddColumn()
+	}
+	var constraintName string
+	if p.acceptKeywords("CONSTRAINT") {
+		if !p.peekKeywords("PRIMARY") && !p.peekKeywords("UNIQUE") && !p.peekKeywords("FOREIGN") && !p.peekKeywords("CHECK") {
+			var err error
+			if constraintName, err = p.identifier(); err != nil {
+				return err
+			}
+		}
+		if !p.peekKeywords("PRIMARY") && !p.peekKeywords("UNIQUE") && !p.peekKeywords("FOREIGN") {
+			return unsupportedAlter("CHECK constraints")
+		}
+	}
+	switch {
+	case p.peek(0).isSymbol("("):
+		return unsupportedAlter("parenthesized column list")
+	case p.acceptKeywords("PRIMARY", "KEY"):
+		return p.parseAddIndex("PRIMARY", true, true)
+	case p.acceptKeywords("UNIQUE"):
+		p.acceptAnyKeyword("INDEX", "KEY")
+		return p.parseAddIndex(constraintName, true, false)
+	case p.acceptAnyKeyword("INDEX", "KEY") != "":
+		return p.parseAddIndex("", false, false)
+	case p.acceptKeywords("FOREIGN", "KEY"):
+		return p.parseAddForeignKey(constraintName)
+	case p.peekKeywords("FULLTEXT") || p.peekKeywords("SPATIAL"):
+		return unsupportedAlter(strings.ToUpper(p.peek(0).val) + " indexes")
+	case p.peekKeywords("PARTITION"):
+		return unsupportedAlter("partitioning")
+	case p.peekKeywords("CHECK"):
+		return unsupportedAlter("CHECK constraints")
+	}
+	return p.parseAddColumn()
+}
+
+func (p *alterParser) parseAddColumn() error {
+	if p.peek(0).isSymbol("(") {
+		return unsupportedAlter("parenthesized column list")
+	}
+	col, err := p.parseColumnDefinition()
+	if err != nil {
+		return err
+	}
+	if p.column(col.Name) != nil {
+		return fmt.Errorf("Unable to add column %s: column already exists", EscapeIdentifier(col.Name))
+	}
+	first, after, err := p.parsePosition()
+	if err != nil {
+		return err
+	}
+	p.newColumns[strings.ToLower(col.Name)] = col
+	p.stmt.Clauses = append(p.stmt.Clauses, AddColumn{
+		Table:         p.table,
+		Column:        col,
+		PositionFirst: first,
+		PositionAfter: after,
+	})
+	return nil
+}
+
+// parseModify parses the remainder of a MODIFY COLUMN clause, or a CHANGE
+// COLUMN clause if oldName is non-blank.
+func (p *alterParser) parseModify(oldName string) error {
+	col, err := p.parseColumnDefinition()
+	if err != nil {
+		return err
+	}
+	if oldName == "" {
+		oldName = col.Name
+	}
+	oldCol := p.existingColumn(oldName)
+	if oldCol == nil {
+		return fmt.Errorf("Unable to modify column %s: column does not exist", EscapeIdentifier(oldName))
+	}
+	first, after, err := p.parsePosition()
+	if err != nil {
+		return err
+	}
+
+	if oldCol.Name != col.Name {
+		// CHANGE COLUMN that renames a column: only supported if nothing else about
+		// the column changes
+		renamed := *oldCol
+		renamed.Name = col.Name
+		if !renamed.Equals(col) || first || after != nil {
+			return unsupportedAlter("CHANGE COLUMN that both renames and redefines a column")
+		}
+		if p.column(col.Name) != nil {
+			return fmt.Errorf("Unable to rename column %s to %s: column already exists", EscapeIdentifier(oldCol.Name), EscapeIdentifier(col.Name))
+		}
+		p.newColumns[strings.ToLower(col.Name)] = col
+		p.stmt.Clauses = append(p.stmt.Clauses, RenameColumn{OldColumn: oldCol, NewName: col.Name})
+		return nil
+	}
+
+	col.Name = oldCol.Name // retain original capitalization
+	p.newColumns[strings.ToLower(col.Name)] = col
+	p.stmt.Clauses = append(p.stmt.Clauses, ModifyColumn{
+		Table:         p.table,
+		OldColumn:     oldCol,
+		NewColumn:     col,
+		PositionFirst: first,
+		PositionAfter: after,
+	})
+	return nil
+}
+
+func (p *alterParser) parseRenameColumn() error {
+	oldName, err := p.identifier()
+	if err != nil {
+		return err
+	}
+	if err := p.expectKeywords("TO"); err != nil {
+		return err
+	}
+	newName, err := p.identifier()
+	if err != nil {
+		return err
+	}
+	oldCol := p.existingColumn(oldName)
+	if oldCol == nil {
+		return fmt.Errorf("Unable to rename column %s: column does not exist", EscapeIdentifier(oldName))
+	}
+	if p.column(newName) != nil {
+		return fmt.Errorf("Unable to rename column %s to %s: column already exists", EscapeIdentifier(oldName), EscapeIdentifier(newName))
+	}
+	renamed := *oldCol
+	renamed.Name = newName
+	p.newColumns[strings.ToLower(newName)] = &renamed
+	p.stmt.Clauses = append(p.stmt.Clauses, RenameColumn{OldColumn: oldCol, NewName: newName})
+	return nil
+}
+
+// parseAlterColumn parses the remainder of an ALTER COLUMN ... SET DEFAULT or
+// ALTER COLUMN ... DROP DEFAULT clause.
+func (p *alterParser) parseAlterColumn() error {
+	name, err := p.identifier()
+	if err != nil {
+		return err
+	}
+	oldCol := p.existingColumn(name)
+	if oldCol == nil {
+		return fmt.Errorf("Unable to alter column %s: column does not exist", EscapeIdentifier(name))
+	}
+	newCol := *oldCol
+	if p.acceptKeywords("SET", "DEFAULT") {
+		if newCol.Default, err = p.parseDefault(); err != nil {
+			return err
+		}
+	} else if p.acceptKeywords("DROP", "DEFAULT") {
+		newCol.Default = ColumnDefaultNull
+	} else {
+		return p.unexpected("SET DEFAULT or DROP DEFAULT")
+	}
+	p.newColumns[strings.ToLower(newCol.Name)] = &newCol
+	p.stmt.Clauses = append(p.stmt.Clauses, ModifyColumn{
+		Table:     p.table,
+		OldColumn: oldCol,
+		NewColumn: &newCol,
+	})
+	return nil
+}
+
+func (p *alterParser) parseDrop() error {
+	switch {
+	case p.acceptKeywords("PRIMARY", "KEY"):
+		if p.table.PrimaryKey == nil {
+			return fmt.Errorf("Unable to drop primary key: table %s has no primary key", EscapeIdentifier(p.table.Name))
+		}
+		p.stmt.Clauses = append(p.stmt.Clauses, DropIndex{Index: p.table.PrimaryKey})
+		return nil
+	case p.acceptAnyKeyword("INDEX", "KEY") != "":
+		name, err := p.identifier()
+		if err != nil {
+			return err
+		}
+		for _, idx := range p.table.SecondaryIndexes {
+			if strings.EqualFold(idx.Name, name) {
+				p.stmt.Clauses = append(p.stmt.Clauses, DropIndex{Index: idx})
+				return nil
+			}
+		}
+		return fmt.Errorf("Unable to drop index %s: index does not exist", EscapeIdentifier(name))
+	case p.acceptKeywords("FOREIGN", "KEY"):
+		name, err := p.identifier()
+		if err != nil {
+			return err
+		}
+		for _, fk := range p.table.ForeignKeys {
+			if strings.EqualFold(fk.Name, name) {
+				p.stmt.Clauses = append(p.stmt.Clauses, DropForeignKey{ForeignKey: fk})
+				return nil
+			}
+		}
+		return fmt.Errorf("Unable to drop foreign key %s: foreign key does not exist", EscapeIdentifier(name))
+	case p.peekKeywords("CHECK") || p.peekKeywords("CONSTRAINT"):
+		return unsupportedAlter("DROP CHECK or DROP CONSTRAINT")
+	case p.peekKeywords("PARTITION"):
+		return unsupportedAlter("partitioning")
+	}
+	p.acceptKeywords("COLUMN")
+	name, err := p.identifier()
+	if err != nil {
+		return err
+	}
+	col := p.existingColumn(name)
+	if col == nil {
+		return fmt.Errorf("Unable to drop column %s: column does not exist", EscapeIdentifier(name))
+	}
+	p.stmt.Clauses = append(p.stmt.Clauses, DropColumn{Column: col})
+	return nil
+}
+
+// existingColumn returns the column of the original table with the supplied
+// name, or nil if there is no such column.
+func (p *alterParser) existingColumn(name string) *Column {
+	for _, col := range p.table.Columns {
+		if strings.EqualFold(col.Name, name) {
+			return col
+		}
+	}
+	return nil
+}
+
+// column returns the column with the supplied name, including columns added
+// or renamed earlier in the statement, or nil if there is no such column.
+func (p *alterParser) column(name string) *Column {
+	if col, ok := p.newColumns[strings.ToLower(name)]; ok {
+		return col
+	}
+	return p.existingColumn(name)
+}
+
+// parsePosition parses an optional FIRST or AFTER clause.
+func (p *alterParser) parsePosition() (first bool, after *Column, err error) {
+	if p.acceptKeywords("FIRST") {
+		return true, nil, nil
+	}
+	if !p.acceptKeywords("AFTER") {
+		return false, nil, nil
+	}
+	name, err := p.identifier()
+	if err != nil {
+		return false, nil, err
+	}
+	if after = p.column(name); after == nil {
+		return false, nil, fmt.Errorf("Unable to position column after %s: column does not exist", EscapeIdentifier(name))
+	}
+	return false, after, nil
+}
+
+// textTypes lists the column types which have a character set and collation.
+var textTypes = map[string]bool{
+	"char": true, "varchar": true, "tinytext": true, "text": true,
+	"mediumtext": true, "longtext": true, "enum": true, "set": true,
+}
+
+// typeAliases maps column type synonyms to the type shown by SHOW CREATE TABLE.
+var typeAliases = map[string]string{
+	"integer":   "int",
+	"int1":      "tinyint",
+	"int2":      "smallint",
+	"int3":      "mediumint",
+	"int4":      "int",
+	"int8":      "bigint",
+	"middleint": "mediumint",
+	"dec":       "decimal",
+	"numeric":   "decimal",
+	"fixed":     "decimal",
+	"real":      "double",
+}
+
+// parseColumnDefinition parses a column name, type, and attributes.
+func (p *alterParser) parseColumnDefinition() (*Column, error) {
+	name, err := p.identifier()
+	if err != nil {
+		return nil, err
+	}
+	col := &Column{
+		Name:     name,
+		Nullable: true,
+		Default:  ColumnDefaultNull,
+	}
+	if col.TypeInDB, err = p.parseColumnType(); err != nil {
+		return nil, err
+	}
+	baseType := col.TypeInDB
+	if paren := strings.IndexAny(baseType, "( "); paren > -1 {
+		baseType = baseType[0:paren]
+	}
+
+	var charSet, collation string
+	for !p.atClauseEnd() && !p.peekKeywords("FIRST") && !p.peekKeywords("AFTER") {
+		switch {
+		case p.acceptKeywords("NOT", "NULL"):
+			col.Nullable = false
+		case p.acceptKeywords("NULL"):
+			col.Nullable = true
+		case p.acceptKeywords("DEFAULT"):
+			if col.Default, err = p.parseDefault(); err != nil {
+				return nil, err
+			}
+		case p.acceptKeywords("AUTO_INCREMENT"):
+			col.AutoIncrement = true
+		case p.acceptKeywords("ON", "UPDATE"):
+			onUpdate, ok := p.parseCurrentTimestamp()
+			if !ok {
+				return nil, unsupportedAlter("ON UPDATE value other than CURRENT_TIMESTAMP")
+			}
+			col.OnUpdate = onUpdate
+		case p.acceptKeywords("COMMENT"):
+			if p.peek(0).typ != sqlTokenString {
+				return nil, p.unexpected("string")
+			}
+			col.Comment = p.peek(0).val
+			p.pos++
+		case p.acceptKeywords("CHARACTER", "SET") || p.acceptKeywords("CHARSET"):
+			if charSet, err = p.identifier(); err != nil {
+				return nil, err
+			}
+		case p.acceptKeywords("COLLATE"):
+			if collation, err = p.identifier(); err != nil {
+				return nil, err
+			}
+		case p.peekKeywords("PRIMARY") || p.peekKeywords("UNIQUE") || p.peekKeywords("KEY"):
+			return nil, unsupportedAlter("inline index definition in column")
+		case p.peekKeywords("GENERATED") || p.peekKeywords("AS"):
+			return nil, unsupportedAlter("generated columns")
+		default:
+			return nil, p.unexpected("column attribute")
+		}
+	}
+
+	if textTypes[baseType] {
+		charSet, collation = strings.ToLower(charSet), strings.ToLower(collation)
+		if charSet == "" && collation == "" {
+			col.CharSet, col.Collation, col.CollationIsDefault = p.charSet, p.collation, p.collationIsDefault
+		} else {
+			if charSet == "" {
+				charSet = charSetForCollation(collation)
+			} else if collation == "" {
+				collation = p.flavor.DefaultCollation(charSet)
+			}
+			col.CharSet, col.Collation = charSet, collation
+			col.CollationIsDefault = (collation == p.flavor.DefaultCollation(charSet))
+		}
+	}
+	return col, nil
+}
+
+// parseColumnType parses a column's data type, returning it in the same
+// format as SHOW CREATE TABLE.
+func (p *alterParser) parseColumnType() (string, error) {
+	tok := p.peek(0)
+	if tok.typ != sqlTokenWord {
+		return "", p.unexpected("column type")
+	}
+	p.pos++
+	base := strings.ToLower(tok.val)
+	if alias, ok := typeAliases[base]; ok {
+		base = alias
+	}
+	if base == "double" {
+		p.acceptKeywords("PRECISION")
+	} else if base == "bool" || base == "boolean" {
+		return "tinyint(1)", nil
+	}
+
+	var args string
+	if p.acceptSymbol("(") {
+		var argParts []string
+		for {
+			tok := p.peek(0)
+			if tok.typ == sqlTokenString {
+				argParts = append(argParts, fmt.Sprintf("'%s'", strings.Replace(tok.val, "'", "''", -1)))
+			} else if tok.typ == sqlTokenWord {
+				argParts = append(argParts, tok.val)
+			} else {
+				return "", p.unexpected("type argument")
+			}
+			p.pos++
+			if p.acceptSymbol(")") {
+				break
+			}
+			if err := p.expectSymbol(","); err != nil {
+				return "", err
+			}
+		}
+		args = fmt.Sprintf("(%s)", strings.Join(argParts, ","))
+	}
+
+	var unsigned, zerofill bool
+	for {
+		if p.acceptKeywords("UNSIGNED") {
+			unsigned = true
+		} else if p.acceptKeywords("ZEROFILL") {
+			zerofill, unsigned = true, true
+		} else if !p.acceptKeywords("SIGNED") {
+			break
+		}
+	}
+
+	// Supply default display widths and precisions as shown by SHOW CREATE TABLE,
+	// or omit int display widths if SHOW CREATE TABLE does so
+	isInt := false
+	for _, it := range intTypeRanks {
+		isInt = isInt || base == it.name
+	}
+	if isInt && p.omitIntWidth && !zerofill && !(base == "tinyint" && args == "(1)") {
+		args = ""
+	} else if args == "" {
+		for _, it := range intTypeRanks {
+			if base == it.name {
+				width := it.signedWidth
+				if unsigned {
+					width = it.unsignedWidth
+				}
+				args = fmt.Sprintf("(%d)", width)
+			}
+		}
+		switch base {
+		case "decimal":
+			args = "(10,0)"
+		case "bit", "char", "binary":
+			args = "(1)"
+		}
+	}
+
+	result := base + args
+	if unsigned {
+		result += " unsigned"
+	}
+	if zerofill {
+		result += " zerofill"
+	}
+	return result, nil
+}
+
+// intDisplayWidthOmitted returns true if SHOW CREATE TABLE omits int display
+// widths for the table. Existing int columns of the table are used as
+// evidence if possible, since this behavior depends on the server's patch
+// version; otherwise flavor.OmitIntDisplayWidth is used.
+func intDisplayWidthOmitted(table *Table, flavor Flavor) bool {
+	for _, col := range table.Columns {
+		typ := strings.ToLower(col.TypeInDB)
+		if rank, _ := col.intTypeRank(); rank < 0 || strings.Contains(typ, "zerofill") || strings.HasPrefix(typ, "tinyint(1)") {
+			continue
+		}
+		return !strings.Contains(typ, "(")
+	}
+	return flavor.OmitIntDisplayWidth()
+}
+
+// parseCurrentTimestamp parses CURRENT_TIMESTAMP or one of its synonyms, with
+// optional fractional precision, returning it in the same format as SHOW
+// CREATE TABLE. The second return value is false if the next token is not
+// CURRENT_TIMESTAMP or a synonym.
+func (p *alterParser) parseCurrentTimestamp() (string, bool) {
+	if p.acceptAnyKeyword("CURRENT_TIMESTAMP", "NOW", "LOCALTIME", "LOCALTIMESTAMP") == "" {
+		return "", false
+	}
+	result := "CURRENT_TIMESTAMP"
+	if p.acceptSymbol("(") {
+		if tok := p.peek(0); tok.typ == sqlTokenWord && tok.val != "0" {
+			result = fmt.Sprintf("%s(%s)", result, tok.val)
+			p.pos++
+		} else if tok.typ == sqlTokenWord {
+			p.pos++
+		}
+		if !p.acceptSymbol(")") {
+			return "", false
+		}
+	}
+	if p.flavor.VendorMinVersion(VendorMariaDB, 10, 2) {
+		result = strings.ToLower(result)
+	}
+	return result, true
+}
+
+// parseDefault parses the value of a DEFAULT clause.
+func (p *alterParser) parseDefault() (ColumnDefault, error) {
+	tok := p.peek(0)
+	if tok.isKeyword("NULL") {
+		p.pos++
+		return ColumnDefaultNull, nil
+	} else if tok.typ == sqlTokenString {
+		p.pos++
+		return ColumnDefaultValue(tok.val), nil
+	} else if expr, ok := p.parseCurrentTimestamp(); ok {
+		return ColumnDefaultExpression(expr), nil
+	}
+
+	// Numeric literals, optionally signed; or bit/hex literals; or booleans
+	var value string
+	if tok.isSymbol("-") || tok.isSymbol("+") {
+		p.pos++
+		if tok.val == "-" {
+			value = "-"
+		}
+		tok = p.peek(0)
+	}
+	if tok.typ != sqlTokenWord {
+		return ColumnDefault{}, p.unexpected("default value")
+	}
+	p.pos++
+	if tok.isKeyword("TRUE") {
+		value += "1"
+	} else if tok.isKeyword("FALSE") {
+		value += "0"
+	} else if lower := strings.ToLower(tok.val); strings.HasPrefix(lower, "b'") || strings.HasPrefix(lower, "x'") {
+		return ColumnDefaultExpression(tok.val), nil
+	} else if _, err := strconv.ParseFloat(tok.val, 64); err == nil {
+		value += tok.val
+	} else {
+		return ColumnDefault{}, unsupportedAlter("default value " + tok.val)
+	}
+	if p.flavor.AllowDefaultExpression() {
+		return ColumnDefaultExpression(value), nil
+	}
+	return ColumnDefaultValue(value), nil
+}
+
+// parseAddIndex parses the remainder of an ADD INDEX, ADD UNIQUE, or ADD
+// PRIMARY KEY clause.
+func (p *alterParser) parseAddIndex(name string, unique, primary bool) error {
+	if !primary && !p.peek(0).isSymbol("(") && !p.peekKeywords("USING") {
+		var err error
+		if name, err = p.identifier(); err != nil {
+			return err
+		}
+	}
+	if p.acceptKeywords("USING") {
+		p.acceptAnyKeyword("BTREE", "HASH")
+	}
+	idx := &Index{
+		Name:       name,
+		Unique:     unique,
+		PrimaryKey: primary,
+	}
+	cols, subParts, err := p.parseKeyParts()
+	if err != nil {
+		return err
+	}
+	idx.Columns, idx.SubParts = cols, subParts
+
+	// Index options
+	for !p.atClauseEnd() {
+		if p.acceptKeywords("COMMENT") {
+			if p.peek(0).typ != sqlTokenString {
+				return p.unexpected("string")
+			}
+			idx.Comment = p.peek(0).val
+			p.pos++
+		} else if p.acceptKeywords("USING") {
+			p.acceptAnyKeyword("BTREE", "HASH")
+		} else if p.peekKeywords("INVISIBLE") || p.peekKeywords("VISIBLE") {
+			return unsupportedAlter("index visibility")
+		} else {
+			return p.unexpected("index option")
+		}
+	}
+
+	if primary {
+		if p.table.PrimaryKey != nil && !p.dropsIndex(p.table.PrimaryKey) {
+			return fmt.Errorf("Unable to add primary key: table %s already has a primary key", EscapeIdentifier(p.table.Name))
+		}
+		for _, col := range idx.Columns {
+			if col.Nullable {
+				return fmt.Errorf("Unable to add primary key: column %s is nullable", EscapeIdentifier(col.Name))
+			}
+		}
+	} else {
+		if idx.Name == "" {
+			idx.Name = p.generatedIndexName(idx.Columns[0].Name)
+		}
+		if p.indexNameInUse(idx.Name) {
+			return fmt.Errorf("Unable to add index %s: index already exists", EscapeIdentifier(idx.Name))
+		}
+		p.newIndexNames[strings.ToLower(idx.Name)] = true
+	}
+	p.stmt.Clauses = append(p.stmt.Clauses, AddIndex{Index: idx})
+	return nil
+}
+
+// parseKeyParts parses a parenthesized list of index columns, each with an
+// optional prefix length.
+func (p *alterParser) parseKeyParts() ([]*Column, []uint16, error) {
+	if err := p.expectSymbol("("); err != nil {
+		return nil, nil, err
+	}
+	var cols []*Column
+	var subParts []uint16
+	for {
+		if p.peek(0).isSymbol("(") {
+			return nil, nil, unsupportedAlter("functional index parts")
+		}
+		name, err := p.identifier()
+		if err != nil {
+			return nil, nil, err
+		}
+		col := p.column(name)
+		if col == nil {
+			return nil, nil, fmt.Errorf("Unable to index column %s: column does not exist", EscapeIdentifier(name))
+		}
+		var subPart uint16
+		if p.acceptSymbol("(") {
+			length, err := strconv.ParseUint(p.peek(0).val, 10, 16)
+			if err != nil {
+				return nil, nil, p.unexpected("prefix length")
+			}
+			p.pos++
+			if err := p.expectSymbol(")"); err != nil {
+				return nil, nil, err
+			}
+			subPart = uint16(length)
+		}
+		if p.acceptKeywords("DESC") {
+			return nil, nil, unsupportedAlter("descending index parts")
+		}
+		p.acceptKeywords("ASC")
+		cols = append(cols, col)
+		subParts = append(subParts, subPart)
+		if p.acceptSymbol(")") {
+			return cols, subParts, nil
+		}
+		if err := p.expectSymbol(","); err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+// dropsIndex returns true if the statement, as parsed so far, drops idx.
+func (p *alterParser) dropsIndex(idx *Index) bool {
+	for _, clause := range p.stmt.Clauses {
+		if di, ok := clause.(DropIndex); ok && di.Index == idx {
+			return true
+		}
+	}
+	return false
+}
+
+// indexNameInUse returns true if a secondary index with the supplied name
+// exists (and is not being dropped), or was added earlier in the statement.
+func (p *alterParser) indexNameInUse(name string) bool {
+	if p.newIndexNames[strings.ToLower(name)] {
+		return true
+	}
+	for _, idx := range p.table.SecondaryIndexes {
+		if strings.EqualFold(idx.Name, name) && !p.dropsIndex(idx) {
+			return true
+		}
+	}
+	return false
+}
+
+// generatedIndexName returns the name MySQL would assign to a new index whose
+// first column has the supplied name, if no name was specified.
+func (p *alterParser) generatedIndexName(colName string) string {
+	name := colName
+	for n := 2; p.indexNameInUse(name) || strings.EqualFold(name, "PRIMARY"); n++ {
+		name = fmt.Sprintf("%s_%d", colName, n)
+	}
+	return name
+}
+
+// parseAddForeignKey parses the remainder of an ADD FOREIGN KEY clause.
+func (p *alterParser) parseAddForeignKey(name string) error {
+	if !p.peek(0).isSymbol("(") {
+		indexName, err := p.identifier()
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			name = indexName
+		}
+	}
+	cols, subParts, err := p.parseKeyParts()
+	if err != nil {
+		return err
+	}
+	for _, subPart := range subParts {
+		if subPart > 0 {
+			return unsupportedAlter("prefix length in foreign key")
+		}
+	}
+	fk := &ForeignKey{
+		Name:       name,
+		Columns:    cols,
+		UpdateRule: "RESTRICT",
+		DeleteRule: "RESTRICT",
+	}
+
+	if err := p.expectKeywords("REFERENCES"); err != nil {
+		return err
+	}
+	if fk.ReferencedTableName, err = p.identifier(); err != nil {
+		return err
+	}
+	if p.acceptSymbol(".") {
+		fk.ReferencedSchemaName = fk.ReferencedTableName
+		if fk.ReferencedTableName, err = p.identifier(); err != nil {
+			return err
+		}
+		if fk.ReferencedSchemaName == p.stmt.SchemaName {
+			fk.ReferencedSchemaName = ""
+		}
+	}
+	if err := p.expectSymbol("("); err != nil {
+		return err
+	}
+	for {
+		refCol, err := p.identifier()
+		if err != nil {
+			return err
+		}
+		fk.ReferencedColumnNames = append(fk.ReferencedColumnNames, refCol)
+		if p.acceptSymbol(")") {
+			break
+		}
+		if err := p.expectSymbol(","); err != nil {
+			return err
+		}
+	}
+	if len(fk.ReferencedColumnNames) != len(fk.Columns) {
+		return fmt.Errorf("Foreign key has %d columns, but references %d columns", len(fk.Columns), len(fk.ReferencedColumnNames))
+	}
+
+	for p.acceptKeywords("ON") {
+		rule := &fk.UpdateRule
+		if p.acceptKeywords("DELETE") {
+			rule = &fk.DeleteRule
+		} else if err := p.expectKeywords("UPDATE"); err != nil {
+			return err
+		}
+		switch {
+		case p.acceptKeywords("RESTRICT"):
+			*rule = "RESTRICT"
+		case p.acceptKeywords("CASCADE"):
+			*rule = "CASCADE"
+		case p.acceptKeywords("SET", "NULL"):
+			*rule = "SET NULL"
+		case p.acceptKeywords("SET", "DEFAULT"):
+			*rule = "SET DEFAULT"
+		case p.acceptKeywords("NO", "ACTION"):
+			*rule = "NO ACTION"
+		default:
+			return p.unexpected("foreign key action")
+		}
+	}
+
+	if fk.Name == "" {
+		fk.Name = p.generatedForeignKeyName()
+	}
+	for _, existing := range p.table.ForeignKeys {
+		if strings.EqualFold(existing.Name, fk.Name) {
+			return fmt.Errorf("Unable to add foreign key %s: foreign key already exists", EscapeIdentifier(fk.Name))
+		}
+	}
+	p.stmt.Clauses = append(p.stmt.Clauses, AddForeignKey{ForeignKey: fk})
+	return nil
+}
+
+// generatedForeignKeyName returns the name InnoDB would assign to a new
+// foreign key if no name was specified.
+func (p *alterParser) generatedForeignKeyName() string {
+	prefix := p.table.Name + "_ibfk_"
+	var max int
+	for _, fk := range p.table.ForeignKeys {
+		if strings.HasPrefix(fk.Name, prefix) {
+			if n, err := strconv.Atoi(fk.Name[len(prefix):]); err == nil && n > max {
+				max = n
+			}
+		}
+	}
+	p.newForeignKeys++
+	return fmt.Sprintf("%s%d", prefix, max+p.newForeignKeys)
+}
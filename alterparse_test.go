@@ -0,0 +1,361 @@
+package tengo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAlterTable(t *testing.T) {
+	from := aTable(1)
+	stmt := "ALTER TABLE `actor` ADD COLUMN age INT UNSIGNED NOT NULL DEFAULT 0 AFTER last_name, " +
+		"MODIFY COLUMN first_name varchar(60) NOT NULL, DROP COLUMN alive_bit, " +
+		"ADD INDEX (age), ADD UNIQUE KEY idx_name (last_name(10), first_name), DROP KEY idx_actor_name, " +
+		"ALTER alive SET DEFAULT 0, COMMENT 'actors', LOCK=NONE, ALGORITHM = INPLACE;"
+	ats, err := ParseAlterTable(stmt, &from, FlavorMySQL57)
+	if err != nil {
+		t.Fatalf("Unexpected error from ParseAlterTable: %s", err)
+	}
+	if ats.TableName != "actor" || ats.SchemaName != "" {
+		t.Errorf("Unexpected table name: schema=%q table=%q", ats.SchemaName, ats.TableName)
+	}
+	if ats.LockClause != "NONE" || ats.AlgorithmClause != "INPLACE" {
+		t.Errorf("Unexpected LOCK or ALGORITHM: %q %q", ats.LockClause, ats.AlgorithmClause)
+	}
+	if len(ats.Clauses) != 8 {
+		t.Fatalf("Expected 8 clauses, instead found %d", len(ats.Clauses))
+	}
+	if ac, ok := ats.Clauses[0].(AddColumn); !ok || ac.Column.TypeInDB != "int(10) unsigned" || ac.Column.Default != ColumnDefaultValue("0") || ac.PositionAfter != from.Columns[2] {
+		t.Errorf("Unexpected first clause: %+v", ats.Clauses[0])
+	}
+	if ai, ok := ats.Clauses[3].(AddIndex); !ok || ai.Index.Name != "age" || ai.Index.Columns[0] != ats.Clauses[0].(AddColumn).Column {
+		t.Errorf("Unexpected fourth clause: %+v", ats.Clauses[3])
+	}
+	if !ats.Unsafe() {
+		t.Error("Expected statement dropping a column to be unsafe, but Unsafe returned false")
+	}
+
+	td, err := ats.TableDiff(FlavorMySQL57)
+	if err != nil {
+		t.Fatalf("Unexpected error from TableDiff: %s", err)
+	}
+	if _, err := td.Statement(ats.Modifiers(StatementModifiers{})); !IsForbiddenDiff(err) {
+		t.Errorf("Expected ForbiddenDiffError without AllowUnsafe, instead err=%v", err)
+	}
+	mods := ats.Modifiers(StatementModifiers{AllowUnsafe: true, LockClause: "SHARED"})
+	result, err := td.Statement(mods)
+	if err != nil {
+		t.Fatalf("Unexpected error from Statement: %s", err)
+	}
+	expected := "ALTER TABLE `actor` ALGORITHM=INPLACE, LOCK=NONE, ADD COLUMN `age` int(10) unsigned NOT NULL DEFAULT '0' AFTER `last_name`, " +
+		"MODIFY COLUMN `first_name` varchar(60) NOT NULL, DROP COLUMN `alive_bit`, ADD KEY `age` (`age`), " +
+		"ADD UNIQUE KEY `idx_name` (`last_name`(10),`first_name`), DROP KEY `idx_actor_name`, " +
+		"MODIFY COLUMN `alive` tinyint(1) NOT NULL DEFAULT '0', COMMENT 'actors'"
+	if result != expected {
+		t.Errorf("Unexpected statement.\nExpected: %s\nFound:    %s", expected, result)
+	}
+	if td.To.Comment != "actors" || td.To.Columns[3].Name != "age" || len(td.To.SecondaryIndexes) != 3 {
+		t.Errorf("Unexpected result of applying statement:\n%s", td.To.CreateStatement)
+	}
+}
+
+func TestParseAlterTableRoundTrip(t *testing.T) {
+	// Statements generated by TableDiff.Statement should parse back into clauses
+	// which yield the same table when applied
+	from := aTable(1)
+	from.CreateStatement = from.GeneratedCreateStatement(FlavorUnknown)
+	mutations := []func(to *Table){
+		func(to *Table) {
+			to.Columns = append(to.Columns, &Column{Name: "bio", TypeInDB: "text", Nullable: true, Default: ColumnDefaultNull, CharSet: "utf8mb4", Collation: "utf8mb4_unicode_ci", Comment: "about"})
+			to.Columns[1].TypeInDB = "varchar(80)"
+		},
+		func(to *Table) {
+			to.Columns = append(to.Columns[0:1], to.Columns[2:]...)
+			to.SecondaryIndexes = to.SecondaryIndexes[0:1]
+			to.NextAutoIncrement = 100
+			to.CreateOptions = "KEY_BLOCK_SIZE=8 ROW_FORMAT=COMPRESSED"
+		},
+		func(to *Table) {
+			to.PrimaryKey = primaryKey(to.Columns[0], to.Columns[4])
+			to.SecondaryIndexes = append(to.SecondaryIndexes, &Index{Name: "alive", Columns: to.Columns[5:6], SubParts: []uint16{0}, Comment: "hi"})
+			to.CharSet, to.Collation = "latin1", "latin1_swedish_ci"
+			to.Engine = "MyISAM"
+		},
+	}
+	for n, mutate := range mutations {
+		to := aTable(1)
+		mutate(&to)
+		to.CreateStatement = to.GeneratedCreateStatement(FlavorUnknown)
+		td := NewAlterTable(&from, &to)
+		stmt, err := td.Statement(StatementModifiers{AllowUnsafe: true, NextAutoInc: NextAutoIncAlways})
+		if err != nil {
+			t.Fatalf("Case %d: Unexpected error from Statement: %s", n, err)
+		}
+		ats, err := ParseAlterTable(stmt, &from, FlavorUnknown)
+		if err != nil {
+			t.Errorf("Case %d: Unexpected error parsing %s: %s", n, stmt, err)
+			continue
+		}
+		result, err := from.Apply(FlavorUnknown, ats.Clauses...)
+		if err != nil {
+			t.Errorf("Case %d: Unexpected error from Apply: %s", n, err)
+		} else if result.CreateStatement != to.CreateStatement {
+			t.Errorf("Case %d: Parsing and applying %s did not yield expected table.\nExpected:\n%s\nFound:\n%s", n, stmt, to.CreateStatement, result.CreateStatement)
+		}
+	}
+}
+
+func TestParseAlterTableColumnDefinitions(t *testing.T) {
+	from := aTable(1)
+	cases := map[string]Column{
+		"flag boolean":                         {TypeInDB: "tinyint(1)", Nullable: true, Default: ColumnDefaultNull},
+		"n integer unsigned zerofill NOT NULL": {TypeInDB: "int(10) unsigned zerofill", Default: ColumnDefaultNull},
+		"n bigint default -5":                  {TypeInDB: "bigint(20)", Nullable: true, Default: ColumnDefaultValue("-5")},
+		"n numeric":                            {TypeInDB: "decimal(10,0)", Nullable: true, Default: ColumnDefaultNull},
+		"b bit default b'0'":                   {TypeInDB: "bit(1)", Nullable: true, Default: ColumnDefaultExpression("b'0'")},
+		"ts timestamp(3) NOT NULL DEFAULT now(3) ON UPDATE current_timestamp(3)": {TypeInDB: "timestamp(3)", Default: ColumnDefaultExpression("CURRENT_TIMESTAMP(3)"), OnUpdate: "CURRENT_TIMESTAMP(3)"},
+		"e ENUM('a', 'b''c') NOT NULL DEFAULT 'a'":                               {TypeInDB: "enum('a','b''c')", Default: ColumnDefaultValue("a"), CharSet: "utf8", Collation: "utf8_general_ci", CollationIsDefault: true},
+		"s varchar(10) COLLATE latin1_bin COMMENT 'hello'":                       {TypeInDB: "varchar(10)", Nullable: true, Default: ColumnDefaultNull, CharSet: "latin1", Collation: "latin1_bin", Comment: "hello"},
+		"s char CHARACTER SET utf8mb4":                                           {TypeInDB: "char(1)", Nullable: true, Default: ColumnDefaultNull, CharSet: "utf8mb4", Collation: "utf8mb4_general_ci", CollationIsDefault: true},
+		"d double precision default 1.5":                                         {TypeInDB: "double", Nullable: true, Default: ColumnDefaultValue("1.5")},
+	}
+	for def, expected := range cases {
+		ats, err := ParseAlterTable("ALTER TABLE actor ADD COLUMN "+def, &from, FlavorMySQL57)
+		if err != nil {
+			t.Errorf("Unexpected error parsing column definition %q: %s", def, err)
+			continue
+		}
+		col := ats.Clauses[0].(AddColumn).Column
+		expected.Name = col.Name
+		if !col.Equals(&expected) {
+			t.Errorf("Column definition %q parsed to unexpected result %+v", def, *col)
+		}
+	}
+
+	// On MariaDB 10.2+, numeric defaults are expressions and CURRENT_TIMESTAMP
+	// is lowercase
+	ats, err := ParseAlterTable("ALTER TABLE actor ADD ts timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP, ADD n int DEFAULT TRUE", &from, FlavorMariaDB102)
+	if err != nil {
+		t.Fatalf("Unexpected error from ParseAlterTable: %s", err)
+	}
+	if col := ats.Clauses[0].(AddColumn).Column; col.Default != ColumnDefaultExpression("current_timestamp") {
+		t.Errorf("Unexpected default %+v", col.Default)
+	}
+	if col := ats.Clauses[1].(AddColumn).Column; col.Default != ColumnDefaultExpression("1") {
+		t.Errorf("Unexpected default %+v", col.Default)
+	}
+}
+
+func TestParseAlterTableIntDisplayWidth(t *testing.T) {
+	// Int display widths are omitted if the table's existing int columns omit
+	// them, or if there are no such columns and the flavor omits them
+	withWidths := aTable(1)
+	withoutWidths := aTable(1)
+	withoutWidths.Columns[0] = &Column{Name: "actor_id", TypeInDB: "smallint unsigned"}
+	noInts := Table{Name: "noints", Columns: []*Column{{Name: "s", TypeInDB: "varchar(10)"}}}
+	cases := []struct {
+		table   *Table
+		flavor  Flavor
+		omitted bool
+	}{
+		{&withWidths, FlavorMySQL80, false},
+		{&withoutWidths, FlavorMySQL80, true},
+		{&withoutWidths, FlavorMySQL57, true},
+		{&noInts, FlavorMySQL80, true},
+		{&noInts, FlavorMySQL57, false},
+		{&noInts, FlavorMariaDB103, false},
+	}
+	for _, c := range cases {
+		ats, err := ParseAlterTable("ALTER TABLE "+EscapeIdentifier(c.table.Name)+" ADD a INT, ADD b INT(5), ADD c tinyint(1), ADD d tinyint(2) unsigned, ADD e INT ZEROFILL", c.table, c.flavor)
+		if err != nil {
+			t.Fatalf("Unexpected error from ParseAlterTable: %s", err)
+		}
+		expected := []string{"int(11)", "int(5)", "tinyint(1)", "tinyint(2) unsigned", "int(10) unsigned zerofill"}
+		if c.omitted {
+			expected = []string{"int", "int", "tinyint(1)", "tinyint unsigned", "int(10) unsigned zerofill"}
+		}
+		for n, clause := range ats.Clauses {
+			if actual := clause.(AddColumn).Column.TypeInDB; actual != expected[n] {
+				t.Errorf("Table %s with flavor %s: expected column %d type %q, instead found %q", c.table.Name, c.flavor, n, expected[n], actual)
+			}
+		}
+	}
+}
+
+func TestParseAlterTableRename(t *testing.T) {
+	from := aTable(1)
+	for _, stmt := range []string{
+		"ALTER TABLE actor RENAME COLUMN ssn TO social",
+		"ALTER TABLE actor CHANGE COLUMN SSN social char(10) NOT NULL",
+		"ALTER TABLE `actor` CHANGE `ssn` `social` char(10) CHARACTER SET utf8 NOT NULL",
+	} {
+		ats, err := ParseAlterTable(stmt, &from, FlavorMySQL57)
+		if err != nil {
+			t.Errorf("Unexpected error parsing %s: %s", stmt, err)
+			continue
+		}
+		if rc, ok := ats.Clauses[0].(RenameColumn); !ok || rc.OldColumn != from.Columns[4] || rc.NewName != "social" {
+			t.Errorf("Unexpected clause from %s: %+v", stmt, ats.Clauses[0])
+		}
+	}
+
+	// CHANGE COLUMN that retains the name is equivalent to MODIFY COLUMN
+	ats, err := ParseAlterTable("ALTER TABLE actor CHANGE ssn ssn char(12) NOT NULL FIRST", &from, FlavorMySQL57)
+	if err != nil {
+		t.Fatalf("Unexpected error from ParseAlterTable: %s", err)
+	}
+	if mc, ok := ats.Clauses[0].(ModifyColumn); !ok || mc.NewColumn.TypeInDB != "char(12)" || !mc.PositionFirst {
+		t.Errorf("Unexpected clause: %+v", ats.Clauses[0])
+	}
+}
+
+func TestParseAlterTableForeignKeys(t *testing.T) {
+	from := foreignKeyTable()
+	stmt := "ALTER TABLE purchasing.orders DROP FOREIGN KEY customer_fk, " +
+		"ADD FOREIGN KEY (model) REFERENCES products (id) ON DELETE CASCADE, " +
+		"ADD CONSTRAINT cust_fk FOREIGN KEY (customer_id) REFERENCES purchasing.customers (id) ON UPDATE NO ACTION ON DELETE SET NULL"
+	from.Name = "orders"
+	ats, err := ParseAlterTable(stmt, &from, FlavorMySQL57)
+	if err != nil {
+		t.Fatalf("Unexpected error from ParseAlterTable: %s", err)
+	}
+	if ats.SchemaName != "purchasing" || len(ats.Clauses) != 3 {
+		t.Fatalf("Unexpected result: %+v", *ats)
+	}
+	fk := ats.Clauses[1].(AddForeignKey).ForeignKey
+	if fk.Name != "orders_ibfk_1" || fk.ReferencedTableName != "products" || fk.DeleteRule != "CASCADE" || fk.UpdateRule != "RESTRICT" {
+		t.Errorf("Unexpected foreign key: %+v", *fk)
+	}
+	fk = ats.Clauses[2].(AddForeignKey).ForeignKey
+	if fk.Name != "cust_fk" || fk.ReferencedSchemaName != "" || fk.DeleteRule != "SET NULL" || fk.UpdateRule != "NO ACTION" {
+		t.Errorf("Unexpected foreign key: %+v", *fk)
+	}
+	if _, err := ats.TableDiff(FlavorMySQL57); err != nil {
+		t.Errorf("Unexpected error from TableDiff: %s", err)
+	}
+}
+
+func TestParseAlterTableErrors(t *testing.T) {
+	from := aTable(1)
+	stmts := []string{
+		"CREATE TABLE actor (id int)",
+		"ALTER TABLE other ADD COLUMN x int",
+		"ALTER TABLE actor",
+		"ALTER TABLE actor ADD COLUMN first_name int",
+		"ALTER TABLE actor DROP COLUMN nope",
+		"ALTER TABLE actor DROP INDEX nope",
+		"ALTER TABLE actor DROP FOREIGN KEY nope",
+		"ALTER TABLE actor MODIFY nope int",
+		"ALTER TABLE actor ADD COLUMN x int AFTER nope",
+		"ALTER TABLE actor ADD INDEX idx_ssn (first_name)",
+		"ALTER TABLE actor ADD INDEX (nope)",
+		"ALTER TABLE actor ADD PRIMARY KEY (ssn)",
+		"ALTER TABLE actor RENAME TO actress",
+		"ALTER TABLE actor RENAME COLUMN ssn TO alive",
+		"ALTER TABLE actor CHANGE ssn social char(12) NOT NULL",
+		"ALTER TABLE actor CONVERT TO CHARACTER SET utf8mb4",
+		"ALTER TABLE actor ADD FULLTEXT INDEX ft (first_name)",
+		"ALTER TABLE actor ADD INDEX (first_name DESC)",
+		"ALTER TABLE actor ADD COLUMN x int AS (actor_id + 1)",
+		"ALTER TABLE actor ADD COLUMN x int PRIMARY KEY",
+		"ALTER TABLE actor ADD (x int, y int)",
+		"ALTER TABLE actor PARTITION BY HASH(actor_id) PARTITIONS 4",
+		"ALTER TABLE actor ADD COLUMN x int DEFAULT (rand())",
+		"ALTER TABLE actor ADD COLUMN x int, ",
+		"ALTER TABLE actor COMMENT 'unterminated",
+		"ALTER TABLE actor AUTO_INCREMENT=-1",
+		"ALTER TABLE actor ENGINE=InnoDB ENGINE",
+	}
+	for _, stmt := range stmts {
+		if _, err := ParseAlterTable(stmt, &from, FlavorMySQL57); err == nil {
+			t.Errorf("Expected error parsing %s, but err was nil", stmt)
+		}
+	}
+}
+
+func TestParseAlterTableOptions(t *testing.T) {
+	from := aTable(20)
+	from.CreateOptions = "ROW_FORMAT=DYNAMIC STATS_PERSISTENT=1"
+	stmt := "/* hi */ ALTER TABLE actor ENGINE=MyISAM DEFAULT CHARSET utf8mb4 COLLATE=utf8mb4_bin, AUTO_INCREMENT 50, " +
+		"row_format=default key_block_size=8, ADD COLUMN note varchar(20) -- trailing\n /*!50100 , ALGORITHM=COPY */"
+	ats, err := ParseAlterTable(stmt, &from, FlavorMySQL57)
+	if err != nil {
+		t.Fatalf("Unexpected error from ParseAlterTable: %s", err)
+	}
+	if len(ats.Clauses) != 5 || ats.AlgorithmClause != "COPY" {
+		t.Fatalf("Unexpected result: %+v", *ats)
+	}
+	if ats.Clauses[0] != (ChangeStorageEngine{NewStorageEngine: "MyISAM"}) {
+		t.Errorf("Unexpected clause: %+v", ats.Clauses[0])
+	}
+	if ats.Clauses[1] != (ChangeCharSet{CharSet: "utf8mb4", Collation: "utf8mb4_bin"}) {
+		t.Errorf("Unexpected clause: %+v", ats.Clauses[1])
+	}
+	if ats.Clauses[2] != (ChangeAutoIncrement{OldNextAutoIncrement: 20, NewNextAutoIncrement: 50}) {
+		t.Errorf("Unexpected clause: %+v", ats.Clauses[2])
+	}
+	if col := ats.Clauses[3].(AddColumn).Column; col.CharSet != "utf8mb4" || col.Collation != "utf8mb4_bin" || col.CollationIsDefault {
+		t.Errorf("New column did not inherit new table default charset and collation: %+v", *col)
+	}
+	expected := ChangeCreateOptions{OldCreateOptions: from.CreateOptions, NewCreateOptions: "STATS_PERSISTENT=1 KEY_BLOCK_SIZE=8"}
+	if ats.Clauses[4] != expected {
+		t.Errorf("Unexpected clause: %+v", ats.Clauses[4])
+	}
+	if !ats.Unsafe() {
+		t.Error("Expected statement changing storage engine to be unsafe, but Unsafe returned false")
+	}
+}
+
+func TestTokenizeSQL(t *testing.T) {
+	tokens, err := tokenizeSQL("SELECT `a``b`, 'it''s', \"x\\ty\", b'01', 1.5e3, 2E-5-0x1e-1, x # comment\n-- another\n/* block */ /*!80000 FROM */ t;")
+	if err != nil {
+		t.Fatalf("Unexpected error from tokenizeSQL: %s", err)
+	}
+	expected := []sqlToken{
+		{sqlTokenWord, "SELECT"},
+		{sqlTokenIdentifier, "a`b"},
+		{sqlTokenSymbol, ","},
+		{sqlTokenString, "it's"},
+		{sqlTokenSymbol, ","},
+		{sqlTokenString, "x\ty"},
+		{sqlTokenSymbol, ","},
+		{sqlTokenWord, "b'01'"},
+		{sqlTokenSymbol, ","},
+		{sqlTokenWord, "1.5e3"},
+		{sqlTokenSymbol, ","},
+		{sqlTokenWord, "2E-5"},
+		{sqlTokenSymbol, "-"},
+		{sqlTokenWord, "0x1e"},
+		{sqlTokenSymbol, "-"},
+		{sqlTokenWord, "1"},
+		{sqlTokenSymbol, ","},
+		{sqlTokenWord, "x"},
+		{sqlTokenWord, "FROM"},
+		{sqlTokenWord, "t"},
+		{sqlTokenSymbol, ";"},
+	}
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected %d tokens, instead found %d: %v", len(expected), len(tokens), tokens)
+	}
+	for n := range tokens {
+		if tokens[n] != expected[n] {
+			t.Errorf("Token %d: expected %+v, found %+v", n, expected[n], tokens[n])
+		}
+	}
+
+	for _, input := range []string{"'unterminated", "`unterminated", "/* unterminated", "x'ff"} {
+		if _, err := tokenizeSQL(input); err == nil {
+			t.Errorf("Expected error tokenizing %q, but err was nil", input)
+		}
+	}
+}
+
+func TestDoubleQuotedStrings(t *testing.T) {
+	input := "SELECT \"a\", 'b \"not\"', `c\"`, \"d\"\"e\" # \"comment\"\n/* \"block\" */ /*!50000 \"f\" */"
+	expected := []string{"a", "d\"e", "f"}
+	if actual, err := doubleQuotedStrings(input); err != nil || !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Unexpected result from doubleQuotedStrings: %v / %v", actual, err)
+	}
+	if _, err := doubleQuotedStrings("SELECT \"unterminated"); err == nil {
+		t.Error("Expected error from doubleQuotedStrings with unterminated string, but err was nil")
+	}
+}
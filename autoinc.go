@@ -0,0 +1,183 @@
+package tengo
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// intTypeRanks lists MySQL's integer types in order of increasing storage
+// size, along with the conventional display widths shown by SHOW CREATE TABLE
+// for the signed and unsigned variants of each type.
+var intTypeRanks = []struct {
+	name          string
+	bits          uint
+	signedWidth   int
+	unsignedWidth int
+}{
+	{"tinyint", 8, 4, 3},
+	{"smallint", 16, 6, 5},
+	{"mediumint", 24, 9, 8},
+	{"int", 32, 11, 10},
+	{"bigint", 64, 20, 20},
+}
+
+// intTypeRank returns the position of the column's integer type in
+// intTypeRanks, and whether the type is unsigned. If the column is not an
+// integer type, the returned rank is -1.
+func (c *Column) intTypeRank() (rank int, unsigned bool) {
+	typ := strings.ToLower(c.TypeInDB)
+	base := typ
+	if end := strings.IndexAny(typ, "( "); end > -1 {
+		base = typ[0:end]
+	}
+	for n, it := range intTypeRanks {
+		if base == it.name {
+			return n, strings.Contains(typ, " unsigned")
+		}
+	}
+	return -1, false
+}
+
+// MaxIntValue returns the maximum value that may be stored in the column, if
+// it is an integer type. The second return value is false if the column is not
+// an integer type.
+func (c *Column) MaxIntValue() (uint64, bool) {
+	rank, unsigned := c.intTypeRank()
+	if rank < 0 {
+		return 0, false
+	}
+	bits := intTypeRanks[rank].bits
+	if !unsigned {
+		bits--
+	}
+	if bits == 64 {
+		return math.MaxUint64, true
+	}
+	return (uint64(1) << bits) - 1, true
+}
+
+// AutoIncrementUsage describes how much of an auto-increment column's value
+// range has been consumed.
+type AutoIncrementUsage struct {
+	SchemaName        string
+	Table             *Table
+	Column            *Column
+	MaxValue          uint64
+	NextAutoIncrement uint64
+	PercentUsed       float64
+	OverThreshold     bool // only set by Instance.AutoIncrementReport
+}
+
+func (aiu *AutoIncrementUsage) String() string {
+	return fmt.Sprintf("%s.%s.%s: %.2f%% used (next value %d of max %d)",
+		EscapeIdentifier(aiu.SchemaName),
+		EscapeIdentifier(aiu.Table.Name),
+		EscapeIdentifier(aiu.Column.Name),
+		aiu.PercentUsed,
+		aiu.NextAutoIncrement,
+		aiu.MaxValue)
+}
+
+// WidenClause returns a ModifyColumn clause which changes the auto-increment
+// column to the next-larger integer type, keeping the same signedness. If the
+// column is already a signed bigint, the clause changes it to an unsigned
+// bigint instead. The second return value is false if the column cannot be
+// widened any further.
+func (aiu *AutoIncrementUsage) WidenClause() (ModifyColumn, bool) {
+	rank, unsigned := aiu.Column.intTypeRank()
+	if rank < 0 || (rank == len(intTypeRanks)-1 && unsigned) {
+		return ModifyColumn{}, false
+	}
+	if rank < len(intTypeRanks)-1 {
+		rank++
+	} else {
+		unsigned = true
+	}
+	newType := intTypeRanks[rank].name
+	if strings.ContainsRune(aiu.Column.TypeInDB, '(') {
+		width := intTypeRanks[rank].signedWidth
+		if unsigned {
+			width = intTypeRanks[rank].unsignedWidth
+		}
+		newType = fmt.Sprintf("%s(%d)", newType, width)
+	}
+	if unsigned {
+		newType += " unsigned"
+	}
+	if strings.Contains(strings.ToLower(aiu.Column.TypeInDB), " zerofill") {
+		newType += " zerofill"
+	}
+	newCol := *aiu.Column
+	newCol.TypeInDB = newType
+	return ModifyColumn{
+		Table:     aiu.Table,
+		OldColumn: aiu.Column,
+		NewColumn: &newCol,
+	}, true
+}
+
+// AutoIncrementUsage returns information on the table's auto-increment column,
+// or nil if the table has no auto-increment column of integer type.
+func (t *Table) AutoIncrementUsage() *AutoIncrementUsage {
+	for _, col := range t.Columns {
+		if !col.AutoIncrement {
+			continue
+		}
+		maxValue, ok := col.MaxIntValue()
+		if !ok {
+			return nil
+		}
+		// NextAutoIncrement is the value that will be used for the next row, so
+		// the values consumed so far are NextAutoIncrement-1
+		var used uint64
+		if t.NextAutoIncrement > 0 {
+			used = t.NextAutoIncrement - 1
+		}
+		return &AutoIncrementUsage{
+			Table:             t,
+			Column:            col,
+			MaxValue:          maxValue,
+			NextAutoIncrement: t.NextAutoIncrement,
+			PercentUsed:       100.0 * float64(used) / float64(maxValue),
+		}
+	}
+	return nil
+}
+
+// AutoIncrementUsage returns information on every auto-increment column in
+// the schema's tables.
+func (s *Schema) AutoIncrementUsage() []*AutoIncrementUsage {
+	var result []*AutoIncrementUsage
+	for _, t := range s.Tables {
+		if aiu := t.AutoIncrementUsage(); aiu != nil {
+			aiu.SchemaName = s.Name
+			result = append(result, aiu)
+		}
+	}
+	return result
+}
+
+// AutoIncrementReport introspects the instance's schemas and returns usage
+// information for every auto-increment column, sorted by descending
+// PercentUsed. Columns with a PercentUsed at or above threshold will have
+// OverThreshold set to true. If no schema names are supplied, all non-system
+// schemas are included.
+func (instance *Instance) AutoIncrementReport(threshold float64, onlyNames ...string) ([]*AutoIncrementUsage, error) {
+	schemas, err := instance.Schemas(onlyNames...)
+	if err != nil {
+		return nil, err
+	}
+	result := []*AutoIncrementUsage{}
+	for _, s := range schemas {
+		for _, aiu := range s.AutoIncrementUsage() {
+			aiu.OverThreshold = (aiu.PercentUsed >= threshold)
+			result = append(result, aiu)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].PercentUsed > result[j].PercentUsed
+	})
+	return result, nil
+}
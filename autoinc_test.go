@@ -0,0 +1,129 @@
+package tengo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestColumnMaxIntValue(t *testing.T) {
+	cases := map[string]uint64{
+		"tinyint(4)":                127,
+		"tinyint(3) unsigned":       255,
+		"smallint":                  32767,
+		"smallint(5) unsigned":      65535,
+		"mediumint(9)":              8388607,
+		"mediumint(8) unsigned":     16777215,
+		"int(11)":                   2147483647,
+		"int(10) unsigned zerofill": 4294967295,
+		"bigint(20)":                math.MaxInt64,
+		"bigint(20) unsigned":       math.MaxUint64,
+		"BIGINT UNSIGNED":           math.MaxUint64,
+	}
+	for typ, expected := range cases {
+		col := &Column{TypeInDB: typ}
+		if actual, ok := col.MaxIntValue(); !ok || actual != expected {
+			t.Errorf("Expected MaxIntValue for %s to return %d,true; instead found %d,%t", typ, expected, actual, ok)
+		}
+	}
+	for _, typ := range []string{"varchar(20)", "decimal(10,2)", "integer_ish", "float"} {
+		col := &Column{TypeInDB: typ}
+		if _, ok := col.MaxIntValue(); ok {
+			t.Errorf("Expected MaxIntValue for %s to return false, but it returned true", typ)
+		}
+	}
+}
+
+func TestTableAutoIncrementUsage(t *testing.T) {
+	table := anotherTable()
+	if aiu := table.AutoIncrementUsage(); aiu != nil {
+		t.Errorf("Expected table without auto-inc column to return nil, instead found %v", aiu)
+	}
+
+	table = aTable(32768)
+	aiu := table.AutoIncrementUsage()
+	if aiu == nil {
+		t.Fatal("Unexpected nil result from AutoIncrementUsage")
+	}
+	if aiu.Column != table.Columns[0] || aiu.MaxValue != 65535 || aiu.NextAutoIncrement != 32768 {
+		t.Errorf("Unexpected result from AutoIncrementUsage: %+v", *aiu)
+	}
+	if aiu.PercentUsed < 49.99 || aiu.PercentUsed > 50.01 {
+		t.Errorf("Expected usage to be 50%%, instead found %f", aiu.PercentUsed)
+	}
+
+	s := aSchema("s1", &table)
+	usage := s.AutoIncrementUsage()
+	if len(usage) != 1 || usage[0].SchemaName != "s1" {
+		t.Errorf("Unexpected result from Schema.AutoIncrementUsage: %v", usage)
+	}
+
+	clause, ok := aiu.WidenClause()
+	if !ok {
+		t.Fatal("Expected WidenClause to succeed, but it returned false")
+	}
+	expected := "MODIFY COLUMN `actor_id` mediumint(8) unsigned NOT NULL AUTO_INCREMENT"
+	if actual := clause.Clause(StatementModifiers{}); actual != expected {
+		t.Errorf("Unexpected widen clause: expected %s, found %s", expected, actual)
+	}
+	if clause.Unsafe() {
+		t.Error("Expected widen clause to be safe, but Unsafe() returned true")
+	}
+	if table.Columns[0].TypeInDB != "smallint(5) unsigned" {
+		t.Error("WidenClause unexpectedly modified the original column")
+	}
+
+	widenCases := map[string]string{
+		"int":                 "bigint",
+		"int(11)":             "bigint(20)",
+		"bigint(20)":          "bigint(20) unsigned",
+		"tinyint(3) unsigned": "smallint(5) unsigned",
+	}
+	for from, to := range widenCases {
+		table.Columns[0].TypeInDB = from
+		if clause, ok := table.AutoIncrementUsage().WidenClause(); !ok || clause.NewColumn.TypeInDB != to {
+			t.Errorf("Expected %s to widen to %s, instead found %s", from, to, clause.NewColumn.TypeInDB)
+		}
+	}
+	table.Columns[0].TypeInDB = "bigint(20) unsigned"
+	if _, ok := table.AutoIncrementUsage().WidenClause(); ok {
+		t.Error("Expected bigint unsigned to not be widenable, but WidenClause returned true")
+	}
+}
+
+func (s TengoIntegrationSuite) TestInstanceAutoIncrementReport(t *testing.T) {
+	report, err := s.d.AutoIncrementReport(90, "testing")
+	if err != nil {
+		t.Fatalf("Unexpected error from AutoIncrementReport: %s", err)
+	}
+	var foundGrabBag bool
+	for n, aiu := range report {
+		if aiu.SchemaName != "testing" {
+			t.Errorf("Unexpected schema in report: %s", aiu)
+		}
+		if n > 0 && aiu.PercentUsed > report[n-1].PercentUsed {
+			t.Errorf("Report not sorted by descending usage: %s after %s", aiu, report[n-1])
+		}
+		if aiu.Table.Name == "grab_bag" {
+			foundGrabBag = true
+			if aiu.NextAutoIncrement != 123 || aiu.MaxValue != math.MaxUint64 || aiu.OverThreshold {
+				t.Errorf("Unexpected usage for grab_bag: %s", aiu)
+			}
+		}
+	}
+	if !foundGrabBag {
+		t.Error("Expected grab_bag to be included in report, but it was not")
+	}
+
+	if _, err := s.d.SourceSQL("testdata/autoinc.sql"); err != nil {
+		t.Fatalf("Unexpected error sourcing testdata/autoinc.sql: %s", err)
+	}
+	report, err = s.d.AutoIncrementReport(90, "testing")
+	if err != nil {
+		t.Fatalf("Unexpected error from AutoIncrementReport: %s", err)
+	}
+	for n, aiu := range report {
+		if (n == 0) != aiu.OverThreshold || (n == 0 && aiu.Table.Name != "nearly_full") {
+			t.Errorf("Expected only nearly_full table to be over threshold, instead found %s with OverThreshold=%t", aiu, aiu.OverThreshold)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package tengo
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+)
+
+// dictSection is a single table or routine's section of a data dictionary,
+// independent of output format.
+type dictSection struct {
+	Heading    string
+	Comment    string
+	Properties [][2]string // name/value pairs, in display order
+	Grids      []dictGrid
+}
+
+// dictGrid is a tabular portion of a dictSection, such as the list of columns
+// of a table.
+type dictGrid struct {
+	Caption string
+	Header  []string
+	Rows    [][]string
+}
+
+// dictionarySections returns the sections of a data dictionary for the
+// schema: one per table, sorted by name, followed by one per routine, sorted
+// by type and then name.
+func (s *Schema) dictionarySections() []dictSection {
+	tables := make([]*Table, len(s.Tables))
+	copy(tables, s.Tables)
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].Name < tables[j].Name
+	})
+	routines := make([]*Routine, len(s.Routines))
+	copy(routines, s.Routines)
+	sort.Slice(routines, func(i, j int) bool {
+		if routines[i].Type != routines[j].Type {
+			return routines[i].Type > routines[j].Type // procedures before functions
+		}
+		return routines[i].Name < routines[j].Name
+	})
+
+	sections := make([]dictSection, 0, len(tables)+len(routines))
+	for _, t := range tables {
+		sections = append(sections, t.dictionarySection())
+	}
+	for _, r := range routines {
+		sections = append(sections, r.dictionarySection())
+	}
+	return sections
+}
+
+// dictionarySection returns the table's data dictionary section.
+func (t *Table) dictionarySection() dictSection {
+	section := dictSection{
+		Heading: fmt.Sprintf("Table %s", t.Name),
+		Comment: t.Comment,
+		Properties: [][2]string{
+			{"Engine", t.Engine},
+			{"Character set", t.CharSet},
+			{"Collation", t.Collation},
+			{"Create options", t.CreateOptions},
+		},
+	}
+
+	columns := dictGrid{
+		Caption: "Columns",
+		Header:  []string{"Name", "Type", "Nullable", "Default", "Extra", "Comment"},
+	}
+	for _, col := range t.Columns {
+		nullable := "NO"
+		if col.Nullable {
+			nullable = "YES"
+		}
+		var extra []string
+		if col.AutoIncrement {
+			extra = append(extra, "AUTO_INCREMENT")
+		}
+		if col.OnUpdate != "" {
+			extra = append(extra, fmt.Sprintf("ON UPDATE %s", col.OnUpdate))
+		}
+		def := strings.TrimPrefix(col.Default.Clause(FlavorUnknown, col), " DEFAULT ")
+		columns.Rows = append(columns.Rows, []string{col.Name, col.TypeInDB, nullable, def, strings.Join(extra, " "), col.Comment})
+	}
+	section.Grids = append(section.Grids, columns)
+
+	indexes := dictGrid{
+		Caption: "Indexes",
+		Header:  []string{"Name", "Type", "Columns", "Comment"},
+	}
+	allIndexes := t.SecondaryIndexes
+	if t.PrimaryKey != nil {
+		allIndexes = append([]*Index{t.PrimaryKey}, allIndexes...)
+	}
+	for _, idx := range allIndexes {
+		idxType := "INDEX"
+		if idx.PrimaryKey {
+			idxType = "PRIMARY"
+		} else if idx.Unique {
+			idxType = "UNIQUE"
+		}
+		colParts := make([]string, len(idx.Columns))
+		for n, col := range idx.Columns {
+			colParts[n] = col.Name
+			if n < len(idx.SubParts) && idx.SubParts[n] > 0 {
+				colParts[n] = fmt.Sprintf("%s(%d)", col.Name, idx.SubParts[n])
+			}
+		}
+		indexes.Rows = append(indexes.Rows, []string{idx.Name, idxType, strings.Join(colParts, ", "), idx.Comment})
+	}
+	if len(indexes.Rows) > 0 {
+		section.Grids = append(section.Grids, indexes)
+	}
+
+	fks := dictGrid{
+		Caption: "Foreign keys",
+		Header:  []string{"Name", "Columns", "References", "On update", "On delete"},
+	}
+	for _, fk := range t.ForeignKeys {
+		colNames := make([]string, len(fk.Columns))
+		for n, col := range fk.Columns {
+			colNames[n] = col.Name
+		}
+		refTable := fk.ReferencedTableName
+		if fk.ReferencedSchemaName != "" {
+			refTable = fmt.Sprintf("%s.%s", fk.ReferencedSchemaName, refTable)
+		}
+		references := fmt.Sprintf("%s (%s)", refTable, strings.Join(fk.ReferencedColumnNames, ", "))
+		fks.Rows = append(fks.Rows, []string{fk.Name, strings.Join(colNames, ", "), references, fk.UpdateRule, fk.DeleteRule})
+	}
+	if len(fks.Rows) > 0 {
+		section.Grids = append(section.Grids, fks)
+	}
+	return section
+}
+
+// dictionarySection returns the routine's data dictionary section.
+func (r *Routine) dictionarySection() dictSection {
+	deterministic := "NO"
+	if r.Deterministic {
+		deterministic = "YES"
+	}
+	section := dictSection{
+		Heading: fmt.Sprintf("%s %s", strings.Title(string(r.Type)), r.Name),
+		Comment: r.Comment,
+	}
+	if r.Type == ObjectTypeFunc {
+		section.Properties = append(section.Properties, [2]string{"Returns", r.ReturnDataType})
+	}
+	section.Properties = append(section.Properties,
+		[2]string{"SQL data access", r.SQLDataAccess},
+		[2]string{"Deterministic", deterministic},
+		[2]string{"SQL security", r.SecurityType},
+		[2]string{"Definer", r.Definer},
+	)
+
+	// If the parameter list cannot be parsed, fall back to displaying it as-is
+	params, err := r.Params()
+	if err != nil {
+		section.Properties = append([][2]string{{"Parameters", r.ParamString}}, section.Properties...)
+		return section
+	}
+	grid := dictGrid{
+		Caption: "Parameters",
+		Header:  []string{"Name", "Type"},
+	}
+	if r.Type == ObjectTypeProc {
+		grid.Header = []string{"Mode", "Name", "Type"}
+	}
+	for _, param := range params {
+		typeParts := []string{param.TypeInDB}
+		if param.CharSet != "" {
+			typeParts = append(typeParts, fmt.Sprintf("CHARACTER SET %s", param.CharSet))
+		}
+		if param.Collation != "" {
+			typeParts = append(typeParts, fmt.Sprintf("COLLATE %s", param.Collation))
+		}
+		row := []string{param.Name, strings.Join(typeParts, " ")}
+		if r.Type == ObjectTypeProc {
+			row = append([]string{param.Mode}, row...)
+		}
+		grid.Rows = append(grid.Rows, row)
+	}
+	if len(grid.Rows) > 0 {
+		section.Grids = append(section.Grids, grid)
+	}
+	return section
+}
+
+// MarkdownDictionary returns a data dictionary for the schema in Markdown
+// format. The output contains one section per table, listing its columns,
+// indexes, foreign keys, and table options; followed by one section per
+// routine. Output is deterministic, making it suitable for storing in version
+// control.
+func (s *Schema) MarkdownDictionary() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# Schema %s\n\n", markdownEscape(s.Name))
+	fmt.Fprintf(&b, "Character set: %s  \nCollation: %s\n", markdownEscape(s.CharSet), markdownEscape(s.Collation))
+	for _, section := range s.dictionarySections() {
+		fmt.Fprintf(&b, "\n## %s\n\n", markdownEscape(section.Heading))
+		if section.Comment != "" {
+			fmt.Fprintf(&b, "%s\n\n", markdownEscape(section.Comment))
+		}
+		for _, prop := range section.Properties {
+			if prop[1] != "" {
+				fmt.Fprintf(&b, "* %s: %s\n", prop[0], markdownEscape(prop[1]))
+			}
+		}
+		for _, grid := range section.Grids {
+			fmt.Fprintf(&b, "\n### %s\n\n", grid.Caption)
+			fmt.Fprintf(&b, "| %s |\n", strings.Join(grid.Header, " | "))
+			fmt.Fprintf(&b, "|%s\n", strings.Repeat(" --- |", len(grid.Header)))
+			for _, row := range grid.Rows {
+				cells := make([]string, len(row))
+				for n := range row {
+					cells[n] = markdownEscape(row[n])
+				}
+				fmt.Fprintf(&b, "| %s |\n", strings.Join(cells, " | "))
+			}
+		}
+	}
+	return b.String()
+}
+
+// markdownEscape escapes characters with special meaning in Markdown, and
+// converts newlines to HTML line breaks so that the text may be used in a
+// single table cell.
+func markdownEscape(input string) string {
+	var b strings.Builder
+	for _, r := range input {
+		switch r {
+		case '\\', '`', '*', '_', '[', ']', '<', '>', '|', '#':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case '\r':
+		case '\n':
+			b.WriteString("<br>")
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// HTMLDictionary returns a data dictionary for the schema as a standalone HTML
+// document. Its content is the same as that of MarkdownDictionary.
+func (s *Schema) HTMLDictionary() string {
+	var b bytes.Buffer
+	title := html.EscapeString(fmt.Sprintf("Schema %s", s.Name))
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&b, "<title>%s</title>\n</head>\n<body>\n<h1>%s</h1>\n", title, title)
+	fmt.Fprintf(&b, "<p>Character set: %s<br>\nCollation: %s</p>\n", html.EscapeString(s.CharSet), html.EscapeString(s.Collation))
+	for _, section := range s.dictionarySections() {
+		fmt.Fprintf(&b, "<h2>%s</h2>\n", html.EscapeString(section.Heading))
+		if section.Comment != "" {
+			fmt.Fprintf(&b, "<p>%s</p>\n", htmlEscapeMultiline(section.Comment))
+		}
+		b.WriteString("<ul>\n")
+		for _, prop := range section.Properties {
+			if prop[1] != "" {
+				fmt.Fprintf(&b, "<li>%s: %s</li>\n", prop[0], html.EscapeString(prop[1]))
+			}
+		}
+		b.WriteString("</ul>\n")
+		for _, grid := range section.Grids {
+			fmt.Fprintf(&b, "<h3>%s</h3>\n<table>\n<tr>", grid.Caption)
+			for _, h := range grid.Header {
+				fmt.Fprintf(&b, "<th>%s</th>", h)
+			}
+			b.WriteString("</tr>\n")
+			for _, row := range grid.Rows {
+				b.WriteString("<tr>")
+				for _, cell := range row {
+					fmt.Fprintf(&b, "<td>%s</td>", htmlEscapeMultiline(cell))
+				}
+				b.WriteString("</tr>\n")
+			}
+			b.WriteString("</table>\n")
+		}
+	}
+	b.WriteString("</body>\n</html>\n")
+	return b.String()
+}
+
+// htmlEscapeMultiline escapes input for use in HTML, converting newlines to
+// line breaks.
+func htmlEscapeMultiline(input string) string {
+	input = strings.Replace(input, "\r\n", "\n", -1)
+	return strings.Replace(html.EscapeString(input), "\n", "<br>", -1)
+}
@@ -0,0 +1,94 @@
+package tengo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSchemaMarkdownDictionary(t *testing.T) {
+	t1, t2, t3 := aTable(1), foreignKeyTable(), productsTable()
+	t1.Comment = "Actors | performers\nof films"
+	s := aSchema("s1", &t2, &t3, &t1)
+	proc, fn := aProc("latin1_swedish_ci", ""), aFunc("latin1_swedish_ci", "")
+	s.Routines = []*Routine{&fn, &proc}
+
+	md := s.MarkdownDictionary()
+	expectLines := []string{
+		"# Schema s1\n",
+		"## Table actor\n\nActors \\| performers<br>of films\n\n* Engine: InnoDB\n",
+		"| Name | Type | Nullable | Default | Extra | Comment |\n| --- | --- | --- | --- | --- | --- |\n",
+		"| actor\\_id | smallint(5) unsigned | NO |  | AUTO\\_INCREMENT |  |\n",
+		"| last\\_name | varchar(45) | YES | NULL |  |  |\n",
+		"| last\\_update | timestamp(2) | NO | CURRENT\\_TIMESTAMP(2) | ON UPDATE CURRENT\\_TIMESTAMP(2) |  |\n",
+		"| alive | tinyint(1) | NO | '1' |  |  |\n",
+		"| PRIMARY | PRIMARY | actor\\_id |  |\n",
+		"| product | UNIQUE | product\\_line, model |  |\n",
+		"| customer\\_fk | customer\\_id | purchasing.customers (id) | RESTRICT | SET NULL |\n",
+		"## Procedure proc1\n",
+		"### Parameters\n\n| Mode | Name | Type |\n| --- | --- | --- |\n",
+		"| IN | name | varchar(30) CHARACTER SET utf8mb4 COLLATE utf8mb4\\_bin |\n",
+		"| INOUT | iterations | int(10) unsigned |\n",
+		"| OUT | pct | decimal(5,2) |\n",
+		"* SQL data access: READS SQL DATA\n",
+		"## Function func1\n",
+		"* Returns: float\n",
+		"| Name | Type |\n| --- | --- |\n| mult | float(10,2) |\n",
+	}
+	for _, line := range expectLines {
+		if !strings.Contains(md, line) {
+			t.Errorf("Expected Markdown output to contain %q, but it did not. Output:\n%s", line, md)
+		}
+	}
+
+	// Sections should be sorted: tables by name, then procs, then funcs
+	var positions []int
+	for _, heading := range []string{"## Table actor", "## Table products", "## Table warranties", "## Procedure proc1", "## Function func1"} {
+		positions = append(positions, strings.Index(md, heading))
+	}
+	for n := range positions {
+		if positions[n] < 0 || (n > 0 && positions[n] < positions[n-1]) {
+			t.Errorf("Sections missing or out of order: positions %v", positions)
+			break
+		}
+	}
+	if strings.Count(md, "### Foreign keys") != 1 {
+		t.Error("Expected only tables with foreign keys to have a foreign key listing")
+	}
+	if again := s.MarkdownDictionary(); again != md {
+		t.Error("Expected Markdown output to be deterministic, but it was not")
+	}
+
+	// Unparseable parameter lists should be displayed as-is
+	proc.ParamString = "IN name varchar(30) garbage"
+	if md := s.MarkdownDictionary(); !strings.Contains(md, "* Parameters: IN name varchar(30) garbage\n") {
+		t.Errorf("Expected unparseable parameter list to be displayed as-is. Output:\n%s", md)
+	}
+}
+
+func TestSchemaHTMLDictionary(t *testing.T) {
+	t1 := aTable(1)
+	t1.Columns[1].Comment = "<b>first</b>\nname"
+	s := aSchema("s1", &t1)
+	proc := aProc("latin1_swedish_ci", "")
+	s.Routines = []*Routine{&proc}
+
+	doc := s.HTMLDictionary()
+	expectStrings := []string{
+		"<title>Schema s1</title>",
+		"<h2>Table actor</h2>",
+		"<li>Engine: InnoDB</li>",
+		"<tr><th>Name</th><th>Type</th><th>Nullable</th><th>Default</th><th>Extra</th><th>Comment</th></tr>",
+		"<tr><td>first_name</td><td>varchar(45)</td><td>NO</td><td></td><td></td><td>&lt;b&gt;first&lt;/b&gt;<br>name</td></tr>",
+		"<tr><td>alive_bit</td><td>bit(1)</td><td>NO</td><td>b&#39;1&#39;</td><td></td><td></td></tr>",
+		"<h2>Procedure proc1</h2>",
+		"<li>SQL security: INVOKER</li>",
+	}
+	for _, str := range expectStrings {
+		if !strings.Contains(doc, str) {
+			t.Errorf("Expected HTML output to contain %q, but it did not. Output:\n%s", str, doc)
+		}
+	}
+	if !strings.HasSuffix(doc, "</html>\n") {
+		t.Errorf("Unexpected end of HTML output:\n%s", doc)
+	}
+}
@@ -0,0 +1,254 @@
+package tengo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DependencyGraph tracks references between objects within a single schema:
+// tables, procedures, or functions referred to by the bodies of routines, as
+// well as parent tables referred to by foreign keys. References are found by
+// scanning SQL for table names after keywords such as FROM, JOIN, INTO, and
+// UPDATE; procedure names after CALL; and function names followed by an open
+// paren. Only names which resolve to an object in the schema are tracked, so
+// references to other schemas, temporary tables, and builtin functions are
+// ignored. Since dynamic SQL cannot be analyzed, the graph may be incomplete.
+type DependencyGraph struct {
+	dependents   map[ObjectKey]map[ObjectKey]bool // referenced object => objects referring to it
+	dependencies map[ObjectKey]map[ObjectKey]bool // referring object => objects it refers to
+}
+
+// Dependencies returns a graph of references between objects in the schema.
+func (s *Schema) Dependencies() *DependencyGraph {
+	g := &DependencyGraph{
+		dependents:   make(map[ObjectKey]map[ObjectKey]bool),
+		dependencies: make(map[ObjectKey]map[ObjectKey]bool),
+	}
+	if s == nil {
+		return g
+	}
+	r := newDependencyResolver(s)
+	for _, t := range s.Tables {
+		from := ObjectKey{Type: ObjectTypeTable, Name: t.Name}
+		for _, fk := range t.ForeignKeys {
+			if fk.ReferencedSchemaName != "" && fk.ReferencedSchemaName != s.Name {
+				continue
+			}
+			if to, ok := r.resolve(ObjectTypeTable, fk.ReferencedTableName); ok && to != from {
+				g.add(from, to)
+			}
+		}
+	}
+	for _, routine := range s.Routines {
+		from := ObjectKey{Type: routine.Type, Name: routine.Name}
+		for _, ref := range bodyReferences(routine.Body, s.Name) {
+			if to, ok := r.resolve(ref.Type, ref.Name); ok && to != from {
+				g.add(from, to)
+			}
+		}
+	}
+	return g
+}
+
+func (g *DependencyGraph) add(from, to ObjectKey) {
+	if g.dependencies[from] == nil {
+		g.dependencies[from] = make(map[ObjectKey]bool)
+	}
+	if g.dependents[to] == nil {
+		g.dependents[to] = make(map[ObjectKey]bool)
+	}
+	g.dependencies[from][to] = true
+	g.dependents[to][from] = true
+}
+
+// DependenciesOf returns the objects directly referred to by key, sorted by
+// type and then name.
+func (g *DependencyGraph) DependenciesOf(key ObjectKey) []ObjectKey {
+	return sortedObjectKeys(g.dependencies[key])
+}
+
+// DependentsOf returns the objects which directly refer to key, sorted by
+// type and then name.
+func (g *DependencyGraph) DependentsOf(key ObjectKey) []ObjectKey {
+	return sortedObjectKeys(g.dependents[key])
+}
+
+// AllDependentsOf returns the objects which refer to key either directly, or
+// indirectly via other dependents, sorted by type and then name. For example,
+// if a procedure calls a function which selects from a table, the procedure
+// and function are both dependents of the table. The result never includes
+// key itself, even if there is a cycle of references.
+func (g *DependencyGraph) AllDependentsOf(key ObjectKey) []ObjectKey {
+	seen := map[ObjectKey]bool{key: true}
+	result := make(map[ObjectKey]bool)
+	queue := []ObjectKey{key}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for dependent := range g.dependents[current] {
+			if !seen[dependent] {
+				seen[dependent] = true
+				result[dependent] = true
+				queue = append(queue, dependent)
+			}
+		}
+	}
+	return sortedObjectKeys(result)
+}
+
+func sortedObjectKeys(keys map[ObjectKey]bool) []ObjectKey {
+	result := make([]ObjectKey, 0, len(keys))
+	for key := range keys {
+		result = append(result, key)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Type != result[j].Type {
+			return result[i].Type < result[j].Type
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// dependencyResolver maps names found in SQL to objects in a schema. Table
+// names are matched exactly if possible, falling back to a case-insensitive
+// match, since the case-sensitivity of table names depends on the server's
+// lower_case_table_names. Routine names are always case-insensitive.
+type dependencyResolver struct {
+	exact map[ObjectKey]bool
+	lower map[ObjectKey]ObjectKey
+}
+
+func newDependencyResolver(s *Schema) *dependencyResolver {
+	r := &dependencyResolver{
+		exact: make(map[ObjectKey]bool),
+		lower: make(map[ObjectKey]ObjectKey),
+	}
+	addKey := func(key ObjectKey) {
+		r.exact[key] = true
+		r.lower[ObjectKey{Type: key.Type, Name: strings.ToLower(key.Name)}] = key
+	}
+	for _, t := range s.Tables {
+		addKey(ObjectKey{Type: ObjectTypeTable, Name: t.Name})
+	}
+	for _, routine := range s.Routines {
+		addKey(ObjectKey{Type: routine.Type, Name: routine.Name})
+	}
+	return r
+}
+
+func (r *dependencyResolver) resolve(typ ObjectType, name string) (ObjectKey, bool) {
+	key := ObjectKey{Type: typ, Name: name}
+	if r.exact[key] {
+		return key, true
+	}
+	key, ok := r.lower[ObjectKey{Type: typ, Name: strings.ToLower(name)}]
+	return key, ok
+}
+
+// tableListEnd contains keywords which end a comma-separated list of tables
+// following FROM, so that subsequent commas are not mistaken for table list
+// separators.
+var tableListEnd = map[string]bool{
+	"WHERE": true, "ON": true, "USING": true, "GROUP": true, "ORDER": true,
+	"LIMIT": true, "HAVING": true, "SET": true, "UNION": true, "WINDOW": true,
+	"FOR": true, "LOCK": true, "INTO": true, "VALUES": true, "SELECT": true,
+	"PARTITION": true, "JOIN": true, "STRAIGHT_JOIN": true, "INNER": true,
+	"CROSS": true, "LEFT": true, "RIGHT": true, "NATURAL": true, "OUTER": true,
+}
+
+// bodyReferences scans the body of a routine for possible references to
+// tables and routines. Names qualified with a schema other than schemaName
+// are excluded. The returned ObjectKeys have not been resolved, so they may
+// refer to nonexistent objects. If the body cannot be tokenized, nil is
+// returned.
+func bodyReferences(body, schemaName string) []ObjectKey {
+	tokens, err := tokenizeSQL(body)
+	if err != nil {
+		return nil
+	}
+	isName := func(tok sqlToken) bool {
+		return tok.typ == sqlTokenWord || tok.typ == sqlTokenIdentifier
+	}
+	var refs []ObjectKey
+
+	// qualifiedName returns the object name starting at tokens[pos], along with
+	// the position after it. The name is blank if it is qualified with a
+	// different schema.
+	qualifiedName := func(pos int) (string, int) {
+		name := tokens[pos].val
+		if pos+2 < len(tokens) && tokens[pos+1].isSymbol(".") && isName(tokens[pos+2]) {
+			if !strings.EqualFold(name, schemaName) {
+				return "", pos + 3
+			}
+			return tokens[pos+2].val, pos + 3
+		}
+		return name, pos + 1
+	}
+	addTable := func(pos int) int {
+		if pos >= len(tokens) || !isName(tokens[pos]) {
+			return pos
+		}
+		name, next := qualifiedName(pos)
+		if name != "" {
+			refs = append(refs, ObjectKey{Type: ObjectTypeTable, Name: name})
+		}
+		return next
+	}
+
+	var inTableList bool
+	for pos := 0; pos < len(tokens); pos++ {
+		tok := tokens[pos]
+		switch {
+		case tok.isKeyword("UPDATE") && pos > 0 && tokens[pos-1].isKeyword("KEY"):
+			// ON DUPLICATE KEY UPDATE is followed by a column, not a table
+		case tok.isKeyword("FROM") || tok.isKeyword("JOIN") || tok.isKeyword("STRAIGHT_JOIN"):
+			pos = addTable(pos+1) - 1
+			inTableList = true
+		case tok.isKeyword("INTO") || tok.isKeyword("UPDATE") || tok.isKeyword("TABLE") || tok.isKeyword("TABLES"):
+			pos = addTable(pos+1) - 1
+			inTableList = tok.isKeyword("UPDATE") || tok.isKeyword("TABLES")
+		case tok.isSymbol(",") && inTableList:
+			pos = addTable(pos+1) - 1
+		case tok.isKeyword("CALL"):
+			if pos+1 < len(tokens) && isName(tokens[pos+1]) {
+				name, next := qualifiedName(pos + 1)
+				if name != "" {
+					refs = append(refs, ObjectKey{Type: ObjectTypeProc, Name: name})
+				}
+				pos = next - 1
+			}
+		case isName(tok):
+			if tok.typ == sqlTokenWord && tableListEnd[strings.ToUpper(tok.val)] {
+				inTableList = false
+			}
+			// Possible function call, unless preceded by a dot (which indicates the
+			// function is qualified with a schema name, handled below)
+			if pos > 0 && tokens[pos-1].isSymbol(".") {
+				continue
+			}
+			name, next := qualifiedName(pos)
+			if next < len(tokens) && tokens[next].isSymbol("(") {
+				if name != "" {
+					refs = append(refs, ObjectKey{Type: ObjectTypeFunc, Name: name})
+				}
+				pos = next - 1
+			}
+		case tok.typ == sqlTokenSymbol && !tok.isSymbol(".") && !tok.isSymbol(","):
+			inTableList = false
+		}
+	}
+	return refs
+}
+
+// DependencyWarning describes an object which would be broken by a diff, due
+// to it referring to an object being dropped.
+type DependencyWarning struct {
+	Dropped   ObjectKey // object being dropped by the diff
+	Dependent ObjectKey // object which refers to Dropped, and still exists after the diff
+}
+
+func (w DependencyWarning) String() string {
+	return fmt.Sprintf("Dropping %s will break %s, which refers to it", w.Dropped, w.Dependent)
+}
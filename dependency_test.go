@@ -0,0 +1,115 @@
+package tengo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBodyReferences(t *testing.T) {
+	body := `BEGIN
+  DECLARE n int;
+  SELECT COUNT(*) INTO n FROM orders o, ` + "`line items`" + ` li JOIN shop.products p ON p.id = li.product_id
+    WHERE o.id = li.order_id AND o.status = 'FROM fake';
+  INSERT INTO audit_log (msg) VALUES (format_msg(n)) ON DUPLICATE KEY UPDATE msg = 'x';
+  UPDATE other_db.stats, counters SET counters.n = n;
+  CALL shop.refresh_totals(n);
+  CALL other_db.ignored();
+  -- SELECT * FROM commented_out
+  RETURN shop.double_it(n) + other_db.ignored_func(n);
+END`
+	expected := []ObjectKey{
+		{Type: ObjectTypeFunc, Name: "COUNT"}, // builtin, removed by resolution
+		{Type: ObjectTypeTable, Name: "n"},    // false positive from SELECT ... INTO var, removed by resolution
+		{Type: ObjectTypeTable, Name: "orders"},
+		{Type: ObjectTypeTable, Name: "line items"},
+		{Type: ObjectTypeTable, Name: "products"},
+		{Type: ObjectTypeTable, Name: "audit_log"},
+		{Type: ObjectTypeFunc, Name: "VALUES"}, // not a function, removed by resolution
+		{Type: ObjectTypeFunc, Name: "format_msg"},
+		{Type: ObjectTypeTable, Name: "counters"},
+		{Type: ObjectTypeProc, Name: "refresh_totals"},
+		{Type: ObjectTypeFunc, Name: "double_it"},
+	}
+	refs := bodyReferences(body, "shop")
+	if !reflect.DeepEqual(refs, expected) {
+		t.Errorf("Unexpected result from bodyReferences:\n%v", refs)
+	}
+	if refs := bodyReferences("SELECT 'unterminated", "shop"); refs != nil {
+		t.Errorf("Expected nil result for untokenizable body, instead found %v", refs)
+	}
+}
+
+func dependencyTestSchema() Schema {
+	customers := &Table{Name: "customers"}
+	orders := &Table{
+		Name: "orders",
+		ForeignKeys: []*ForeignKey{
+			{Name: "cust_fk", ReferencedTableName: "customers"},
+			{Name: "self_fk", ReferencedTableName: "orders"},
+			{Name: "other_fk", ReferencedSchemaName: "other", ReferencedTableName: "customers"},
+		},
+	}
+	s := aSchema("shop", customers, orders, &Table{Name: "unused"})
+	s.Routines = []*Routine{
+		{Name: "order_total", Type: ObjectTypeFunc, Body: "RETURN (SELECT SUM(amount) FROM Orders WHERE id = order_id)"},
+		{Name: "report", Type: ObjectTypeProc, Body: "BEGIN SELECT name, ORDER_TOTAL(id) FROM customers; CALL report(); END"},
+		{Name: "nightly", Type: ObjectTypeProc, Body: "BEGIN CALL report(); CALL missing(); END"},
+	}
+	return s
+}
+
+func TestSchemaDependencies(t *testing.T) {
+	s := dependencyTestSchema()
+	g := s.Dependencies()
+	ordersKey := ObjectKey{Type: ObjectTypeTable, Name: "orders"}
+	customersKey := ObjectKey{Type: ObjectTypeTable, Name: "customers"}
+	funcKey := ObjectKey{Type: ObjectTypeFunc, Name: "order_total"}
+	reportKey := ObjectKey{Type: ObjectTypeProc, Name: "report"}
+	nightlyKey := ObjectKey{Type: ObjectTypeProc, Name: "nightly"}
+
+	if actual, expected := g.DependentsOf(ordersKey), []ObjectKey{funcKey}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Unexpected DependentsOf(%s): %v", ordersKey, actual)
+	}
+	if actual, expected := g.DependentsOf(customersKey), []ObjectKey{reportKey, ordersKey}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Unexpected DependentsOf(%s): %v", customersKey, actual)
+	}
+	if actual, expected := g.DependenciesOf(reportKey), []ObjectKey{funcKey, customersKey}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Unexpected DependenciesOf(%s): %v", reportKey, actual)
+	}
+	if actual, expected := g.AllDependentsOf(ordersKey), []ObjectKey{funcKey, nightlyKey, reportKey}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Unexpected AllDependentsOf(%s): %v", ordersKey, actual)
+	}
+	if actual := g.AllDependentsOf(ObjectKey{Type: ObjectTypeTable, Name: "unused"}); len(actual) != 0 {
+		t.Errorf("Expected no dependents of unused table, instead found %v", actual)
+	}
+
+	var nilSchema *Schema
+	if actual := nilSchema.Dependencies().DependentsOf(ordersKey); len(actual) != 0 {
+		t.Errorf("Expected no dependents in nil schema, instead found %v", actual)
+	}
+}
+
+func TestSchemaDiffDependencyWarnings(t *testing.T) {
+	from := dependencyTestSchema()
+	to := dependencyTestSchema()
+
+	// Drop the customers table and the order_total function, as well as the
+	// report proc which refers to both
+	to.Tables = []*Table{to.Tables[1], to.Tables[2]}
+	to.Routines = []*Routine{to.Routines[2]}
+	sd := NewSchemaDiff(&from, &to)
+	expected := []DependencyWarning{
+		{Dropped: ObjectKey{Type: ObjectTypeProc, Name: "report"}, Dependent: ObjectKey{Type: ObjectTypeProc, Name: "nightly"}},
+		{Dropped: ObjectKey{Type: ObjectTypeTable, Name: "customers"}, Dependent: ObjectKey{Type: ObjectTypeTable, Name: "orders"}},
+	}
+	if actual := sd.DependencyWarnings(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Unexpected result from DependencyWarnings: %v", actual)
+	}
+	if str := expected[0].String(); str != "Dropping procedure `report` will break procedure `nightly`, which refers to it" {
+		t.Errorf("Unexpected String result: %s", str)
+	}
+
+	if warnings := NewSchemaDiff(&from, &from).DependencyWarnings(); len(warnings) != 0 {
+		t.Errorf("Expected no warnings for empty diff, instead found %v", warnings)
+	}
+}
@@ -0,0 +1,299 @@
+package tengo
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// DiagramOptions controls the output of Schema.DOT and Schema.Mermaid.
+type DiagramOptions struct {
+	Tables      []string // If non-empty, only include tables with these names
+	OmitColumns bool     // If true, only render table names and relationships
+}
+
+// diagramTable is an entity in an ER diagram. Tables referenced by a foreign
+// key, but not otherwise included in the diagram (for example, tables in a
+// different schema), are rendered as stubs without any columns.
+type diagramTable struct {
+	ID    string
+	Table *Table // nil for stub entities
+}
+
+// diagramEdge is a relationship between a child table and the parent table
+// referenced by one of its foreign keys.
+type diagramEdge struct {
+	Child      *Table
+	ParentID   string
+	Parent     *Table // nil if parent is a stub
+	ForeignKey *ForeignKey
+}
+
+// Label returns a description of the relationship's column mapping.
+func (e diagramEdge) Label() string {
+	childCols := make([]string, len(e.ForeignKey.Columns))
+	for n, col := range e.ForeignKey.Columns {
+		childCols[n] = col.Name
+	}
+	return fmt.Sprintf("%s: (%s) -> (%s)", e.ForeignKey.Name, strings.Join(childCols, ", "), strings.Join(e.ForeignKey.ReferencedColumnNames, ", "))
+}
+
+// diagramEntities returns the tables and relationships to include in an ER
+// diagram of the schema, in a deterministic order.
+func (s *Schema) diagramEntities(opts DiagramOptions) ([]diagramTable, []diagramEdge) {
+	var tables []*Table
+	if len(opts.Tables) == 0 {
+		tables = make([]*Table, len(s.Tables))
+		copy(tables, s.Tables)
+	} else {
+		for _, name := range opts.Tables {
+			if t := s.Table(name); t != nil {
+				tables = append(tables, t)
+			}
+		}
+	}
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].Name < tables[j].Name
+	})
+
+	included := make(map[string]*Table, len(tables))
+	entities := make([]diagramTable, 0, len(tables))
+	for _, t := range tables {
+		included[t.Name] = t
+		entities = append(entities, diagramTable{ID: t.Name, Table: t})
+	}
+
+	var edges []diagramEdge
+	stubs := make(map[string]bool)
+	var stubIDs []string
+	for _, t := range tables {
+		for _, fk := range t.ForeignKeys {
+			edge := diagramEdge{Child: t, ForeignKey: fk}
+			if fk.ReferencedSchemaName == "" || fk.ReferencedSchemaName == s.Name {
+				edge.ParentID = fk.ReferencedTableName
+				edge.Parent = included[fk.ReferencedTableName]
+			} else {
+				edge.ParentID = fmt.Sprintf("%s.%s", fk.ReferencedSchemaName, fk.ReferencedTableName)
+			}
+			if edge.Parent == nil && !stubs[edge.ParentID] {
+				stubs[edge.ParentID] = true
+				stubIDs = append(stubIDs, edge.ParentID)
+			}
+			edges = append(edges, edge)
+		}
+	}
+	sort.Strings(stubIDs)
+	for _, id := range stubIDs {
+		entities = append(entities, diagramTable{ID: id})
+	}
+	return entities, edges
+}
+
+// diagramKeyMarkers returns a map of column name to key markers (PK, UK, FK)
+// for the table's columns.
+func (t *Table) diagramKeyMarkers() map[string][]string {
+	markers := make(map[string][]string, len(t.Columns))
+	add := func(colName, marker string) {
+		for _, existing := range markers[colName] {
+			if existing == marker {
+				return
+			}
+		}
+		markers[colName] = append(markers[colName], marker)
+	}
+	if t.PrimaryKey != nil {
+		for _, col := range t.PrimaryKey.Columns {
+			add(col.Name, "PK")
+		}
+	}
+	for _, idx := range t.SecondaryIndexes {
+		if idx.Unique {
+			for _, col := range idx.Columns {
+				add(col.Name, "UK")
+			}
+		}
+	}
+	for _, fk := range t.ForeignKeys {
+		for _, col := range fk.Columns {
+			add(col.Name, "FK")
+		}
+	}
+	return markers
+}
+
+// DOT returns a Graphviz DOT representation of an entity-relationship diagram
+// of the schema. Each table is rendered as a node listing its columns, types,
+// and key markers; each foreign key is rendered as an edge from the child
+// column to the parent table, labeled with the column mapping. For composite
+// foreign keys, the edge only connects the first column of each side, but the
+// label lists all columns. Tables in other schemas referenced by foreign keys
+// are rendered as dashed stub nodes.
+func (s *Schema) DOT(opts DiagramOptions) string {
+	entities, edges := s.diagramEntities(opts)
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "digraph %s {\n", dotQuote(s.Name))
+	b.WriteString("\trankdir=LR;\n")
+	b.WriteString("\tnode [shape=plaintext];\n")
+	for _, ent := range entities {
+		if ent.Table == nil {
+			fmt.Fprintf(&b, "\t%s [shape=box, style=dashed];\n", dotQuote(ent.ID))
+			continue
+		}
+		fmt.Fprintf(&b, "\t%s [label=<<table border=\"0\" cellborder=\"1\" cellspacing=\"0\">", dotQuote(ent.ID))
+		fmt.Fprintf(&b, "<tr><td bgcolor=\"lightgrey\" colspan=\"3\"><b>%s</b></td></tr>", html.EscapeString(ent.Table.Name))
+		if !opts.OmitColumns {
+			markers := ent.Table.diagramKeyMarkers()
+			for _, col := range ent.Table.Columns {
+				fmt.Fprintf(&b, "<tr><td port=\"%s\" align=\"left\">%s</td><td align=\"left\">%s</td><td>%s</td></tr>",
+					dotPort(col.Name), html.EscapeString(col.Name), html.EscapeString(col.TypeInDB), strings.Join(markers[col.Name], ","))
+			}
+		}
+		b.WriteString("</table>>];\n")
+	}
+	for _, edge := range edges {
+		from := dotQuote(edge.Child.Name)
+		to := dotQuote(edge.ParentID)
+		if !opts.OmitColumns {
+			from = fmt.Sprintf("%s:%s", from, dotQuote(dotPort(edge.ForeignKey.Columns[0].Name)))
+			if edge.Parent != nil && edge.Parent.ColumnsByName()[edge.ForeignKey.ReferencedColumnNames[0]] != nil {
+				to = fmt.Sprintf("%s:%s", to, dotQuote(dotPort(edge.ForeignKey.ReferencedColumnNames[0])))
+			}
+		}
+		fmt.Fprintf(&b, "\t%s -> %s [label=%s];\n", from, to, dotQuote(edge.Label()))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// dotPort returns the port name for a column in a DOT table node. Column names
+// consisting solely of letters, digits, and underscores are used as-is; others
+// are hex-encoded, since Graphviz does not unescape port names consistently
+// between HTML labels and edge endpoints. The "x-" prefix of encoded names
+// cannot occur in unencoded ones, so the two forms never collide.
+func dotPort(colName string) string {
+	for _, r := range colName {
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return "x-" + hex.EncodeToString([]byte(colName))
+		}
+	}
+	return colName
+}
+
+// dotQuote returns input as a double-quoted DOT identifier.
+func dotQuote(input string) string {
+	input = strings.Replace(input, `\`, `\\`, -1)
+	return `"` + strings.Replace(input, `"`, `\"`, -1) + `"`
+}
+
+// Mermaid returns a Mermaid erDiagram representation of the schema. Each table
+// is rendered as an entity listing its columns, types, and key markers; each
+// foreign key is rendered as a relationship labeled with the column mapping.
+// Tables in other schemas referenced by foreign keys are rendered as entities
+// without attributes, named as schema.table.
+func (s *Schema) Mermaid(opts DiagramOptions) string {
+	entities, edges := s.diagramEntities(opts)
+	var b bytes.Buffer
+	b.WriteString("erDiagram\n")
+	for _, ent := range entities {
+		if ent.Table == nil || opts.OmitColumns {
+			fmt.Fprintf(&b, "\t%s\n", mermaidName(ent.ID))
+			continue
+		}
+		fmt.Fprintf(&b, "\t%s {\n", mermaidName(ent.ID))
+		markers := ent.Table.diagramKeyMarkers()
+		for _, col := range ent.Table.Columns {
+			fmt.Fprintf(&b, "\t\t%s %s", mermaidType(col.TypeInDB), mermaidName(col.Name))
+			if len(markers[col.Name]) > 0 {
+				fmt.Fprintf(&b, " %s", strings.Join(markers[col.Name], ","))
+			}
+			if col.Comment != "" {
+				fmt.Fprintf(&b, " %s", mermaidQuote(col.Comment))
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("\t}\n")
+	}
+	for _, edge := range edges {
+		// Parent side: exactly one if all FK columns are NOT NULL, else zero or one.
+		// Child side: zero or one if the FK columns are unique, else zero or more.
+		parentCard, childCard := "||", "o{"
+		for _, col := range edge.ForeignKey.Columns {
+			if col.Nullable {
+				parentCard = "|o"
+				break
+			}
+		}
+		if edge.Child.hasUniqueIndexOn(edge.ForeignKey.Columns) {
+			childCard = "o|"
+		}
+		fmt.Fprintf(&b, "\t%s %s--%s %s : %s\n", mermaidName(edge.ParentID), parentCard, childCard, mermaidName(edge.Child.Name), mermaidQuote(edge.Label()))
+	}
+	return b.String()
+}
+
+// hasUniqueIndexOn returns true if the table has a unique index (or primary
+// key) consisting of exactly the supplied columns, in any order.
+func (t *Table) hasUniqueIndexOn(cols []*Column) bool {
+	indexes := t.SecondaryIndexes
+	if t.PrimaryKey != nil {
+		indexes = append([]*Index{t.PrimaryKey}, indexes...)
+	}
+	for _, idx := range indexes {
+		if (!idx.Unique && !idx.PrimaryKey) || len(idx.Columns) != len(cols) {
+			continue
+		}
+		matched := true
+		for _, col := range cols {
+			var found bool
+			for _, idxCol := range idx.Columns {
+				if idxCol.Name == col.Name {
+					found = true
+					break
+				}
+			}
+			if !found {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+var mermaidBareName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// mermaidName returns a name suitable for use as a Mermaid entity or attribute
+// name, quoting it if necessary.
+func mermaidName(input string) string {
+	if mermaidBareName.MatchString(input) {
+		return input
+	}
+	return mermaidQuote(input)
+}
+
+// mermaidQuote returns input as a double-quoted Mermaid string. Mermaid does
+// not support escaping double quotes, so they are replaced with single quotes.
+func mermaidQuote(input string) string {
+	return `"` + strings.Replace(input, `"`, "'", -1) + `"`
+}
+
+// mermaidType converts a column type into a form accepted as a Mermaid
+// attribute type, which may not contain spaces, commas, or quotes. Enum and
+// set types are reduced to their base type.
+func mermaidType(typeInDB string) string {
+	if strings.ContainsAny(typeInDB, `'"`) {
+		if paren := strings.IndexRune(typeInDB, '('); paren > -1 {
+			typeInDB = typeInDB[0:paren]
+		}
+	}
+	typeInDB = strings.Replace(typeInDB, ",", "-", -1)
+	return strings.Replace(typeInDB, " ", "_", -1)
+}
@@ -0,0 +1,109 @@
+package tengo
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSchemaDOT(t *testing.T) {
+	child, parent, other := foreignKeyTable(), productsTable(), aTable(1)
+	s := aSchema("store", &other, &child, &parent)
+	dot := s.DOT(DiagramOptions{})
+
+	expectLines := []string{
+		`digraph "store" {`,
+		`"warranties":"customer_id" -> "purchasing.customers" [label="customer_fk: (customer_id) -> (id)"];`,
+		`"warranties":"product_line" -> "products":"line" [label="product_fk: (product_line, model) -> (line, model)"];`,
+		`"purchasing.customers" [shape=box, style=dashed];`,
+		`<tr><td port="product_line" align="left">product_line</td><td align="left">char(12)</td><td>UK,FK</td></tr>`,
+		`<tr><td port="id" align="left">id</td><td align="left">int(10) unsigned</td><td>PK</td></tr>`,
+	}
+	for _, line := range expectLines {
+		if !strings.Contains(dot, line) {
+			t.Errorf("Expected DOT output to contain %s, but it did not. Output:\n%s", line, dot)
+		}
+	}
+	posActor, posProducts, posWarranties := strings.Index(dot, `"actor" [`), strings.Index(dot, `"products" [`), strings.Index(dot, `"warranties" [`)
+	if posActor < 0 || posProducts < posActor || posWarranties < posProducts {
+		t.Errorf("Expected table nodes to be sorted by name; instead found positions %d, %d, %d", posActor, posProducts, posWarranties)
+	}
+	if again := s.DOT(DiagramOptions{}); again != dot {
+		t.Error("Expected DOT output to be deterministic, but it was not")
+	}
+
+	// Filtering to only the child table should cause the parent to become a stub,
+	// and edges should no longer point to its columns
+	dot = s.DOT(DiagramOptions{Tables: []string{"warranties"}, OmitColumns: true})
+	if strings.Contains(dot, "actor") || strings.Contains(dot, "<td port") {
+		t.Errorf("Unexpected DOT output:\n%s", dot)
+	}
+	for _, line := range []string{`"products" [shape=box, style=dashed];`, `"warranties" -> "products" [label=`} {
+		if !strings.Contains(dot, line) {
+			t.Errorf("Expected DOT output to contain %s, but it did not. Output:\n%s", line, dot)
+		}
+	}
+}
+
+func TestSchemaDOTPorts(t *testing.T) {
+	// Column names with characters that require escaping must yield identical
+	// port names in node labels and edge endpoints
+	child, parent := foreignKeyTable(), productsTable()
+	childCol, parentCol := `prod"line&`, `line\x`
+	parent.ColumnsByName()["line"].Name = parentCol
+	child.Columns[2].Name = childCol
+	child.ForeignKeys[1].ReferencedColumnNames[0] = parentCol
+	s := aSchema("store", &child, &parent)
+	dot := s.DOT(DiagramOptions{})
+
+	childPort, parentPort := "x-"+hex.EncodeToString([]byte(childCol)), "x-"+hex.EncodeToString([]byte(parentCol))
+	expectLines := []string{
+		fmt.Sprintf(`"warranties":"%s" -> "products":"%s" [label=`, childPort, parentPort),
+		fmt.Sprintf(`<tr><td port="%s" align="left">prod&#34;line&amp;</td>`, childPort),
+		fmt.Sprintf(`<tr><td port="%s" align="left">line\x</td>`, parentPort),
+		`"warranties":"customer_id" -> "purchasing.customers"`,
+	}
+	for _, line := range expectLines {
+		if !strings.Contains(dot, line) {
+			t.Errorf("Expected DOT output to contain %s, but it did not. Output:\n%s", line, dot)
+		}
+	}
+}
+
+func TestSchemaMermaid(t *testing.T) {
+	child, parent := foreignKeyTable(), productsTable()
+	child.Columns[0].Comment = `the "id"`
+	s := aSchema("store", &child, &parent)
+	expected := `erDiagram
+	products {
+		char(12) line PK
+		int(10)_unsigned model PK
+		varchar(100) name
+	}
+	warranties {
+		int(10)_unsigned id PK "the 'id'"
+		int(10)_unsigned customer_id FK
+		char(12) product_line UK,FK
+		int(10)_unsigned model UK,FK
+	}
+	"purchasing.customers"
+	"purchasing.customers" |o--o{ warranties : "customer_fk: (customer_id) -> (id)"
+	products ||--o| warranties : "product_fk: (product_line, model) -> (line, model)"
+`
+	if actual := s.Mermaid(DiagramOptions{}); actual != expected {
+		t.Errorf("Unexpected Mermaid output.\nExpected:\n%s\nFound:\n%s", expected, actual)
+	}
+
+	cases := map[string]string{
+		"int(10) unsigned":       "int(10)_unsigned",
+		"decimal(10,2)":          "decimal(10-2)",
+		"enum('a','b')":          "enum",
+		"set('x') CHARACTER SET": "set",
+	}
+	for input, expected := range cases {
+		if actual := mermaidType(input); actual != expected {
+			t.Errorf("Expected mermaidType(%q) to return %q, instead found %q", input, expected, actual)
+		}
+	}
+}
@@ -36,7 +36,8 @@ func (dt DiffType) String() string {
 }
 
 // ObjectDiff is an interface allowing generic handling of differences between
-// two objects.
+// two objects. For the diffs returned by SchemaDiff.ObjectDiffs, Statement
+// returns a single statement, or a blank string if no DDL is necessary.
 type ObjectDiff interface {
 	DiffType() DiffType
 	ObjectKey() ObjectKey
@@ -69,6 +70,8 @@ type StatementModifiers struct {
 	StrictForeignKeyNaming bool            // If true, maintain foreign key names even if no functional difference in definition
 	CompareMetadata        bool            // If true, compare creation-time sql_mode and db collation for funcs, procs (and eventually events, triggers)
 	Flavor                 Flavor          // Adjust generated DDL to match vendor/version. Zero value is FlavorUnknown which makes no adjustments.
+	RewriteDefiner         string          // If non-empty, replace the DEFINER of created routines with this account, in "user@host" format; other formats cause RoutineDiff.Statements to return an error
+	StripDefiner           bool            // If true, omit DEFINER from created routines, so the user running the DDL becomes the definer. Takes precedence over RewriteDefiner.
 }
 
 ///// SchemaDiff ///////////////////////////////////////////////////////////////
@@ -142,19 +145,21 @@ func compareRoutines(from, to *Schema) (routineDiffs []*RoutineDiff) {
 				routineDiffs = append(routineDiffs, &RoutineDiff{From: fromRoutine})
 			} else if !fromRoutine.Equals(toRoutine) {
 				// Determine if only the creation-time metadata (db collation, sql_mode)
-				// has changed, and flag the diffs if so. This type of change requires
+				// has changed, and flag the diff if so. This type of change requires
 				// StatementModifiers to execute, since its appearance is counterintuitive
-				// (since otherwise it looks like a routine is being dropped and recreated
-				// with the exact same statement)
+				// (since otherwise it looks like a routine is being replaced with the
+				// exact same statement)
 				metadataOnly := fromRoutine.CreateStatement == toRoutine.CreateStatement
-
-				// TODO: Currently this handles all changes to existing routines via DROP-
-				// then-ADD, but characteristic-only changes could use ALTER FUNCTION /
-				// ALTER PROCEDURE instead.
-				routineDiffs = append(routineDiffs,
-					&RoutineDiff{From: fromRoutine, ForMetadata: metadataOnly},
-					&RoutineDiff{To: toRoutine, ForMetadata: metadataOnly},
-				)
+				if fromRoutine.CanAlterTo(toRoutine) {
+					routineDiffs = append(routineDiffs, &RoutineDiff{From: fromRoutine, To: toRoutine, ForMetadata: metadataOnly})
+					continue
+				}
+				// Replacing a routine may require multiple statements depending on the
+				// flavor, so emit a separate diff for each step, to ensure each diff
+				// corresponds to at most one statement
+				for step := RoutineDiffStepCleanupTemp; step <= RoutineDiffStepCreate; step++ {
+					routineDiffs = append(routineDiffs, &RoutineDiff{From: fromRoutine, To: toRoutine, ForMetadata: metadataOnly, Step: step})
+				}
 			}
 		}
 		for name, toRoutine := range toByName {
@@ -213,6 +218,48 @@ func (sd *SchemaDiff) String() string {
 	return strings.Join(diffStatements, "")
 }
 
+// DependencyWarnings returns a warning for each object in ToSchema which
+// refers to a table or routine being dropped by the diff. Such objects would
+// be broken by the diff; in the case of foreign keys, the DROP TABLE would
+// fail. Objects which are dropped by the diff themselves are not included. See
+// DependencyGraph for limitations of the dependency analysis.
+func (sd *SchemaDiff) DependencyWarnings() []DependencyWarning {
+	dropped := make(map[ObjectKey]bool)
+	var droppedTables []*Table
+	var droppedRoutines []*Routine
+	for _, td := range sd.TableDiffs {
+		if td.DiffType() == DiffTypeDrop {
+			dropped[td.ObjectKey()] = true
+			droppedTables = append(droppedTables, td.From)
+		}
+	}
+	for _, rd := range sd.RoutineDiffs {
+		if rd.DiffType() == DiffTypeDrop {
+			dropped[rd.ObjectKey()] = true
+			droppedRoutines = append(droppedRoutines, rd.From)
+		}
+	}
+	if len(dropped) == 0 || sd.ToSchema == nil {
+		return nil
+	}
+
+	// Build a graph from the To side, plus the dropped objects, so that
+	// references to the dropped objects can be resolved
+	combined := *sd.ToSchema
+	combined.Tables = append(append([]*Table{}, sd.ToSchema.Tables...), droppedTables...)
+	combined.Routines = append(append([]*Routine{}, sd.ToSchema.Routines...), droppedRoutines...)
+	graph := combined.Dependencies()
+	var warnings []DependencyWarning
+	for _, key := range sortedObjectKeys(dropped) {
+		for _, dependent := range graph.DependentsOf(key) {
+			if !dropped[dependent] {
+				warnings = append(warnings, DependencyWarning{Dropped: key, Dependent: dependent})
+			}
+		}
+	}
+	return warnings
+}
+
 // FilteredTableDiffs returns any TableDiffs of the specified type(s).
 func (sd *SchemaDiff) FilteredTableDiffs(onlyTypes ...DiffType) []*TableDiff {
 	result := make([]*TableDiff, 0, len(sd.TableDiffs))
@@ -543,13 +590,35 @@ func (td *TableDiff) alterStatement(mods StatementModifiers) (string, error) {
 
 ///// RoutineDiff //////////////////////////////////////////////////////////////
 
-// RoutineDiff represents a difference between two routines.
+// RoutineDiff represents a difference between two routines. For an alter,
+// both From and To are set. If the routines differ only in characteristics
+// supported by ALTER PROCEDURE or ALTER FUNCTION, the alter is performed that
+// way; otherwise, the routine is replaced using a flavor-specific strategy,
+// which may involve multiple steps. NewSchemaDiff emits a separate RoutineDiff
+// for each step of a replacement.
 type RoutineDiff struct {
 	From        *Routine
 	To          *Routine
-	ForMetadata bool // if true, routine is being replaced only to update creation-time metadata
+	ForMetadata bool            // if true, routine is being replaced only to update creation-time metadata
+	Step        RoutineDiffStep // which step of a replacement this diff represents, or RoutineDiffStepAll
 }
 
+// RoutineDiffStep identifies a single step of replacing a routine, for changes
+// which cannot be made with ALTER PROCEDURE or ALTER FUNCTION. Each step
+// corresponds to at most one statement; steps which are unnecessary for the
+// flavor have no statement.
+type RoutineDiffStep int
+
+// Constants for the steps of replacing a routine, in execution order.
+const (
+	RoutineDiffStepAll         RoutineDiffStep = iota // all steps; may correspond to multiple statements
+	RoutineDiffStepCleanupTemp                        // drop temporary routine leftover from an interrupted replacement
+	RoutineDiffStepCreateTemp                         // create new version under a temporary name, to confirm it compiles
+	RoutineDiffStepDropTemp                           // drop temporary routine
+	RoutineDiffStepDrop                               // drop existing routine
+	RoutineDiffStepCreate                             // create new version, or replace atomically if flavor supports it
+)
+
 // ObjectKey returns a value representing the type and name of the routine being
 // diff'ed. The type will be either ObjectTypeFunc or ObjectTypeProc. The name
 // will be the From side routine, unless this is a Create, in which case the To
@@ -575,41 +644,424 @@ func (rd *RoutineDiff) DiffType() DiffType {
 	return DiffTypeAlter
 }
 
-// Statement returns the full DDL statement corresponding to the RoutineDiff. A
-// blank string may be returned if the mods indicate the statement should be
-// skipped. If the mods indicate the statement should be disallowed, it will
-// still be returned as-is, but the error will be non-nil. Be sure not to
-// ignore the error value of this method.
+// Statement returns the DDL statement corresponding to the RoutineDiff, or a
+// blank string if no statement is necessary. If rd.Step is RoutineDiffStepAll
+// and replacing the routine requires multiple statements with mods.Flavor, an
+// error is returned instead; use Statements in this situation. Diffs emitted
+// by NewSchemaDiff always correspond to at most one statement.
 func (rd *RoutineDiff) Statement(mods StatementModifiers) (string, error) {
+	stmts, err := rd.Statements(mods)
+	if len(stmts) > 1 {
+		return "", fmt.Errorf("Replacing %s requires %d statements; use Statements instead of Statement", rd.ObjectKey(), len(stmts))
+	} else if len(stmts) == 0 {
+		return "", err
+	}
+	return stmts[0], err
+}
+
+// Statements returns the DDL statements corresponding to the RoutineDiff, or
+// to the single step in rd.Step if set. No statements are returned if the mods
+// indicate the diff should be skipped. If
+// the mods indicate the statements should be disallowed, they will still be
+// returned as-is, but the error will be non-nil. Be sure not to ignore the
+// error value of this method.
+func (rd *RoutineDiff) Statements(mods StatementModifiers) ([]string, error) {
 	// If we're replacing a routine only because its creation-time sql_mode or
 	// db collation has changed, only proceed if mods indicate we should. (This
 	// type of replacement is effectively opt-in because it is counter-intuitive
 	// and obscure.)
 	if rd != nil && rd.ForMetadata && !mods.CompareMetadata {
-		return "", nil
+		return nil, nil
+	}
+	if dt := rd.DiffType(); (dt == DiffTypeCreate || dt == DiffTypeAlter) && !mods.StripDefiner && mods.RewriteDefiner != "" && escapeDefiner(mods.RewriteDefiner) == "" {
+		return nil, fmt.Errorf("Invalid RewriteDefiner %q: must be in user@host format", mods.RewriteDefiner)
 	}
 	switch rd.DiffType() {
 	case DiffTypeNone:
-		return "", nil
+		return nil, nil
 	case DiffTypeCreate:
-		return rd.To.CreateStatement, nil
+		return []string{rd.createStatement(mods)}, nil
 	case DiffTypeDrop:
-		var comment string
-		if rd.ForMetadata {
-			comment = fmt.Sprintf("# Dropping and re-creating %s to update metadata\n", rd.ObjectKey())
-		}
-		stmt := fmt.Sprintf("%s%s", comment, rd.From.DropStatement())
-		var err error
+		stmts := []string{rd.From.DropStatement()}
 		if !mods.AllowUnsafe {
-			err = &ForbiddenDiffError{
+			return stmts, &ForbiddenDiffError{
 				Reason:    fmt.Sprintf("DROP %s not permitted", rd.From.Type.Caps()),
-				Statement: stmt,
+				Statement: stmts[0],
 			}
 		}
-		return stmt, err
-	default: // DiffTypeAlter and DiffTypeRename not supported yet
-		return "", fmt.Errorf("Unsupported diff type %d", rd.DiffType())
+		return stmts, nil
+	case DiffTypeAlter:
+		if rd.From.CanAlterTo(rd.To) {
+			if rd.Step != RoutineDiffStepAll && rd.Step != RoutineDiffStepCreate {
+				return nil, nil
+			}
+			return []string{rd.From.AlterStatement(rd.To)}, nil
+		}
+		return rd.replaceStatements(mods)
+	default: // DiffTypeRename not supported yet
+		return nil, fmt.Errorf("Unsupported diff type %d", rd.DiffType())
+	}
+}
+
+// createStatement returns the CREATE statement for rd.To, with its DEFINER
+// clause adjusted as per mods.
+func (rd *RoutineDiff) createStatement(mods StatementModifiers) string {
+	if mods.StripDefiner {
+		return rewriteDefiner(rd.To.CreateStatement, "")
+	} else if mods.RewriteDefiner != "" {
+		return rewriteDefiner(rd.To.CreateStatement, mods.RewriteDefiner)
+	}
+	return rd.To.CreateStatement
+}
+
+// replaceStatements returns the statements for replacing rd.From with rd.To,
+// for changes that cannot be made with ALTER. If the flavor supports CREATE OR
+// REPLACE, a single atomic statement is used, which leaves the existing
+// routine untouched if the new one fails to compile. Otherwise, the new
+// routine is first created and dropped under a temporary name, so that a
+// compilation failure halts execution before the existing routine is dropped.
+// Any leftover temporary routine, from a previous interrupted run, is dropped
+// before this validation step.
+// Since the DROP and CREATE still leave a brief window in which the routine
+// does not exist, this method is only permitted if mods.AllowUnsafe is true.
+func (rd *RoutineDiff) replaceStatements(mods StatementModifiers) ([]string, error) {
+	stepStmts := make([]string, RoutineDiffStepCreate+1)
+	create := rd.createStatement(mods)
+	atomic := mods.Flavor.AllowCreateOrReplace() && strings.HasPrefix(create, "CREATE ")
+	if atomic {
+		stepStmts[RoutineDiffStepCreate] = "CREATE OR REPLACE " + create[7:]
+	} else {
+		tempName := rd.To.validationName()
+		if tempCreate, ok := rd.To.renameInCreate(create, tempName); ok {
+			tempRoutine := Routine{Name: tempName, Type: rd.To.Type}
+			stepStmts[RoutineDiffStepCleanupTemp] = tempRoutine.dropIfExistsStatement()
+			stepStmts[RoutineDiffStepCreateTemp] = tempCreate
+			stepStmts[RoutineDiffStepDropTemp] = tempRoutine.DropStatement()
+		}
+		stepStmts[RoutineDiffStepDrop] = rd.From.DropStatement()
+		stepStmts[RoutineDiffStepCreate] = create
+	}
+	var stmts []string
+	var commented bool
+	for step, stmt := range stepStmts {
+		if stmt == "" {
+			continue
+		}
+		if rd.ForMetadata && !commented {
+			stmt = fmt.Sprintf("# Replacing %s to update metadata\n%s", rd.ObjectKey(), stmt)
+			commented = true
+		}
+		if rd.Step == RoutineDiffStepAll || rd.Step == RoutineDiffStep(step) {
+			stmts = append(stmts, stmt)
+		}
+	}
+	if !atomic && !mods.AllowUnsafe && len(stmts) > 0 {
+		return stmts, &ForbiddenDiffError{
+			Reason:    fmt.Sprintf("DROP %s not permitted", rd.From.Type.Caps()),
+			Statement: strings.Join(stmts, ";\n"),
+		}
+	}
+	return stmts, nil
+}
+
+// Changes returns human-readable descriptions of the differences between
+// rd.From and rd.To, explaining why the routine is being altered or replaced.
+// Parameters and return types are compared after parsing, so that a change in
+// a single parameter is described specifically. Nil is returned for diffs
+// other than DiffTypeAlter, and for replacement steps other than
+// RoutineDiffStepCreate, so that each change is only described once.
+func (rd *RoutineDiff) Changes() []string {
+	if rd.DiffType() != DiffTypeAlter || (rd.Step != RoutineDiffStepAll && rd.Step != RoutineDiffStepCreate) {
+		return nil
+	}
+	from, to := rd.From, rd.To
+	changes := routineParamChanges(from, to)
+	if from.ReturnDataType != to.ReturnDataType {
+		fromRet, fromErr := from.ReturnType()
+		toRet, toErr := to.ReturnType()
+		if fromErr != nil || toErr != nil || *fromRet != *toRet {
+			changes = append(changes, fmt.Sprintf("return type changed from %s to %s", from.ReturnDataType, to.ReturnDataType))
+		}
+	}
+	if from.Body != to.Body {
+		changes = append(changes, "body changed")
+	}
+	if from.Deterministic != to.Deterministic {
+		names := map[bool]string{true: "DETERMINISTIC", false: "NOT DETERMINISTIC"}
+		changes = append(changes, fmt.Sprintf("changed from %s to %s", names[from.Deterministic], names[to.Deterministic]))
+	}
+	if from.SQLDataAccess != to.SQLDataAccess {
+		changes = append(changes, fmt.Sprintf("data access changed from %s to %s", from.SQLDataAccess, to.SQLDataAccess))
+	}
+	if from.SecurityType != to.SecurityType {
+		changes = append(changes, fmt.Sprintf("SQL SECURITY changed from %s to %s", from.SecurityType, to.SecurityType))
+	}
+	if from.Comment != to.Comment {
+		changes = append(changes, "comment changed")
+	}
+	if from.Definer != to.Definer {
+		changes = append(changes, fmt.Sprintf("definer changed from %s to %s", from.Definer, to.Definer))
+	}
+	if from.SQLMode != to.SQLMode {
+		changes = append(changes, fmt.Sprintf("creation-time sql_mode changed from '%s' to '%s'", from.SQLMode, to.SQLMode))
+	}
+	if from.DatabaseCollation != to.DatabaseCollation {
+		changes = append(changes, fmt.Sprintf("creation-time database collation changed from %s to %s", from.DatabaseCollation, to.DatabaseCollation))
+	}
+	return changes
+}
+
+// routineParamChanges returns descriptions of the differences between the
+// parameters of two routines, compared by position.
+func routineParamChanges(from, to *Routine) []string {
+	if from.ParamString == to.ParamString {
+		return nil
+	}
+	fromParams, fromErr := from.Params()
+	toParams, toErr := to.Params()
+	if fromErr != nil || toErr != nil {
+		return []string{"parameter list changed"}
+	}
+	var changes []string
+	for n := 0; n < len(fromParams) || n < len(toParams); n++ {
+		if n >= len(toParams) {
+			changes = append(changes, fmt.Sprintf("parameter %s removed", EscapeIdentifier(fromParams[n].Name)))
+		} else if n >= len(fromParams) {
+			changes = append(changes, fmt.Sprintf("parameter %s added", EscapeIdentifier(toParams[n].Name)))
+		} else if *fromParams[n] != *toParams[n] {
+			changes = append(changes, fmt.Sprintf("parameter %d changed from %s to %s", n+1, fromParams[n], toParams[n]))
+		}
+	}
+	if len(changes) == 0 {
+		changes = append(changes, "parameter list formatting changed")
+	}
+	return changes
+}
+
+///// UserDiff /////////////////////////////////////////////////////////////////
+
+// UserDiff represents a difference between two users or roles.
+type UserDiff struct {
+	From *User
+	To   *User
+}
+
+// NewUserDiffs computes the set of differences between two sets of users and
+// roles, typically obtained from Instance.Users on two different instances.
+// Users and roles are matched by account name and host only. In MySQL 8, a
+// role is an ordinary account which Instance.Users can only identify as a role
+// if it has been granted or set as a default role, so the same account may be
+// a user on one side and a role on the other; this results in an alter rather
+// than a drop and re-create.
+func NewUserDiffs(from, to []*User) (userDiffs []*UserDiff) {
+	fromByAccount := make(map[string]*User, len(from))
+	for _, u := range from {
+		fromByAccount[u.Account()] = u
+	}
+	toByAccount := make(map[string]*User, len(to))
+	for _, u := range to {
+		toByAccount[u.Account()] = u
+	}
+
+	// Roles are created before users, since users may be granted roles; drops
+	// are done in the reverse order
+	for _, u := range to {
+		if u.IsRole && fromByAccount[u.Account()] == nil {
+			userDiffs = append(userDiffs, &UserDiff{To: u})
+		}
+	}
+	for _, u := range to {
+		if fromUser := fromByAccount[u.Account()]; fromUser == nil && !u.IsRole {
+			userDiffs = append(userDiffs, &UserDiff{To: u})
+		} else if fromUser != nil {
+			if ud := (&UserDiff{From: fromUser, To: u}); len(ud.alterStatements(FlavorUnknown)) > 0 {
+				userDiffs = append(userDiffs, ud)
+			}
+		}
+	}
+	for _, u := range from {
+		if !u.IsRole && toByAccount[u.Account()] == nil {
+			userDiffs = append(userDiffs, &UserDiff{From: u})
+		}
+	}
+	for _, u := range from {
+		if u.IsRole && toByAccount[u.Account()] == nil {
+			userDiffs = append(userDiffs, &UserDiff{From: u})
+		}
+	}
+	return userDiffs
+}
+
+// ObjectKey returns a value representing the type and account of the user or
+// role being diff'ed. The type will be either ObjectTypeUser or
+// ObjectTypeRole.
+func (ud *UserDiff) ObjectKey() ObjectKey {
+	if ud != nil && ud.From != nil {
+		return ud.From.ObjectKey()
+	} else if ud != nil && ud.To != nil {
+		return ud.To.ObjectKey()
+	}
+	return ObjectKey{}
+}
+
+// DiffType returns the type of diff operation.
+func (ud *UserDiff) DiffType() DiffType {
+	if ud == nil || (ud.To == nil && ud.From == nil) {
+		return DiffTypeNone
+	} else if ud.To == nil {
+		return DiffTypeDrop
+	} else if ud.From == nil {
+		return DiffTypeCreate
+	}
+	return DiffTypeAlter
+}
+
+// Statement returns the statements corresponding to the UserDiff, joined by
+// semicolons and newlines. See Statements for more information.
+func (ud *UserDiff) Statement(mods StatementModifiers) (string, error) {
+	stmts, err := ud.Statements(mods)
+	return strings.Join(stmts, ";\n"), err
+}
+
+// Statements returns the CREATE USER, ALTER USER, GRANT, REVOKE, or DROP USER
+// statements needed to transform the From side into the To side. Since these
+// statements may remove access, DROP USER and REVOKE are only permitted if
+// mods.AllowUnsafe is true. If the mods indicate the statements should be
+// disallowed, they will still be returned as-is, but the error will be non-nil.
+// Be sure not to ignore the error value of this method.
+func (ud *UserDiff) Statements(mods StatementModifiers) ([]string, error) {
+	var stmts []string
+	var unsafe bool
+	switch ud.DiffType() {
+	case DiffTypeCreate:
+		return ud.To.CreateStatements(mods.Flavor), nil
+	case DiffTypeDrop:
+		stmts, unsafe = []string{ud.From.DropStatement()}, true
+	case DiffTypeAlter:
+		stmts = ud.alterStatements(mods.Flavor)
+		for _, stmt := range stmts {
+			if strings.HasPrefix(stmt, "REVOKE ") || strings.HasPrefix(stmt, "DROP ") {
+				unsafe = true
+			}
+		}
+	}
+	if unsafe && !mods.AllowUnsafe {
+		reason := fmt.Sprintf("Removing privileges from %s not permitted", ud.ObjectKey())
+		if ud.DiffType() == DiffTypeDrop {
+			reason = fmt.Sprintf("DROP %s not permitted", ud.From.ObjectKey().Type.Caps())
+		}
+		return stmts, &ForbiddenDiffError{
+			Reason:    reason,
+			Statement: strings.Join(stmts, ";\n"),
+		}
+	}
+	return stmts, nil
+}
+
+// alterStatements returns the statements needed to transform an existing user
+// or role from ud.From to ud.To.
+func (ud *UserDiff) alterStatements(flavor Flavor) []string {
+	from, to := ud.From, ud.To
+	acct := to.Account()
+	var stmts []string
+	if from.IsRole != to.IsRole && (from.Host == "" || to.Host == "") {
+		// MariaDB roles have no host, and are a distinct type of object from
+		// users, so converting between the two requires re-creating the account
+		stmts = append(stmts, from.DropStatement())
+		return append(stmts, to.CreateStatements(flavor)...)
+	}
+
+	// Account attributes. MySQL 8 roles are ordinary accounts, so if either side
+	// is a user, attributes are altered in place even if the other side is a
+	// role.
+	if !to.IsRole || !from.IsRole {
+		var authClause, optionClauses string
+		if from.AuthPlugin != to.AuthPlugin || from.AuthString != to.AuthString || (from.PasswordExpired && !to.PasswordExpired) {
+			// Setting the password again is the only way to un-expire it
+			authClause = to.authClause(flavor)
+		}
+		limits := to.limitsClause(from)
+		if accountLockSupported(flavor) {
+			if from.PasswordLifetime != to.PasswordLifetime {
+				optionClauses += passwordLifetimeClause(to.PasswordLifetime)
+			}
+			if to.Locked && !from.Locked {
+				optionClauses += " ACCOUNT LOCK"
+			} else if from.Locked && !to.Locked {
+				optionClauses += " ACCOUNT UNLOCK"
+			}
+		}
+		if !accountOptionsInCreateUser(flavor) {
+			if authClause != "" {
+				stmts = append(stmts, fmt.Sprintf("SET PASSWORD FOR %s = %s", acct, quoteAuthString(to.AuthString)))
+			}
+			if limits != "" {
+				stmts = append(stmts, fmt.Sprintf("GRANT USAGE ON *.* TO %s%s", acct, limits))
+			}
+		} else if authClause != "" || limits != "" || optionClauses != "" {
+			stmts = append(stmts, fmt.Sprintf("ALTER USER %s%s%s%s", acct, authClause, limits, optionClauses))
+		}
+		if to.PasswordExpired && !from.PasswordExpired && accountLockSupported(flavor) {
+			stmts = append(stmts, fmt.Sprintf("ALTER USER %s PASSWORD EXPIRE", acct))
+		}
+	}
+
+	// Privileges: grants are emitted before revokes, to avoid momentary loss of
+	// access when privileges are being restructured
+	var revokes []string
+	fromGrants, toGrants := from.GrantsByTarget(), to.GrantsByTarget()
+	for _, toGrant := range to.Grants {
+		fromGrant := fromGrants[toGrant.Target]
+		if fromGrant == nil {
+			fromGrant = &Grant{Target: toGrant.Target}
+		}
+		added := stringsDifference(toGrant.Privileges, fromGrant.Privileges)
+		removed := stringsDifference(fromGrant.Privileges, toGrant.Privileges)
+		if fromGrant.GrantOption && !toGrant.GrantOption {
+			removed = append(removed, "GRANT OPTION")
+		}
+		if len(added) > 0 || (toGrant.GrantOption && !fromGrant.GrantOption) {
+			stmts = append(stmts, toGrant.grantStatement(acct, added, toGrant.GrantOption && !fromGrant.GrantOption))
+		}
+		if len(removed) > 0 {
+			revokes = append(revokes, toGrant.revokeStatement(acct, removed))
+		}
+	}
+	for _, fromGrant := range from.Grants {
+		if toGrants[fromGrant.Target] == nil {
+			removed := fromGrant.Privileges
+			if fromGrant.GrantOption {
+				removed = append(removed[:len(removed):len(removed)], "GRANT OPTION")
+			}
+			revokes = append(revokes, fromGrant.revokeStatement(acct, removed))
+		}
+	}
+
+	// Roles: changing the admin option requires revoking and re-granting
+	fromRoles := make(map[string]*RoleGrant, len(from.Roles))
+	for _, rg := range from.Roles {
+		fromRoles[rg.Role] = rg
+	}
+	toRoles := make(map[string]*RoleGrant, len(to.Roles))
+	for _, rg := range to.Roles {
+		toRoles[rg.Role] = rg
+		if fromRG := fromRoles[rg.Role]; fromRG == nil || (rg.AdminOption && !fromRG.AdminOption) {
+			stmts = append(stmts, rg.grantStatement(acct))
+		} else if fromRG.AdminOption && !rg.AdminOption {
+			revokes = append(revokes, fmt.Sprintf("REVOKE %s FROM %s", rg.Role, acct), rg.grantStatement(acct))
+		}
+	}
+	for _, rg := range from.Roles {
+		if toRoles[rg.Role] == nil {
+			revokes = append(revokes, fmt.Sprintf("REVOKE %s FROM %s", rg.Role, acct))
+		}
+	}
+	stmts = append(stmts, revokes...)
+
+	if strings.Join(from.DefaultRoles, ",") != strings.Join(to.DefaultRoles, ",") {
+		stmts = append(stmts, defaultRoleStatement(acct, to.DefaultRoles, flavor))
 	}
+	return stmts
 }
 
 ///// Errors ///////////////////////////////////////////////////////////////////
@@ -2,9 +2,11 @@ package tengo
 
 import (
 	"fmt"
+	"reflect"
 	"regexp"
 	"strings"
 	"testing"
+	"unicode/utf8"
 )
 
 func TestSchemaDiffEmpty(t *testing.T) {
@@ -455,28 +457,25 @@ func TestSchemaDiffRoutines(t *testing.T) {
 		t.Errorf("Modifier AllowUnsafe=true not working; error (%s) returned for %s", err, stmt)
 	}
 
-	// Test alter, which currently always is handled by a drop and re-add.
-	// Since this is a creation-time metadata change, also test statement modifier
-	// affecting whether or not those changes are suppressed.
+	// Test alter of creation-time metadata, which is handled by replacing the
+	// routine, since ALTER cannot change it. Since this is a metadata change,
+	// also test statement modifier affecting whether or not those changes are
+	// suppressed.
 	s1r2 := aProc("utf8mb4_general_ci", "")
 	s1.Routines = append(s1.Routines, &s1r2)
+	// Replacement is split into one diff per step, each with at most one
+	// statement.
 	sd = NewSchemaDiff(&s2, &s1)
-	if len(sd.RoutineDiffs) != 2 {
-		t.Fatalf("Incorrect number of routine diffs: expected 2, found %d", len(sd.RoutineDiffs))
+	if len(sd.RoutineDiffs) != 5 {
+		t.Fatalf("Incorrect number of routine diffs: expected 5, found %d", len(sd.RoutineDiffs))
 	}
-	rd = sd.RoutineDiffs[0]
-	if rd.DiffType() != DiffTypeDrop {
-		t.Fatalf("Incorrect type of diff returned: expected %s, found %s", DiffTypeDrop, rd.DiffType())
-	}
-	if rd.From != &s2r2 || rd.ObjectKey().Name != s2r2.Name {
-		t.Error("Pointer in diff does not point to expected value")
-	}
-	rd = sd.RoutineDiffs[1]
-	if rd.DiffType() != DiffTypeCreate {
-		t.Fatalf("Incorrect type of diff returned: expected %s, found %s", DiffTypeCreate, rd.DiffType())
-	}
-	if rd.To != &s1r2 || rd.ObjectKey().Name != s1r2.Name {
-		t.Error("Pointer in diff does not point to expected value")
+	for n, rd := range sd.RoutineDiffs {
+		if rd.DiffType() != DiffTypeAlter || !rd.ForMetadata || rd.Step != RoutineDiffStep(n+1) {
+			t.Fatalf("Incorrect diff returned: expected %s step %d for metadata, found %s step %d", DiffTypeAlter, n+1, rd.DiffType(), rd.Step)
+		}
+		if rd.From != &s2r2 || rd.To != &s1r2 || rd.ObjectKey().Name != s2r2.Name {
+			t.Error("Pointer in diff does not point to expected value")
+		}
 	}
 	mods := StatementModifiers{AllowUnsafe: true}
 	for _, od := range sd.ObjectDiffs() {
@@ -488,10 +487,18 @@ func TestSchemaDiffRoutines(t *testing.T) {
 	mods.CompareMetadata = true
 	for n, od := range sd.ObjectDiffs() {
 		stmt, err := od.Statement(mods)
-		if stmt == "" || err != nil || (n == 0 && !strings.HasPrefix(stmt, "# ")) {
+		if stmt == "" || err != nil || strings.HasPrefix(stmt, "# ") != (n == 0) {
 			t.Errorf("Unexpected return from Statement: %s / %v", stmt, err)
 		}
 	}
+	// With CREATE OR REPLACE, only the final step has a statement
+	mods.Flavor = FlavorMariaDB103
+	for n, od := range sd.ObjectDiffs() {
+		stmt, err := od.Statement(mods)
+		if err != nil || (stmt != "") != (n == 4) || (n == 4 && !strings.HasPrefix(stmt, "# Replacing procedure `proc1` to update metadata\nCREATE OR REPLACE ")) {
+			t.Errorf("Unexpected return from Statement for step %d: %s / %v", n+1, stmt, err)
+		}
+	}
 
 	// Confirm that procs and funcs with same name are handled properly
 	s1r2 = aProc("latin1_swedish_ci", "")
@@ -510,6 +517,179 @@ func TestSchemaDiffRoutines(t *testing.T) {
 	}
 }
 
+func TestSchemaDiffRoutineAlter(t *testing.T) {
+	s1 := aSchema("s1")
+	s2 := aSchema("s2")
+	from := aProc("latin1_swedish_ci", "")
+	to := aProc("latin1_swedish_ci", "")
+	to.Comment = "it's new"
+	to.SecurityType = "DEFINER"
+	to.CreateStatement = to.Definition(FlavorUnknown)
+	s1.Routines = []*Routine{&from}
+	s2.Routines = []*Routine{&to}
+
+	sd := NewSchemaDiff(&s1, &s2)
+	if len(sd.RoutineDiffs) != 1 {
+		t.Fatalf("Incorrect number of routine diffs: expected 1, found %d", len(sd.RoutineDiffs))
+	}
+	rd := sd.RoutineDiffs[0]
+	if rd.DiffType() != DiffTypeAlter || rd.From != &from || rd.To != &to {
+		t.Fatalf("Unexpected diff returned: %+v", *rd)
+	}
+	expected := "ALTER PROCEDURE `proc1` SQL SECURITY DEFINER COMMENT 'it''s new'"
+	if stmt, err := rd.Statement(StatementModifiers{}); stmt != expected || err != nil {
+		t.Errorf("Unexpected return value from Statement(): %s / %v", stmt, err)
+	}
+
+	// Data access characteristic is also alterable
+	to = aProc("latin1_swedish_ci", "")
+	to.SQLDataAccess = "MODIFIES SQL DATA"
+	to.CreateStatement = to.Definition(FlavorUnknown)
+	sd = NewSchemaDiff(&s1, &s2)
+	expected = "ALTER PROCEDURE `proc1` MODIFIES SQL DATA"
+	if len(sd.RoutineDiffs) != 1 {
+		t.Fatalf("Incorrect number of routine diffs: expected 1, found %d", len(sd.RoutineDiffs))
+	} else if stmt, _ := sd.RoutineDiffs[0].Statement(StatementModifiers{}); stmt != expected {
+		t.Errorf("Unexpected return value from Statement(): %s", stmt)
+	}
+
+	// Changes to the body, params, or determinism require a drop and re-add, even
+	// if a characteristic changed too
+	to.Deterministic = true
+	to.CreateStatement = to.Definition(FlavorUnknown)
+	if to.CanAlterTo(&from) || from.CanAlterTo(&to) {
+		t.Error("Expected CanAlterTo to return false for DETERMINISTIC change, but it returned true")
+	}
+	to = aProc("latin1_swedish_ci", "")
+	to.Comment = "hello"
+	to.Body = "BEGIN END"
+	to.CreateStatement = to.Definition(FlavorUnknown)
+	sd = NewSchemaDiff(&s1, &s2)
+	if len(sd.RoutineDiffs) != 5 {
+		t.Fatalf("Incorrect number of routine diffs: expected 5, found %d", len(sd.RoutineDiffs))
+	}
+	for _, rd := range sd.RoutineDiffs {
+		if stmt, _ := rd.Statement(StatementModifiers{AllowUnsafe: true}); strings.HasPrefix(stmt, "ALTER") {
+			t.Errorf("Expected body change to generate drop and re-add, instead found %s", stmt)
+		}
+		if changes := rd.Changes(); (len(changes) > 0) != (rd.Step == RoutineDiffStepCreate) {
+			t.Errorf("Expected changes only for final step, instead found %v for step %d", changes, rd.Step)
+		}
+	}
+	if from.CanAlterTo(&from) {
+		t.Error("Expected CanAlterTo to return false for identical routines, but it returned true")
+	}
+}
+
+func TestRoutineDiffReplace(t *testing.T) {
+	from := aFunc("latin1_swedish_ci", "")
+	to := aFunc("latin1_swedish_ci", "")
+	to.Body = "return mult * 3.0"
+	to.CreateStatement = to.Definition(FlavorUnknown)
+	rd := &RoutineDiff{From: &from, To: &to}
+
+	// MariaDB replaces atomically, which does not require AllowUnsafe
+	stmts, err := rd.Statements(StatementModifiers{Flavor: FlavorMariaDB103, StripDefiner: true})
+	if err != nil {
+		t.Errorf("Unexpected error from Statements: %s", err)
+	} else if len(stmts) != 1 || !strings.HasPrefix(stmts[0], "CREATE OR REPLACE FUNCTION `func1`(") || !strings.HasSuffix(stmts[0], to.Body) {
+		t.Errorf("Unexpected statements for MariaDB: %v", stmts)
+	}
+
+	// Other flavors validate under a temporary name, then drop and re-create
+	mods := StatementModifiers{Flavor: FlavorMySQL57}
+	stmts, err = rd.Statements(mods)
+	if !IsForbiddenDiff(err) {
+		t.Errorf("Expected forbidden diff error, instead found %v", err)
+	}
+	if len(stmts) != 5 {
+		t.Fatalf("Expected 5 statements, instead found %d: %v", len(stmts), stmts)
+	}
+	expectedPrefixes := []string{
+		"DROP FUNCTION IF EXISTS `_tengo_new_func1`",
+		"CREATE DEFINER=`root`@`localhost` FUNCTION `_tengo_new_func1`(",
+		"DROP FUNCTION `_tengo_new_func1`",
+		"DROP FUNCTION `func1`",
+		"CREATE DEFINER=`root`@`localhost` FUNCTION `func1`(",
+	}
+	for n, prefix := range expectedPrefixes {
+		if !strings.HasPrefix(stmts[n], prefix) {
+			t.Errorf("Expected statement[%d] to begin with %s, instead found %s", n, prefix, stmts[n])
+		}
+	}
+	mods.AllowUnsafe = true
+	if stmt, err := rd.Statement(mods); err == nil || stmt != "" {
+		t.Errorf("Expected Statement to return an error for multi-statement replacement, instead found %s / %v", stmt, err)
+	}
+	for n := range stmts {
+		stepDiff := &RoutineDiff{From: &from, To: &to, Step: RoutineDiffStep(n + 1)}
+		if stmt, err := stepDiff.Statement(mods); err != nil || stmt != stmts[n] {
+			t.Errorf("Unexpected return from Statement for step %d: %s / %v", n+1, stmt, err)
+		}
+	}
+
+	// Temporary name is truncated to the max identifier length
+	from.Name = strings.Repeat("x", 60)
+	if name := from.validationName(); len(name) != 64 || !strings.HasPrefix(name, "_tengo_new_xxx") {
+		t.Errorf("Unexpected validationName result: %s", name)
+	}
+
+	// Truncation counts characters, not bytes, and never splits a character
+	from.Name = strings.Repeat("é", 60)
+	if name := from.validationName(); utf8.RuneCountInString(name) != 64 || !utf8.ValidString(name) {
+		t.Errorf("Unexpected validationName result: %s", name)
+	}
+}
+
+func TestRoutineDiffChanges(t *testing.T) {
+	from := aProc("latin1_swedish_ci", "")
+	to := aProc("latin1_swedish_ci", "")
+	to.ParamString = "IN name varchar(30) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin, INOUT iterations bigint unsigned, OUT pct decimal(5,2), OUT extra int"
+	to.SecurityType = "DEFINER"
+	to.SQLMode = "STRICT_TRANS_TABLES"
+	rd := &RoutineDiff{From: &from, To: &to}
+	expected := []string{
+		"parameter 2 changed from INOUT `iterations` int(10) unsigned to INOUT `iterations` bigint(20) unsigned",
+		"parameter `extra` added",
+		"SQL SECURITY changed from INVOKER to DEFINER",
+		"creation-time sql_mode changed from '' to 'STRICT_TRANS_TABLES'",
+	}
+	if actual := rd.Changes(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Unexpected result from Changes: %v", actual)
+	}
+	if changes := (&RoutineDiff{To: &to}).Changes(); changes != nil {
+		t.Errorf("Expected nil Changes for create, instead found %v", changes)
+	}
+
+	// Formatting-only differences in params are described as such
+	to = aProc("latin1_swedish_ci", "")
+	to.ParamString = "IN name VARCHAR(30) COLLATE utf8mb4_bin, INOUT iterations INTEGER(10) UNSIGNED, OUT pct DEC(5,2)"
+	expected = []string{"parameter list formatting changed"}
+	if actual := rd.Changes(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Unexpected result from Changes: %v", actual)
+	}
+
+	fromFunc := aFunc("latin1_swedish_ci", "")
+	toFunc := aFunc("latin1_swedish_ci", "")
+	toFunc.ParamString = ""
+	toFunc.ReturnDataType = "double"
+	toFunc.Body = "return 2.0"
+	toFunc.Deterministic = !fromFunc.Deterministic
+	rd = &RoutineDiff{From: &fromFunc, To: &toFunc}
+	expected = []string{
+		"parameter `mult` removed",
+		"return type changed from float to double",
+		"body changed",
+		"changed from DETERMINISTIC to NOT DETERMINISTIC",
+	}
+	if !fromFunc.Deterministic {
+		expected[3] = "changed from NOT DETERMINISTIC to DETERMINISTIC"
+	}
+	if actual := rd.Changes(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Unexpected result from Changes: %v", actual)
+	}
+}
+
 func TestSchemaDiffFilteredTableDiffs(t *testing.T) {
 	s1t1 := anotherTable()
 	s1t2 := aTable(1)
@@ -785,3 +965,151 @@ func TestNilObjectDiff(t *testing.T) {
 		t.Errorf("Unexpected return from Statement: %s / %v", stmt, err)
 	}
 }
+
+func TestUserDiffs(t *testing.T) {
+	role := &User{Name: "reader", Host: "%", IsRole: true, PasswordLifetime: -1}
+	from := &User{
+		Name:             "app",
+		Host:             "%",
+		AuthPlugin:       "mysql_native_password",
+		AuthString:       "*ABC",
+		PasswordLifetime: -1,
+		Grants: []*Grant{
+			{Target: "*.*", Privileges: []string{"PROCESS"}},
+			{Target: "`shop`.*", Privileges: []string{"INSERT", "SELECT"}, GrantOption: true},
+		},
+	}
+	to := &User{
+		Name:               "app",
+		Host:               "%",
+		AuthPlugin:         "mysql_native_password",
+		AuthString:         "*DEF",
+		PasswordLifetime:   -1,
+		Locked:             true,
+		MaxUserConnections: 3,
+		Grants: []*Grant{
+			{Target: "`shop`.*", Privileges: []string{"DELETE", "SELECT"}},
+		},
+		Roles:        []*RoleGrant{{Role: "`reader`@`%`"}},
+		DefaultRoles: []string{"`reader`@`%`"},
+	}
+
+	diffs := NewUserDiffs([]*User{from}, []*User{to, role})
+	if len(diffs) != 2 || diffs[0].DiffType() != DiffTypeCreate || diffs[0].ObjectKey().Type != ObjectTypeRole || diffs[1].DiffType() != DiffTypeAlter {
+		t.Fatalf("Unexpected diffs: %+v", diffs)
+	}
+	mods := StatementModifiers{Flavor: FlavorMySQL80}
+	stmt, err := diffs[1].Statement(mods)
+	if !IsForbiddenDiff(err) {
+		t.Errorf("Expected forbidden diff error, instead found %v", err)
+	}
+	mods.AllowUnsafe = true
+	if stmt, err = diffs[1].Statement(mods); err != nil {
+		t.Fatalf("Unexpected error from Statement: %s", err)
+	}
+	expected := strings.Join([]string{
+		"ALTER USER `app`@`%` IDENTIFIED WITH mysql_native_password AS '*DEF' WITH MAX_USER_CONNECTIONS 3 ACCOUNT LOCK",
+		"GRANT DELETE ON `shop`.* TO `app`@`%`",
+		"GRANT `reader`@`%` TO `app`@`%`",
+		"REVOKE INSERT, GRANT OPTION ON `shop`.* FROM `app`@`%`",
+		"REVOKE PROCESS ON *.* FROM `app`@`%`",
+		"SET DEFAULT ROLE `reader`@`%` TO `app`@`%`",
+	}, ";\n")
+	if stmt != expected {
+		t.Errorf("Unexpected statement:\n%s\nExpected:\n%s", stmt, expected)
+	}
+
+	// Reverse direction should drop the role, and be forbidden without
+	// AllowUnsafe
+	diffs = NewUserDiffs([]*User{to, role}, []*User{from})
+	if len(diffs) != 2 || diffs[1].DiffType() != DiffTypeDrop || diffs[1].ObjectKey().Type != ObjectTypeRole {
+		t.Fatalf("Unexpected diffs: %+v", diffs)
+	}
+	if stmt, err := diffs[1].Statement(StatementModifiers{}); stmt != "DROP ROLE `reader`@`%`" || !IsForbiddenDiff(err) {
+		t.Errorf("Unexpected return from Statement: %q, %v", stmt, err)
+	}
+
+	// Identical users should have no diffs
+	if diffs = NewUserDiffs([]*User{from, role}, []*User{from, role}); len(diffs) != 0 {
+		t.Errorf("Expected no diffs between identical users, instead found %d", len(diffs))
+	}
+
+	// In MySQL 8, an account may only be detected as a role on one side. This
+	// should be an alter, rather than a drop and re-create.
+	unusedRole := &User{Name: "reader", Host: "%", PasswordLifetime: -1}
+	diffs = NewUserDiffs([]*User{unusedRole}, []*User{role})
+	if len(diffs) != 0 {
+		t.Errorf("Expected no diffs between unused role and role, instead found %+v", diffs)
+	}
+	unusedRole.Locked = true
+	diffs = NewUserDiffs([]*User{role}, []*User{unusedRole})
+	if len(diffs) != 1 || diffs[0].DiffType() != DiffTypeAlter {
+		t.Fatalf("Unexpected diffs: %+v", diffs)
+	}
+	if stmt, err := diffs[0].Statement(StatementModifiers{Flavor: FlavorMySQL80}); stmt != "ALTER USER `reader`@`%` ACCOUNT LOCK" || err != nil {
+		t.Errorf("Unexpected return from Statement: %q, %v", stmt, err)
+	}
+
+	// MariaDB roles have no host, so a role and user with the same name are
+	// different accounts; but a user with a blank host has the same account as
+	// a role, and must be re-created
+	mariaRole := &User{Name: "reader", IsRole: true, PasswordLifetime: -1}
+	diffs = NewUserDiffs([]*User{mariaRole}, []*User{unusedRole})
+	if len(diffs) != 2 || diffs[0].DiffType() != DiffTypeCreate || diffs[1].DiffType() != DiffTypeDrop {
+		t.Errorf("Unexpected diffs: %+v", diffs)
+	}
+	blankHostUser := &User{Name: "reader", PasswordLifetime: -1}
+	diffs = NewUserDiffs([]*User{mariaRole}, []*User{blankHostUser})
+	if len(diffs) != 1 || diffs[0].DiffType() != DiffTypeAlter {
+		t.Fatalf("Unexpected diffs: %+v", diffs)
+	}
+	mods = StatementModifiers{Flavor: FlavorMariaDB103, AllowUnsafe: true}
+	if stmt, err := diffs[0].Statement(mods); stmt != "DROP ROLE `reader`;\nCREATE USER `reader`" || err != nil {
+		t.Errorf("Unexpected return from Statement: %q, %v", stmt, err)
+	}
+}
+
+func TestRoutineDiffDefinerMods(t *testing.T) {
+	proc := aProc("latin1_swedish_ci", "")
+	rd := &RoutineDiff{To: &proc}
+	cases := []struct {
+		mods     StatementModifiers
+		expected string
+	}{
+		{StatementModifiers{}, "CREATE DEFINER=`root`@`localhost` PROCEDURE"},
+		{StatementModifiers{RewriteDefiner: "app@%"}, "CREATE DEFINER=`app`@`%` PROCEDURE"},
+		{StatementModifiers{StripDefiner: true}, "CREATE PROCEDURE"},
+		{StatementModifiers{StripDefiner: true, RewriteDefiner: "app@%"}, "CREATE PROCEDURE"},
+	}
+	for _, c := range cases {
+		stmt, err := rd.Statement(c.mods)
+		if err != nil {
+			t.Errorf("Unexpected error from Statement: %s", err)
+		} else if !strings.HasPrefix(stmt, c.expected) || !strings.HasSuffix(stmt, proc.Body) {
+			t.Errorf("Unexpected statement with mods %+v: %s", c.mods, stmt)
+		}
+	}
+	for _, definer := range []string{"app", "CURRENT_USER"} {
+		if _, err := rd.Statement(StatementModifiers{RewriteDefiner: definer}); err == nil {
+			t.Errorf("Expected error from Statement with RewriteDefiner %q, but err was nil", definer)
+		}
+	}
+}
+
+func TestRewriteDefiner(t *testing.T) {
+	cases := []struct {
+		input, definer, expected string
+	}{
+		{"CREATE DEFINER=`root`@`%` FUNCTION f() RETURNS int RETURN 1", "", "CREATE FUNCTION f() RETURNS int RETURN 1"},
+		{"CREATE DEFINER=`we``ird`@`10.%` PROCEDURE p() BEGIN END", "bob@localhost", "CREATE DEFINER=`bob`@`localhost` PROCEDURE p() BEGIN END"},
+		{"create definer = 'root'@'localhost' procedure p() begin end", "", "create procedure p() begin end"},
+		{"CREATE DEFINER=CURRENT_USER() PROCEDURE p() BEGIN END", "a@b", "CREATE DEFINER=`a`@`b` PROCEDURE p() BEGIN END"},
+		{"CREATE DEFINER=CURRENT_USER PROCEDURE p() BEGIN END", "", "CREATE PROCEDURE p() BEGIN END"},
+		{"CREATE PROCEDURE p() BEGIN END", "a@b", "CREATE PROCEDURE p() BEGIN END"},
+	}
+	for _, c := range cases {
+		if actual := rewriteDefiner(c.input, c.definer); actual != c.expected {
+			t.Errorf("rewriteDefiner(%q, %q): expected %q, found %q", c.input, c.definer, c.expected, actual)
+		}
+	}
+}
@@ -1,6 +1,11 @@
 package tengo
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"strings"
+
 	"github.com/VividCortex/mysqlerr"
 	"github.com/go-sql-driver/mysql"
 )
@@ -44,3 +49,48 @@ func IsAccessError(err error) bool {
 	}
 	return IsDatabaseError(err, authErrors...)
 }
+
+// NegotiationError is returned by Instance.CanConnect if a connection attempt
+// failed during TLS negotiation or authentication plugin negotiation, as
+// opposed to invalid credentials or network problems.
+type NegotiationError struct {
+	TLS bool  // true if TLS negotiation failed, false if auth plugin negotiation failed
+	Err error // underlying error from the driver or crypto/tls
+}
+
+// Error satisfies the builtin error interface.
+func (ne *NegotiationError) Error() string {
+	if ne.TLS {
+		return fmt.Sprintf("TLS negotiation failed: %s", ne.Err)
+	}
+	return fmt.Sprintf("Authentication plugin negotiation failed: %s", ne.Err)
+}
+
+// IsTLSError returns true if err indicates a failure to negotiate TLS, such as
+// the server not supporting TLS, or a certificate verification problem.
+func IsTLSError(err error) bool {
+	switch err := err.(type) {
+	case *NegotiationError:
+		return err.TLS
+	case x509.UnknownAuthorityError, x509.HostnameError, x509.CertificateInvalidError, tls.RecordHeaderError:
+		return true
+	case nil:
+		return false
+	}
+	return err == mysql.ErrNoTLS || strings.Contains(err.Error(), "tls: ")
+}
+
+// IsAuthNegotiationError returns true if err indicates that the client and
+// server could not agree on an authentication plugin. This typically means
+// the user's plugin requires an option that was not enabled, such as
+// InstanceOptions.AllowCleartextPasswords, or a newer server or driver.
+func IsAuthNegotiationError(err error) bool {
+	if ne, ok := err.(*NegotiationError); ok {
+		return !ne.TLS
+	}
+	switch err {
+	case mysql.ErrCleartextPassword, mysql.ErrNativePassword, mysql.ErrOldPassword, mysql.ErrUnknownPlugin:
+		return true
+	}
+	return IsDatabaseError(err, mysqlerr.ER_NOT_SUPPORTED_AUTH_MODE)
+}
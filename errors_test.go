@@ -1,9 +1,13 @@
 package tengo
 
 import (
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"testing"
+
+	"github.com/VividCortex/mysqlerr"
+	"github.com/go-sql-driver/mysql"
 )
 
 func (s TengoIntegrationSuite) TestIsDatabaseError(t *testing.T) {
@@ -76,3 +80,38 @@ func (s TengoIntegrationSuite) TestIsAccessError(t *testing.T) {
 		t.Errorf("Error of type %T %+v unexpectedly considered access error", err, err)
 	}
 }
+
+func TestIsNegotiationError(t *testing.T) {
+	tlsErrors := []error{
+		mysql.ErrNoTLS,
+		x509.UnknownAuthorityError{},
+		errors.New("remote error: tls: bad certificate"),
+		&NegotiationError{TLS: true, Err: mysql.ErrNoTLS},
+	}
+	authErrors := []error{
+		mysql.ErrCleartextPassword,
+		mysql.ErrUnknownPlugin,
+		&mysql.MySQLError{Number: mysqlerr.ER_NOT_SUPPORTED_AUTH_MODE},
+		&NegotiationError{Err: mysql.ErrCleartextPassword},
+	}
+	otherErrors := []error{
+		nil,
+		errors.New("non-db error"),
+		&mysql.MySQLError{Number: mysqlerr.ER_ACCESS_DENIED_ERROR},
+	}
+	for _, err := range tlsErrors {
+		if !IsTLSError(err) || IsAuthNegotiationError(err) {
+			t.Errorf("Error %v not classified as expected", err)
+		}
+	}
+	for _, err := range authErrors {
+		if IsTLSError(err) || !IsAuthNegotiationError(err) {
+			t.Errorf("Error %v not classified as expected", err)
+		}
+	}
+	for _, err := range otherErrors {
+		if IsTLSError(err) || IsAuthNegotiationError(err) {
+			t.Errorf("Error %v not classified as expected", err)
+		}
+	}
+}
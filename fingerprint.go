@@ -0,0 +1,129 @@
+package tengo
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// FingerprintOptions controls which aspects of an object's definition are
+// included when computing its fingerprint.
+type FingerprintOptions struct {
+	IgnoreComments bool // If true, table, column, index, and routine comments do not affect fingerprints
+	IgnoreMetadata bool // If true, routines' creation-time sql_mode and db collation do not affect fingerprints
+}
+
+// Fingerprint returns a hex-encoded SHA-256 hash of a normalized version of
+// the table's definition. Two tables with identical definitions will have the
+// same fingerprint, even if their next auto-increment values differ.
+// For tables using features not supported by this package (UnsupportedDDL),
+// the fingerprint is based on the table's actual SHOW CREATE TABLE, and
+// comments cannot be ignored.
+func (t *Table) Fingerprint(opts FingerprintOptions) string {
+	h := sha256.New()
+	t.writeFingerprint(h, opts)
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// writeFingerprint writes the table's normalized definition to w.
+func (t *Table) writeFingerprint(w io.Writer, opts FingerprintOptions) {
+	if t.UnsupportedDDL {
+		stmt, _ := ParseCreateAutoInc(t.CreateStatement)
+		io.WriteString(w, stmt)
+		return
+	}
+	normalized := *t
+	normalized.NextAutoIncrement = 0
+	if opts.IgnoreComments {
+		normalized.Comment = ""
+		normalized.Columns = make([]*Column, len(t.Columns))
+		for n, col := range t.Columns {
+			colCopy := *col
+			colCopy.Comment = ""
+			normalized.Columns[n] = &colCopy
+		}
+		if t.PrimaryKey != nil {
+			pkCopy := *t.PrimaryKey
+			pkCopy.Comment = ""
+			normalized.PrimaryKey = &pkCopy
+		}
+		normalized.SecondaryIndexes = make([]*Index, len(t.SecondaryIndexes))
+		for n, idx := range t.SecondaryIndexes {
+			idxCopy := *idx
+			idxCopy.Comment = ""
+			normalized.SecondaryIndexes[n] = &idxCopy
+		}
+	}
+	io.WriteString(w, normalized.GeneratedCreateStatement(FlavorUnknown))
+}
+
+// Fingerprint returns a hex-encoded SHA-256 hash of a normalized version of
+// the routine's definition.
+func (r *Routine) Fingerprint(opts FingerprintOptions) string {
+	h := sha256.New()
+	r.writeFingerprint(h, opts)
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// writeFingerprint writes the routine's normalized definition to w.
+func (r *Routine) writeFingerprint(w io.Writer, opts FingerprintOptions) {
+	normalized := *r
+	if opts.IgnoreComments {
+		normalized.Comment = ""
+	}
+	io.WriteString(w, normalized.Definition(FlavorUnknown))
+	if !opts.IgnoreMetadata {
+		fmt.Fprintf(w, "\x00%s\x00%s", r.SQLMode, r.DatabaseCollation)
+	}
+}
+
+// Fingerprint returns a hex-encoded SHA-256 hash of a normalized version of
+// the schema's definition, including its default character set and collation,
+// and the definitions of all of its tables and routines. The schema's name
+// does not affect the fingerprint, allowing comparison of identical schemas
+// which have different names.
+func (s *Schema) Fingerprint(opts FingerprintOptions) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00", s.CharSet, s.Collation)
+
+	tables := make([]*Table, len(s.Tables))
+	copy(tables, s.Tables)
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].Name < tables[j].Name
+	})
+	for _, t := range tables {
+		t.writeFingerprint(h, opts)
+		h.Write([]byte{0})
+	}
+
+	routines := make([]*Routine, len(s.Routines))
+	copy(routines, s.Routines)
+	sort.Slice(routines, func(i, j int) bool {
+		if routines[i].Type != routines[j].Type {
+			return routines[i].Type < routines[j].Type
+		}
+		return routines[i].Name < routines[j].Name
+	})
+	for _, r := range routines {
+		r.writeFingerprint(h, opts)
+		h.Write([]byte{0})
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// SchemaFingerprints introspects the instance's schemas and returns a map of
+// schema name to fingerprint. If no schema names are supplied, all non-system
+// schemas are included. This permits cheap detection of schema drift between
+// instances, without needing to compute a full diff.
+func (instance *Instance) SchemaFingerprints(opts FingerprintOptions, onlyNames ...string) (map[string]string, error) {
+	schemas, err := instance.Schemas(onlyNames...)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]string, len(schemas))
+	for _, s := range schemas {
+		result[s.Name] = s.Fingerprint(opts)
+	}
+	return result, nil
+}
@@ -0,0 +1,111 @@
+package tengo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTableFingerprint(t *testing.T) {
+	t1, t2 := aTable(1), aTable(456)
+	opts := FingerprintOptions{}
+	fp := t1.Fingerprint(opts)
+	if len(fp) != 64 {
+		t.Errorf("Expected fingerprint to be 64 hex characters, instead found %q", fp)
+	}
+	if fp2 := t2.Fingerprint(opts); fp2 != fp {
+		t.Errorf("Expected next auto-increment value to be ignored, but fingerprints differ: %s vs %s", fp, fp2)
+	}
+
+	t2.Columns[1].Comment = "hello"
+	t2.SecondaryIndexes[0].Comment = "world"
+	t2.Comment = "!"
+	if fp2 := t2.Fingerprint(opts); fp2 == fp {
+		t.Error("Expected comments to affect fingerprint, but they did not")
+	}
+	opts.IgnoreComments = true
+	if fp2 := t2.Fingerprint(opts); fp2 != fp {
+		t.Errorf("Expected comments to be ignored, but fingerprints differ: %s vs %s", fp, fp2)
+	}
+	if t2.Columns[1].Comment != "hello" || t2.SecondaryIndexes[0].Comment != "world" || t2.Comment != "!" {
+		t.Error("Fingerprint unexpectedly modified the table")
+	}
+
+	t2.Columns[1].TypeInDB = "varchar(46)"
+	if fp2 := t2.Fingerprint(opts); fp2 == fp {
+		t.Error("Expected column type change to affect fingerprint, but it did not")
+	}
+
+	// Unsupported tables are fingerprinted based on CreateStatement
+	t3, t4 := unsupportedTable(), unsupportedTable()
+	t4.NextAutoIncrement = 999
+	t4.CreateStatement = strings.Replace(t4.CreateStatement, "ENGINE=InnoDB", "ENGINE=InnoDB AUTO_INCREMENT=999", 1)
+	if t3.Fingerprint(opts) != t4.Fingerprint(opts) {
+		t.Error("Expected next auto-increment value to be ignored for unsupported table, but fingerprints differ")
+	}
+	t4.CreateStatement = strings.Replace(t4.CreateStatement, "LESS THAN (123)", "LESS THAN (456)", 1)
+	if t3.Fingerprint(opts) == t4.Fingerprint(opts) {
+		t.Error("Expected different CreateStatement to affect fingerprint of unsupported table, but it did not")
+	}
+}
+
+func TestRoutineFingerprint(t *testing.T) {
+	r1, r2 := aProc("latin1_swedish_ci", ""), aProc("latin1_swedish_ci", "")
+	opts := FingerprintOptions{}
+	if r1.Fingerprint(opts) != r2.Fingerprint(opts) {
+		t.Error("Expected identical routines to have identical fingerprints")
+	}
+	r2.SQLMode = "STRICT_TRANS_TABLES"
+	if r1.Fingerprint(opts) == r2.Fingerprint(opts) {
+		t.Error("Expected sql_mode to affect fingerprint, but it did not")
+	}
+	opts.IgnoreMetadata = true
+	if r1.Fingerprint(opts) != r2.Fingerprint(opts) {
+		t.Error("Expected sql_mode to be ignored, but fingerprints differ")
+	}
+	r2.Comment = "hi"
+	if r1.Fingerprint(opts) == r2.Fingerprint(opts) {
+		t.Error("Expected comment to affect fingerprint, but it did not")
+	}
+	opts.IgnoreComments = true
+	if r1.Fingerprint(opts) != r2.Fingerprint(opts) {
+		t.Error("Expected comment to be ignored, but fingerprints differ")
+	}
+}
+
+func TestSchemaFingerprint(t *testing.T) {
+	t1, t2 := aTable(1), anotherTable()
+	t1b, t2b := aTable(20), anotherTable()
+	proc, fn := aProc("latin1_swedish_ci", ""), aFunc("latin1_swedish_ci", "")
+	s1 := aSchema("s1", &t1, &t2)
+	s1.Routines = []*Routine{&proc, &fn}
+	s2 := aSchema("s2", &t2b, &t1b)
+	s2.Routines = []*Routine{&fn, &proc}
+
+	opts := FingerprintOptions{}
+	if s1.Fingerprint(opts) != s2.Fingerprint(opts) {
+		t.Error("Expected schemas with same objects in different order to have identical fingerprints")
+	}
+	s2.Collation = "latin1_bin"
+	if s1.Fingerprint(opts) == s2.Fingerprint(opts) {
+		t.Error("Expected default collation to affect fingerprint, but it did not")
+	}
+	s2.Collation = s1.Collation
+	s2.Routines = s2.Routines[0:1]
+	if s1.Fingerprint(opts) == s2.Fingerprint(opts) {
+		t.Error("Expected missing routine to affect fingerprint, but it did not")
+	}
+}
+
+func (s TengoIntegrationSuite) TestInstanceSchemaFingerprints(t *testing.T) {
+	fingerprints, err := s.d.SchemaFingerprints(FingerprintOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error from SchemaFingerprints: %s", err)
+	}
+	schema := s.GetSchema(t, "testing")
+	if fingerprints["testing"] != schema.Fingerprint(FingerprintOptions{}) {
+		t.Errorf("Unexpected fingerprint for schema testing: %s", fingerprints["testing"])
+	}
+	if _, ok := fingerprints["information_schema"]; ok {
+		t.Error("Expected system schemas to be excluded from SchemaFingerprints")
+	}
+}
@@ -202,6 +202,21 @@ func (fl Flavor) HasDataDictionary() bool {
 	return fl.MySQLishMinVersion(8, 0)
 }
 
+// OmitIntDisplayWidth returns true if the flavor omits integer display widths
+// in SHOW CREATE TABLE, except for tinyint(1) and zerofill columns. This
+// behavior was added in MySQL 8.0.19; since flavors do not track patch
+// versions, it is assumed for all of MySQL 8.0.
+func (fl Flavor) OmitIntDisplayWidth() bool {
+	return fl.MySQLishMinVersion(8, 0)
+}
+
+// AllowCreateOrReplace returns true if the flavor supports CREATE OR REPLACE
+// for stored procedures and functions. This was added in MariaDB 10.1.3, which
+// predates the first GA release of 10.1.
+func (fl Flavor) AllowCreateOrReplace() bool {
+	return fl.VendorMinVersion(VendorMariaDB, 10, 1)
+}
+
 // DefaultUtf8mb4Collation returns the name of the default collation of the
 // utf8mb4 character set in this flavor.
 func (fl Flavor) DefaultUtf8mb4Collation() string {
@@ -211,6 +226,35 @@ func (fl Flavor) DefaultUtf8mb4Collation() string {
 	return "utf8mb4_general_ci"
 }
 
+// DefaultCollation returns the name of the default collation of the supplied
+// character set in this flavor. Most character sets use a default collation
+// of the form charset_general_ci; exceptions are tracked explicitly.
+func (fl Flavor) DefaultCollation(charSet string) string {
+	exceptions := map[string]string{
+		"binary":  "binary",
+		"latin1":  "latin1_swedish_ci",
+		"latin5":  "latin5_turkish_ci",
+		"dec8":    "dec8_swedish_ci",
+		"swe7":    "swe7_swedish_ci",
+		"hp8":     "hp8_english_ci",
+		"big5":    "big5_chinese_ci",
+		"gb2312":  "gb2312_chinese_ci",
+		"gbk":     "gbk_chinese_ci",
+		"gb18030": "gb18030_chinese_ci",
+		"sjis":    "sjis_japanese_ci",
+		"cp932":   "cp932_japanese_ci",
+		"ujis":    "ujis_japanese_ci",
+		"eucjpms": "eucjpms_japanese_ci",
+		"euckr":   "euckr_korean_ci",
+		"tis620":  "tis620_thai_ci",
+		"utf8mb4": fl.DefaultUtf8mb4Collation(),
+	}
+	if collation, ok := exceptions[charSet]; ok {
+		return collation
+	}
+	return charSet + "_general_ci"
+}
+
 // AlwaysShowTableCollation returns true if this flavor always emits a collation
 // clause for the supplied character set, even if the collation is the default
 // for the character set
@@ -174,6 +174,31 @@ func TestFlavorDefaultUtf8mb4Collation(t *testing.T) {
 	}
 }
 
+func TestFlavorDefaultCollation(t *testing.T) {
+	type testcase struct {
+		receiver Flavor
+		charSet  string
+		expected string
+	}
+	cases := []testcase{
+		{FlavorMySQL57, "utf8", "utf8_general_ci"},
+		{FlavorMySQL80, "utf8mb4", "utf8mb4_0900_ai_ci"},
+		{FlavorMySQL57, "latin1", "latin1_swedish_ci"},
+		{FlavorMySQL57, "latin2", "latin2_general_ci"},
+		{FlavorMySQL57, "latin5", "latin5_turkish_ci"},
+		{FlavorMySQL57, "dec8", "dec8_swedish_ci"},
+		{FlavorMySQL57, "swe7", "swe7_swedish_ci"},
+		{FlavorMySQL57, "hp8", "hp8_english_ci"},
+		{FlavorMariaDB101, "binary", "binary"},
+	}
+	for _, tc := range cases {
+		actual := tc.receiver.DefaultCollation(tc.charSet)
+		if actual != tc.expected {
+			t.Errorf("Expected %s.DefaultCollation(%s) to return %s, instead found %s", tc.receiver, tc.charSet, tc.expected, actual)
+		}
+	}
+}
+
 func TestFlavorAlwaysShowTableCollation(t *testing.T) {
 	type testcase struct {
 		receiver Flavor
@@ -0,0 +1,224 @@
+package tengo
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ForeignKeyProblemType enumerates ways in which a foreign key's definition is
+// incompatible with its referenced (parent) table.
+type ForeignKeyProblemType int
+
+// Constants representing the types of foreign key problems.
+const (
+	ForeignKeyMissingTable ForeignKeyProblemType = iota
+	ForeignKeyMissingColumn
+	ForeignKeyTypeMismatch
+	ForeignKeySignednessMismatch
+	ForeignKeyCharSetMismatch
+	ForeignKeyMissingParentIndex
+)
+
+func (pt ForeignKeyProblemType) String() string {
+	switch pt {
+	case ForeignKeyMissingTable:
+		return "missing parent table"
+	case ForeignKeyMissingColumn:
+		return "missing parent column"
+	case ForeignKeyTypeMismatch:
+		return "column type mismatch"
+	case ForeignKeySignednessMismatch:
+		return "column signedness mismatch"
+	case ForeignKeyCharSetMismatch:
+		return "column character set or collation mismatch"
+	case ForeignKeyMissingParentIndex:
+		return "no usable index on parent table"
+	default:
+		panic(fmt.Errorf("Unsupported foreign key problem type %d", pt))
+	}
+}
+
+// ForeignKeyProblem describes a single incompatibility between a foreign key
+// and its parent table. It satisfies the builtin error interface.
+type ForeignKeyProblem struct {
+	SchemaName string
+	Table      *Table // child table containing the foreign key
+	ForeignKey *ForeignKey
+	Type       ForeignKeyProblemType
+	Column     *Column // child column; nil if problem is not specific to one column
+	Detail     string
+}
+
+// Error satisfies the builtin error interface.
+func (p ForeignKeyProblem) Error() string {
+	var colClause string
+	if p.Column != nil {
+		colClause = fmt.Sprintf(" column %s", EscapeIdentifier(p.Column.Name))
+	}
+	return fmt.Sprintf("Foreign key %s in table %s.%s%s: %s: %s",
+		EscapeIdentifier(p.ForeignKey.Name),
+		EscapeIdentifier(p.SchemaName),
+		EscapeIdentifier(p.Table.Name),
+		colClause,
+		p.Type,
+		p.Detail)
+}
+
+// ValidateForeignKeys checks every foreign key in the supplied schemas against
+// its referenced table, returning any problems that would cause MySQL to reject
+// the foreign key's DDL. Foreign keys referencing a schema that was not
+// supplied are not validated, since their parent tables cannot be resolved.
+func ValidateForeignKeys(schemas ...*Schema) []ForeignKeyProblem {
+	schemasByName := make(map[string]*Schema, len(schemas))
+	for _, s := range schemas {
+		schemasByName[s.Name] = s
+	}
+	var problems []ForeignKeyProblem
+	for _, s := range schemas {
+		for _, t := range s.Tables {
+			for _, fk := range t.ForeignKeys {
+				problems = append(problems, validateForeignKey(s, t, fk, schemasByName)...)
+			}
+		}
+	}
+	return problems
+}
+
+// ValidateForeignKeys checks every foreign key in the schema against its
+// referenced table. Any other schemas referenced by foreign keys may be
+// supplied as args; otherwise, cross-schema foreign keys are not validated.
+// See the package-level ValidateForeignKeys for more information.
+func (s *Schema) ValidateForeignKeys(others ...*Schema) []ForeignKeyProblem {
+	schemasByName := make(map[string]*Schema, len(others)+1)
+	for _, other := range others {
+		schemasByName[other.Name] = other
+	}
+	schemasByName[s.Name] = s
+	var problems []ForeignKeyProblem
+	for _, t := range s.Tables {
+		for _, fk := range t.ForeignKeys {
+			problems = append(problems, validateForeignKey(s, t, fk, schemasByName)...)
+		}
+	}
+	return problems
+}
+
+// ReferencedTable returns the parent table of the foreign key, looked up in
+// the supplied schemas by name. The schema arg should be the schema containing
+// the child table. If the parent table cannot be found, nil is returned.
+func (fk *ForeignKey) ReferencedTable(schema *Schema, schemasByName map[string]*Schema) *Table {
+	parentSchema := schema
+	if fk.ReferencedSchemaName != "" {
+		parentSchema = schemasByName[fk.ReferencedSchemaName]
+	}
+	return parentSchema.Table(fk.ReferencedTableName)
+}
+
+func validateForeignKey(s *Schema, t *Table, fk *ForeignKey, schemasByName map[string]*Schema) (problems []ForeignKeyProblem) {
+	problem := func(typ ForeignKeyProblemType, col *Column, detail string, args ...interface{}) {
+		problems = append(problems, ForeignKeyProblem{
+			SchemaName: s.Name,
+			Table:      t,
+			ForeignKey: fk,
+			Type:       typ,
+			Column:     col,
+			Detail:     fmt.Sprintf(detail, args...),
+		})
+	}
+
+	parentSchemaName := s.Name
+	if fk.ReferencedSchemaName != "" {
+		parentSchemaName = fk.ReferencedSchemaName
+		if _, ok := schemasByName[parentSchemaName]; !ok {
+			return nil
+		}
+	}
+	parent := fk.ReferencedTable(s, schemasByName)
+	if parent == nil {
+		problem(ForeignKeyMissingTable, nil, "table %s.%s does not exist", EscapeIdentifier(parentSchemaName), EscapeIdentifier(fk.ReferencedTableName))
+		return
+	}
+
+	parentCols := parent.ColumnsByName()
+	resolved := make([]*Column, len(fk.Columns))
+	for n, col := range fk.Columns {
+		parentCol, ok := parentCols[fk.ReferencedColumnNames[n]]
+		if !ok {
+			problem(ForeignKeyMissingColumn, col, "column %s does not exist in table %s", EscapeIdentifier(fk.ReferencedColumnNames[n]), EscapeIdentifier(parent.Name))
+			continue
+		}
+		resolved[n] = parentCol
+		childType, childUnsigned := fkComparableType(col.TypeInDB)
+		parentType, parentUnsigned := fkComparableType(parentCol.TypeInDB)
+		if childType != parentType {
+			problem(ForeignKeyTypeMismatch, col, "type %s is not compatible with parent column %s type %s", col.TypeInDB, EscapeIdentifier(parentCol.Name), parentCol.TypeInDB)
+		} else if childUnsigned != parentUnsigned {
+			problem(ForeignKeySignednessMismatch, col, "type %s does not match signedness of parent column %s type %s", col.TypeInDB, EscapeIdentifier(parentCol.Name), parentCol.TypeInDB)
+		} else if col.CharSet != parentCol.CharSet || col.Collation != parentCol.Collation {
+			problem(ForeignKeyCharSetMismatch, col, "character set %s collation %s does not match parent column %s character set %s collation %s", col.CharSet, col.Collation, EscapeIdentifier(parentCol.Name), parentCol.CharSet, parentCol.Collation)
+		}
+	}
+	for _, parentCol := range resolved {
+		if parentCol == nil {
+			return // no sense checking indexes if some parent columns are missing
+		}
+	}
+
+	if !parent.hasIndexWithLeadingColumns(resolved) {
+		colNames := make([]string, len(resolved))
+		for n, col := range resolved {
+			colNames[n] = EscapeIdentifier(col.Name)
+		}
+		problem(ForeignKeyMissingParentIndex, nil, "table %s has no index beginning with (%s)", EscapeIdentifier(parent.Name), strings.Join(colNames, ", "))
+	}
+	return
+}
+
+// hasIndexWithLeadingColumns returns true if the table has an index (primary
+// or secondary) whose first columns are the supplied columns, in order, with
+// no sub-part prefix lengths.
+func (t *Table) hasIndexWithLeadingColumns(cols []*Column) bool {
+	indexes := t.SecondaryIndexes
+	if t.PrimaryKey != nil {
+		indexes = append([]*Index{t.PrimaryKey}, indexes...)
+	}
+Outer:
+	for _, idx := range indexes {
+		if len(idx.Columns) < len(cols) {
+			continue
+		}
+		for n, col := range cols {
+			if idx.Columns[n].Name != col.Name || idx.SubParts[n] != 0 {
+				continue Outer
+			}
+		}
+		return true
+	}
+	return false
+}
+
+var reDisplayWidth = regexp.MustCompile(`^(tinyint|smallint|mediumint|int|bigint)\(\d+\)`)
+
+// fkComparableType normalizes a column type for purposes of foreign key
+// compatibility. Integer display widths and zerofill are ignored, as are the
+// lengths of string types, since MySQL does not require these to match.
+// Signedness is returned separately so that it can be reported distinctly.
+func fkComparableType(typ string) (string, bool) {
+	typ = strings.ToLower(typ)
+	unsigned := strings.Contains(typ, " unsigned")
+	typ = strings.Replace(typ, " unsigned", "", 1)
+	typ = strings.Replace(typ, " zerofill", "", 1)
+	typ = reDisplayWidth.ReplaceAllString(typ, "$1")
+	base := typ
+	if paren := strings.IndexByte(typ, '('); paren > -1 {
+		base = typ[0:paren]
+	}
+	switch base {
+	case "char", "varchar":
+		return "char", unsigned
+	case "binary", "varbinary":
+		return "binary", unsigned
+	}
+	return typ, unsigned
+}
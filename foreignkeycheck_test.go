@@ -0,0 +1,120 @@
+package tengo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateForeignKeys(t *testing.T) {
+	child := foreignKeyTable()
+	parent := productsTable()
+	s := aSchema("store", &child, &parent)
+	purchasing := aSchema("purchasing")
+
+	// With only the store schema, the cross-schema FK is not validated, and the
+	// product FK is fine
+	if problems := ValidateForeignKeys(&s); len(problems) != 0 {
+		t.Errorf("Expected no problems, instead found %v", problems)
+	}
+
+	// With the purchasing schema present but lacking the customers table, expect
+	// a missing-table problem
+	problems := s.ValidateForeignKeys(&purchasing)
+	if len(problems) != 1 || problems[0].Type != ForeignKeyMissingTable || problems[0].ForeignKey.Name != "customer_fk" {
+		t.Fatalf("Unexpected problems: %v", problems)
+	}
+	if !strings.Contains(problems[0].Error(), "`purchasing`.`customers`") {
+		t.Errorf("Unexpected error string: %s", problems[0].Error())
+	}
+
+	// Display width differences are fine, but signedness is not
+	parent.Columns[1].TypeInDB = "int(11) unsigned"
+	if problems := ValidateForeignKeys(&s); len(problems) != 0 {
+		t.Errorf("Expected no problems, instead found %v", problems)
+	}
+	parent.Columns[1].TypeInDB = "int(11)"
+	problems = ValidateForeignKeys(&s)
+	if len(problems) != 1 || problems[0].Type != ForeignKeySignednessMismatch || problems[0].Column != child.Columns[3] {
+		t.Errorf("Unexpected problems: %v", problems)
+	}
+	parent.Columns[1].TypeInDB = "bigint(20) unsigned"
+	problems = ValidateForeignKeys(&s)
+	if len(problems) != 1 || problems[0].Type != ForeignKeyTypeMismatch {
+		t.Errorf("Unexpected problems: %v", problems)
+	}
+	parent.Columns[1].TypeInDB = "int(10) unsigned"
+
+	// String lengths may differ, but charset and collation may not
+	parent.Columns[0].TypeInDB = "varchar(20)"
+	if problems := ValidateForeignKeys(&s); len(problems) != 0 {
+		t.Errorf("Expected no problems, instead found %v", problems)
+	}
+	parent.Columns[0].CharSet = "utf8mb4"
+	parent.Columns[0].Collation = "utf8mb4_general_ci"
+	problems = ValidateForeignKeys(&s)
+	if len(problems) != 1 || problems[0].Type != ForeignKeyCharSetMismatch {
+		t.Errorf("Unexpected problems: %v", problems)
+	}
+	parent.Columns[0].CharSet = "latin1"
+	parent.Columns[0].Collation = "latin1_swedish_ci"
+
+	// Parent index must begin with the referenced columns, in order
+	parent.PrimaryKey = primaryKey(parent.Columns[1], parent.Columns[0])
+	problems = ValidateForeignKeys(&s)
+	if len(problems) != 1 || problems[0].Type != ForeignKeyMissingParentIndex {
+		t.Errorf("Unexpected problems: %v", problems)
+	}
+	parent.SecondaryIndexes = []*Index{{
+		Name:     "line_model_name",
+		Columns:  []*Column{parent.Columns[0], parent.Columns[1], parent.Columns[2]},
+		SubParts: []uint16{0, 0, 0},
+	}}
+	if problems := ValidateForeignKeys(&s); len(problems) != 0 {
+		t.Errorf("Expected no problems, instead found %v", problems)
+	}
+
+	// Missing parent column
+	parent.Columns[1].Name = "model_number"
+	problems = ValidateForeignKeys(&s)
+	if len(problems) != 1 || problems[0].Type != ForeignKeyMissingColumn {
+		t.Errorf("Unexpected problems: %v", problems)
+	}
+}
+
+func productsTable() Table {
+	columns := []*Column{
+		{
+			Name:               "line",
+			TypeInDB:           "char(12)",
+			CharSet:            "latin1",
+			Collation:          "latin1_swedish_ci",
+			CollationIsDefault: true,
+			Default:            ColumnDefaultNull,
+		},
+		{
+			Name:     "model",
+			TypeInDB: "int(10) unsigned",
+			Default:  ColumnDefaultNull,
+		},
+		{
+			Name:               "name",
+			TypeInDB:           "varchar(100)",
+			CharSet:            "latin1",
+			Collation:          "latin1_swedish_ci",
+			CollationIsDefault: true,
+			Default:            ColumnDefaultNull,
+		},
+	}
+	table := Table{
+		Name:               "products",
+		Engine:             "InnoDB",
+		CharSet:            "latin1",
+		Collation:          "latin1_swedish_ci",
+		CollationIsDefault: true,
+		Columns:            columns,
+		PrimaryKey:         primaryKey(columns[0], columns[1]),
+		SecondaryIndexes:   []*Index{},
+	}
+	table.CreateStatement = table.GeneratedCreateStatement(FlavorUnknown)
+	return table
+}
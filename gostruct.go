@@ -0,0 +1,272 @@
+package tengo
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// GoStructOptions controls the output of Table.GoStruct and Schema.GoStructs.
+type GoStructOptions struct {
+	PackageName  string // If non-blank, output is a complete Go source file with package clause and imports
+	StructName   string // Name of generated struct; defaults to camel-cased table name. Ignored by Schema.GoStructs.
+	NullPointers bool   // If true, use pointer types for nullable columns instead of database/sql Null types
+	OmitJSONTags bool   // If true, only emit db struct tags
+}
+
+// goInitialisms lists the name segments which are fully capitalized when
+// converting column names to Go field names, as per golint conventions.
+var goInitialisms = map[string]bool{
+	"api": true, "ascii": true, "cpu": true, "css": true, "dns": true,
+	"guid": true, "html": true, "http": true, "https": true, "id": true,
+	"ip": true, "json": true, "sql": true, "ssh": true, "tcp": true,
+	"ttl": true, "ui": true, "uid": true, "uri": true, "url": true,
+	"utf8": true, "uuid": true, "xml": true,
+}
+
+// goExportedName converts a database identifier such as "customer_id" into an
+// exported Go identifier such as "CustomerID".
+func goExportedName(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var b strings.Builder
+	for _, word := range words {
+		if goInitialisms[strings.ToLower(word)] {
+			b.WriteString(strings.ToUpper(word))
+		} else {
+			runes := []rune(word)
+			b.WriteRune(unicode.ToUpper(runes[0]))
+			b.WriteString(string(runes[1:]))
+		}
+	}
+	result := b.String()
+	if result == "" || !unicode.IsLetter([]rune(result)[0]) {
+		result = "X" + result
+	}
+	return result
+}
+
+// GoType returns the Go type corresponding to the column's type, along with the
+// import path required to use that type (or a blank string if none needed).
+// Nullable columns are mapped to the corresponding database/sql Null type if
+// one exists, or a pointer type otherwise; if nullPointers is true, a pointer
+// type is always used for nullable columns. Binary and bit types are always
+// mapped to []byte, since a nil slice can already represent NULL, and the
+// driver returns bit values as raw bytes which cannot be scanned into bool or
+// integer types. Date, datetime, and timestamp columns are mapped to
+// time.Time, which requires the parseTime=true DSN parameter when scanning
+// values with the go-sql-driver/mysql driver.
+func (c *Column) GoType(nullPointers bool) (goType string, importPath string) {
+	typ := strings.ToLower(c.TypeInDB)
+	base := typ
+	if end := strings.IndexAny(typ, "( "); end > -1 {
+		base = typ[0:end]
+	}
+	unsigned := strings.Contains(typ, " unsigned")
+
+	var nullType string
+	switch base {
+	case "tinyint":
+		if strings.HasPrefix(typ, "tinyint(1)") {
+			goType, nullType = "bool", "sql.NullBool"
+		} else if unsigned {
+			goType, nullType = "uint8", "sql.NullInt64"
+		} else {
+			goType, nullType = "int8", "sql.NullInt64"
+		}
+	case "smallint", "year":
+		if unsigned {
+			goType, nullType = "uint16", "sql.NullInt64"
+		} else {
+			goType, nullType = "int16", "sql.NullInt64"
+		}
+	case "mediumint", "int", "integer":
+		if unsigned {
+			goType, nullType = "uint32", "sql.NullInt64"
+		} else {
+			goType, nullType = "int32", "sql.NullInt64"
+		}
+	case "bigint":
+		if unsigned {
+			goType = "uint64" // no sql.Null type can hold full range
+		} else {
+			goType, nullType = "int64", "sql.NullInt64"
+		}
+	case "float":
+		goType, nullType = "float32", "sql.NullFloat64"
+	case "double", "real":
+		goType, nullType = "float64", "sql.NullFloat64"
+	case "date", "datetime", "timestamp":
+		goType, importPath = "time.Time", "time"
+	case "bit", "binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob":
+		return "[]byte", ""
+	default: // char, varchar, text types, enum, set, decimal, time, json, etc
+		goType, nullType = "string", "sql.NullString"
+	}
+
+	if !c.Nullable {
+		return goType, importPath
+	}
+	if nullPointers || nullType == "" {
+		return "*" + goType, importPath
+	}
+	return nullType, "database/sql"
+}
+
+// GoStruct returns Go source code for a struct type which mirrors the table.
+// Each column becomes an exported field with db and json struct tags, and
+// column comments become field comments. The struct also receives TableName
+// and PrimaryKeyColumns methods. The output is gofmt'ed and deterministic.
+func (t *Table) GoStruct(opts GoStructOptions) (string, error) {
+	structName := opts.StructName
+	if structName == "" {
+		structName = goExportedName(t.Name)
+	}
+	var body bytes.Buffer
+	imports := t.writeGoStruct(&body, structName, opts)
+	return formatGoSource(body.Bytes(), opts.PackageName, imports)
+}
+
+// GoStructs returns Go source code for struct types mirroring every table in
+// the schema, sorted by table name. If multiple table names map to the same
+// struct name, such as foo_bar and FooBar, underscores are appended to the
+// later struct names to make them unique. See Table.GoStruct for more
+// information.
+func (s *Schema) GoStructs(opts GoStructOptions) (string, error) {
+	tables := make([]*Table, len(s.Tables))
+	copy(tables, s.Tables)
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].Name < tables[j].Name
+	})
+	var body bytes.Buffer
+	imports := make(map[string]bool)
+	structNames := make(map[string]bool, len(tables))
+	for n, t := range tables {
+		if n > 0 {
+			body.WriteString("\n")
+		}
+		structName := goExportedName(t.Name)
+		for structNames[structName] {
+			structName += "_"
+		}
+		structNames[structName] = true
+		for path := range t.writeGoStruct(&body, structName, opts) {
+			imports[path] = true
+		}
+	}
+	return formatGoSource(body.Bytes(), opts.PackageName, imports)
+}
+
+// writeGoStruct writes unformatted Go source for the table's struct type and
+// methods to buf. It returns the set of import paths needed by the struct.
+// Field names which would clash with other fields or with the generated
+// methods have underscores appended.
+func (t *Table) writeGoStruct(buf *bytes.Buffer, structName string, opts GoStructOptions) map[string]bool {
+	imports := make(map[string]bool)
+	pkCols := make(map[string]bool)
+	var pkNames []string
+	if t.PrimaryKey != nil {
+		for _, col := range t.PrimaryKey.Columns {
+			pkCols[col.Name] = true
+			pkNames = append(pkNames, fmt.Sprintf("%q", col.Name))
+		}
+	}
+
+	fmt.Fprintf(buf, "// %s mirrors table %s.\n", structName, EscapeIdentifier(t.Name))
+	if t.Comment != "" {
+		fmt.Fprintf(buf, "// %s\n", goCommentText(t.Comment))
+	}
+	fmt.Fprintf(buf, "type %s struct {\n", structName)
+	usedNames := map[string]bool{"TableName": true, "PrimaryKeyColumns": true}
+	for _, col := range t.Columns {
+		fieldName := goExportedName(col.Name)
+		for usedNames[fieldName] {
+			fieldName += "_"
+		}
+		usedNames[fieldName] = true
+		goType, importPath := col.GoType(opts.NullPointers)
+		if importPath != "" {
+			imports[importPath] = true
+		}
+		var comments []string
+		if col.Comment != "" {
+			comments = append(comments, goCommentText(col.Comment))
+		}
+		if pkCols[col.Name] {
+			comments = append(comments, "primary key")
+		}
+		if col.AutoIncrement {
+			comments = append(comments, "auto-increment")
+		}
+		fmt.Fprintf(buf, "\t%s %s %s", fieldName, goType, goStructTag(col.Name, opts))
+		if len(comments) > 0 {
+			fmt.Fprintf(buf, " // %s", strings.Join(comments, "; "))
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString("}\n\n")
+
+	fmt.Fprintf(buf, "// TableName returns the name of the table mirrored by %s.\n", structName)
+	fmt.Fprintf(buf, "func (%s) TableName() string {\n\treturn %q\n}\n\n", structName, t.Name)
+	fmt.Fprintf(buf, "// PrimaryKeyColumns returns the column names of the primary key of the table\n// mirrored by %s, or nil if the table has no primary key.\n", structName)
+	if len(pkNames) == 0 {
+		fmt.Fprintf(buf, "func (%s) PrimaryKeyColumns() []string {\n\treturn nil\n}\n", structName)
+	} else {
+		fmt.Fprintf(buf, "func (%s) PrimaryKeyColumns() []string {\n\treturn []string{%s}\n}\n", structName, strings.Join(pkNames, ", "))
+	}
+	return imports
+}
+
+// goStructTag returns a Go string literal containing the struct tag for the
+// supplied column name. Tag values are quoted, so that column names containing
+// quotes or backslashes are preserved; if the column name contains a backtick,
+// an interpreted string literal is used instead of a raw one.
+func goStructTag(colName string, opts GoStructOptions) string {
+	tag := "db:" + strconv.Quote(colName)
+	if !opts.OmitJSONTags {
+		tag += " json:" + strconv.Quote(colName)
+	}
+	if strings.Contains(tag, "`") {
+		return strconv.Quote(tag)
+	}
+	return "`" + tag + "`"
+}
+
+// goCommentText flattens a database comment onto a single line, so that it may
+// be safely used in a Go line comment.
+func goCommentText(comment string) string {
+	return strings.Join(strings.Fields(comment), " ")
+}
+
+// formatGoSource gofmt's the supplied declarations. If packageName is non-blank,
+// a package clause and import block are prepended first.
+func formatGoSource(decls []byte, packageName string, imports map[string]bool) (string, error) {
+	var src bytes.Buffer
+	if packageName != "" {
+		src.WriteString("// Code generated by tengo. DO NOT EDIT.\n\n")
+		fmt.Fprintf(&src, "package %s\n\n", packageName)
+		if len(imports) > 0 {
+			paths := make([]string, 0, len(imports))
+			for path := range imports {
+				paths = append(paths, path)
+			}
+			sort.Strings(paths)
+			src.WriteString("import (\n")
+			for _, path := range paths {
+				fmt.Fprintf(&src, "\t%q\n", path)
+			}
+			src.WriteString(")\n\n")
+		}
+	}
+	src.Write(decls)
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("Unable to format generated Go source: %s", err)
+	}
+	return string(formatted), nil
+}
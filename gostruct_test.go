@@ -0,0 +1,231 @@
+package tengo
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// typeCheckGoSource confirms that src, which must include a package clause,
+// is valid Go which compiles without errors.
+func typeCheckGoSource(t *testing.T, src string) {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "generated.go", src, 0)
+	if err != nil {
+		t.Fatalf("Unable to parse generated source: %s\n%s", err, src)
+	}
+	conf := types.Config{Importer: importer.For("source", nil)}
+	if _, err := conf.Check("models", fset, []*ast.File{f}, nil); err != nil {
+		t.Errorf("Generated source does not type-check: %s\n%s", err, src)
+	}
+}
+
+func TestGoExportedName(t *testing.T) {
+	cases := map[string]string{
+		"actor_id":      "ActorID",
+		"first_name":    "FirstName",
+		"url":           "URL",
+		"api_key":       "APIKey",
+		"2fa_enabled":   "X2faEnabled",
+		"camelCase":     "CamelCase",
+		"weird-name!x":  "WeirdNameX",
+		"":              "X",
+		"uuid_and_json": "UUIDAndJSON",
+	}
+	for input, expected := range cases {
+		if actual := goExportedName(input); actual != expected {
+			t.Errorf("Expected goExportedName(%q) to return %q, instead found %q", input, expected, actual)
+		}
+	}
+}
+
+func TestColumnGoType(t *testing.T) {
+	cases := []struct {
+		typeInDB     string
+		nullable     bool
+		nullPointers bool
+		expected     string
+		importPath   string
+	}{
+		{"int(10) unsigned", false, false, "uint32", ""},
+		{"int(11)", true, false, "sql.NullInt64", "database/sql"},
+		{"int(11)", true, true, "*int32", ""},
+		{"bigint(20) unsigned", true, false, "*uint64", ""},
+		{"tinyint(1)", false, false, "bool", ""},
+		{"tinyint(4)", true, false, "sql.NullInt64", "database/sql"},
+		{"varchar(45)", true, false, "sql.NullString", "database/sql"},
+		{"decimal(10,2)", false, false, "string", ""},
+		{"double", false, false, "float64", ""},
+		{"float(10,2)", true, false, "sql.NullFloat64", "database/sql"},
+		{"timestamp(2)", false, false, "time.Time", "time"},
+		{"datetime", true, false, "*time.Time", "time"},
+		{"blob", true, false, "[]byte", ""},
+		{"bit(1)", false, false, "[]byte", ""},
+		{"bit(8)", true, false, "[]byte", ""},
+		{"enum('a','b')", false, false, "string", ""},
+	}
+	for _, c := range cases {
+		col := &Column{TypeInDB: c.typeInDB, Nullable: c.nullable}
+		if goType, importPath := col.GoType(c.nullPointers); goType != c.expected || importPath != c.importPath {
+			t.Errorf("Expected %s (nullable=%t, nullPointers=%t) to map to %s,%q; instead found %s,%q", c.typeInDB, c.nullable, c.nullPointers, c.expected, c.importPath, goType, importPath)
+		}
+	}
+}
+
+func TestTableGoStruct(t *testing.T) {
+	table := aTable(1)
+	table.Comment = "Actors\nwho act"
+	table.Columns[4].Comment = "social security number"
+	expected := strings.Replace(`// Code generated by tengo. DO NOT EDIT.
+
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Actor mirrors table ~actor~.
+// Actors who act
+type Actor struct {
+	ActorID    uint16         ~db:"actor_id" json:"actor_id"~ // primary key; auto-increment
+	FirstName  string         ~db:"first_name" json:"first_name"~
+	LastName   sql.NullString ~db:"last_name" json:"last_name"~
+	LastUpdate time.Time      ~db:"last_update" json:"last_update"~
+	Ssn        string         ~db:"ssn" json:"ssn"~ // social security number
+	Alive      bool           ~db:"alive" json:"alive"~
+	AliveBit   []byte         ~db:"alive_bit" json:"alive_bit"~
+}
+
+// TableName returns the name of the table mirrored by Actor.
+func (Actor) TableName() string {
+	return "actor"
+}
+
+// PrimaryKeyColumns returns the column names of the primary key of the table
+// mirrored by Actor, or nil if the table has no primary key.
+func (Actor) PrimaryKeyColumns() []string {
+	return []string{"actor_id"}
+}
+`, "~", "`", -1)
+	actual, err := table.GoStruct(GoStructOptions{PackageName: "models"})
+	if err != nil {
+		t.Fatalf("Unexpected error from GoStruct: %s", err)
+	}
+	if actual != expected {
+		t.Errorf("Unexpected output from GoStruct.\nExpected:\n%s\nFound:\n%s", expected, actual)
+	}
+	typeCheckGoSource(t, actual)
+
+	// Without a package name, only the declarations are returned
+	actual, err = table.GoStruct(GoStructOptions{StructName: "Thespian", NullPointers: true, OmitJSONTags: true})
+	if err != nil {
+		t.Fatalf("Unexpected error from GoStruct: %s", err)
+	}
+	if strings.Contains(actual, "package") || strings.Contains(actual, "import") || strings.Contains(actual, "json:") {
+		t.Errorf("Unexpected output from GoStruct:\n%s", actual)
+	}
+	if !strings.Contains(actual, "type Thespian struct") || !strings.Contains(actual, "LastName   *string   `db:\"last_name\"`") {
+		t.Errorf("Unexpected output from GoStruct:\n%s", actual)
+	}
+}
+
+func TestSchemaGoStructs(t *testing.T) {
+	t1, t2, t3 := aTable(1), anotherTable(), unsupportedTable()
+	t3.PrimaryKey = nil
+	s := aSchema("s1", &t3, &t1, &t2)
+	actual, err := s.GoStructs(GoStructOptions{PackageName: "models"})
+	if err != nil {
+		t.Fatalf("Unexpected error from GoStructs: %s", err)
+	}
+	pos1 := strings.Index(actual, "type Actor struct")
+	pos2 := strings.Index(actual, "type ActorInFilm struct")
+	pos3 := strings.Index(actual, "type Orders struct")
+	if pos1 < 0 || pos2 < pos1 || pos3 < pos2 {
+		t.Errorf("Expected structs to be present and sorted by table name; instead found positions %d, %d, %d", pos1, pos2, pos3)
+	}
+	if !strings.Contains(actual, "func (Orders) PrimaryKeyColumns() []string {\n\treturn nil\n}") {
+		t.Errorf("Expected table without primary key to return nil from PrimaryKeyColumns, but output was:\n%s", actual)
+	}
+	if again, _ := s.GoStructs(GoStructOptions{PackageName: "models"}); again != actual {
+		t.Error("Expected GoStructs output to be deterministic, but it was not")
+	}
+	typeCheckGoSource(t, actual)
+}
+
+func TestGoStructNameClashes(t *testing.T) {
+	// Columns named like the generated methods, or like each other, must be
+	// renamed to avoid compile errors
+	table := aTable(1)
+	table.Columns[1].Name = "table_name"
+	table.Columns[2].Name = "primary_key_columns"
+	table.Columns[3].Name = "TableName"
+	actual, err := table.GoStruct(GoStructOptions{PackageName: "models"})
+	if err != nil {
+		t.Fatalf("Unexpected error from GoStruct: %s", err)
+	}
+	for _, field := range []string{"TableName_ ", "PrimaryKeyColumns_ ", "TableName__ "} {
+		if !strings.Contains(actual, "\t"+field) {
+			t.Errorf("Expected field %q in output, but it was not found:\n%s", field, actual)
+		}
+	}
+	typeCheckGoSource(t, actual)
+
+	// Tables whose names map to the same struct name must not clash either
+	t1, t2 := aTable(1), aTable(1)
+	t1.Name, t2.Name = "foo_bar", "FooBar"
+	s := aSchema("s1", &t1, &t2)
+	actual, err = s.GoStructs(GoStructOptions{PackageName: "models"})
+	if err != nil {
+		t.Fatalf("Unexpected error from GoStructs: %s", err)
+	}
+	if !strings.Contains(actual, "type FooBar struct") || !strings.Contains(actual, "type FooBar_ struct") {
+		t.Errorf("Expected clashing struct names to be made unique, but output was:\n%s", actual)
+	}
+	typeCheckGoSource(t, actual)
+}
+
+func TestGoStructTagQuoting(t *testing.T) {
+	table := aTable(1)
+	names := []string{`say "hi"`, "back`tick", `back\slash`}
+	for n, name := range names {
+		table.Columns[n+1].Name = name
+	}
+	actual, err := table.GoStruct(GoStructOptions{PackageName: "models"})
+	if err != nil {
+		t.Fatalf("Unexpected error from GoStruct: %s", err)
+	}
+	typeCheckGoSource(t, actual)
+
+	// Confirm the tags decode back to the original column names
+	f, err := parser.ParseFile(token.NewFileSet(), "generated.go", actual, 0)
+	if err != nil {
+		t.Fatalf("Unable to parse generated source: %s", err)
+	}
+	var tags []reflect.StructTag
+	ast.Inspect(f, func(node ast.Node) bool {
+		if field, ok := node.(*ast.Field); ok && field.Tag != nil {
+			tag, err := strconv.Unquote(field.Tag.Value)
+			if err != nil {
+				t.Errorf("Unable to unquote tag %s: %s", field.Tag.Value, err)
+			}
+			tags = append(tags, reflect.StructTag(tag))
+		}
+		return true
+	})
+	if len(tags) != len(table.Columns) {
+		t.Fatalf("Expected %d struct tags, found %d", len(table.Columns), len(tags))
+	}
+	for n, name := range names {
+		if db, json := tags[n+1].Get("db"), tags[n+1].Get("json"); db != name || json != name {
+			t.Errorf("Expected tags for column %q to round-trip, instead found db=%q json=%q", name, db, json)
+		}
+	}
+}
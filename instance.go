@@ -2,13 +2,20 @@ package tengo
 
 import (
 	"context"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
 	"database/sql"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/VividCortex/mysqlerr"
@@ -28,9 +35,10 @@ type Instance struct {
 	SocketPath     string
 	defaultParams  map[string]string
 	connectionPool map[string]*sqlx.DB // key is in format "schema?params"
-	*sync.RWMutex                      // protects connectionPool for concurrent operations
+	*sync.RWMutex                      // protects connectionPool and schemaCache for concurrent operations
 	flavor         Flavor
 	version        [3]int
+	schemaCache    *schemaCache // nil unless EnableSchemaCache has been called
 }
 
 // NewInstance returns a pointer to a new Instance corresponding to the
@@ -40,6 +48,49 @@ type Instance struct {
 // applied as default params to all connections (in addition to whatever is
 // supplied in Connect).
 func NewInstance(driver, dsn string) (*Instance, error) {
+	return NewInstanceWithOptions(driver, dsn, InstanceOptions{})
+}
+
+// InstanceOptions configures TLS and authentication behavior of an Instance
+// beyond what can be expressed in a DSN string. The zero value is equivalent
+// to using NewInstance.
+type InstanceOptions struct {
+	// TLSConfig, if non-nil, is used for all connections. Client certificates
+	// may be supplied in its Certificates field, or via ClientCertFile and
+	// ClientKeyFile.
+	TLSConfig *tls.Config
+
+	// CACertFile, ClientCertFile, and ClientKeyFile are paths to PEM files. If
+	// any are set, TLS is used for all connections, and the certificates are
+	// added to a copy of TLSConfig (or to a new config if TLSConfig is nil).
+	// ClientCertFile and ClientKeyFile must be supplied together.
+	CACertFile     string
+	ClientCertFile string
+	ClientKeyFile  string
+
+	// ServerPubKey is the server's RSA public key, used to encrypt the password
+	// when authenticating without TLS via the caching_sha2_password or
+	// sha256_password plugins. Alternatively ServerPubKeyFile may be the path to
+	// a PEM file containing the key.
+	ServerPubKey     *rsa.PublicKey
+	ServerPubKeyFile string
+
+	// AllowCleartextPasswords permits use of the mysql_clear_password plugin,
+	// which is required by some external authentication schemes such as IAM
+	// authentication on cloud platforms. This should only be used with TLS.
+	AllowCleartextPasswords bool
+}
+
+// driverRegistrationCounter is used to generate unique names for TLS configs
+// and public keys registered with the mysql driver.
+var driverRegistrationCounter uint64
+
+// NewInstanceWithOptions is like NewInstance, but also applies the supplied
+// TLS and authentication options. Any TLS config or public key is registered
+// with the mysql driver under a unique name, which is then included in the
+// default params of the instance, so that all pooled connections use it. An
+// error is returned if dsn already contains a conflicting param.
+func NewInstanceWithOptions(driver, dsn string, opts InstanceOptions) (*Instance, error) {
 	if driver != "mysql" {
 		return nil, fmt.Errorf("Unsupported driver \"%s\"", driver)
 	}
@@ -50,6 +101,9 @@ func NewInstance(driver, dsn string) (*Instance, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := opts.register(params); err != nil {
+		return nil, err
+	}
 
 	instance := &Instance{
 		BaseDSN:        base,
@@ -78,6 +132,108 @@ func NewInstance(driver, dsn string) (*Instance, error) {
 	return instance, nil
 }
 
+// register registers any TLS config or server public key in opts with the
+// mysql driver, and sets the corresponding driver params in params.
+func (opts InstanceOptions) register(params map[string]string) error {
+	setParam := func(name, value string) error {
+		if _, already := params[name]; already {
+			return fmt.Errorf("DSN param %s conflicts with InstanceOptions", name)
+		}
+		params[name] = value
+		return nil
+	}
+
+	tlsConfig, err := opts.tlsConfig()
+	if err != nil {
+		return err
+	} else if tlsConfig != nil {
+		name := fmt.Sprintf("tengo%d", atomic.AddUint64(&driverRegistrationCounter, 1))
+		if err := mysql.RegisterTLSConfig(name, tlsConfig); err != nil {
+			return err
+		}
+		if err := setParam("tls", name); err != nil {
+			return err
+		}
+	}
+
+	pubKey := opts.ServerPubKey
+	if pubKey == nil && opts.ServerPubKeyFile != "" {
+		if pubKey, err = readPublicKeyFile(opts.ServerPubKeyFile); err != nil {
+			return err
+		}
+	}
+	if pubKey != nil {
+		name := fmt.Sprintf("tengo%d", atomic.AddUint64(&driverRegistrationCounter, 1))
+		mysql.RegisterServerPubKey(name, pubKey)
+		if err := setParam("serverPubKey", name); err != nil {
+			return err
+		}
+	}
+
+	if opts.AllowCleartextPasswords {
+		return setParam("allowCleartextPasswords", "true")
+	}
+	return nil
+}
+
+// tlsConfig returns the TLS config to use for opts, or nil if TLS options were
+// not supplied.
+func (opts InstanceOptions) tlsConfig() (*tls.Config, error) {
+	if opts.CACertFile == "" && opts.ClientCertFile == "" && opts.ClientKeyFile == "" {
+		return opts.TLSConfig, nil
+	}
+	var config *tls.Config
+	if opts.TLSConfig != nil {
+		config = opts.TLSConfig.Clone()
+	} else {
+		config = &tls.Config{}
+	}
+	if opts.CACertFile != "" {
+		pem, err := ioutil.ReadFile(opts.CACertFile)
+		if err != nil {
+			return nil, err
+		}
+		if config.RootCAs == nil {
+			config.RootCAs = x509.NewCertPool()
+		}
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("No valid certificates found in %s", opts.CACertFile)
+		}
+	}
+	if opts.ClientCertFile != "" || opts.ClientKeyFile != "" {
+		if opts.ClientCertFile == "" || opts.ClientKeyFile == "" {
+			return nil, errors.New("ClientCertFile and ClientKeyFile must be supplied together")
+		}
+		cert, err := tls.LoadX509KeyPair(opts.ClientCertFile, opts.ClientKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = append(config.Certificates, cert)
+	}
+	return config, nil
+}
+
+// readPublicKeyFile returns the RSA public key in the PEM file at path.
+func readPublicKeyFile(path string) (*rsa.PublicKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("No PEM data found in %s", path)
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse public key in %s: %s", path, err)
+	}
+	rsaPub, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("Public key in %s is not an RSA key", path)
+	}
+	return rsaPub, nil
+}
+
 // String for an instance returns a "host:port" string (or "localhost:/path/to/socket"
 // if using UNIX domain socket)
 func (instance *Instance) String() string {
@@ -122,6 +278,14 @@ func (instance *Instance) buildParamString(params string) string {
 // automatically have a max conn lifetime of at most 30sec, or less if a lower
 // session-level wait_timeout was set in params or instance.defaultParams.
 func (instance *Instance) Connect(defaultSchema string, params string) (*sqlx.DB, error) {
+	return instance.ConnectContext(context.Background(), defaultSchema, params)
+}
+
+// ConnectContext is like Connect, but the supplied context is used for the
+// initial connection attempt and any queries needed to configure a new
+// connection pool. The context has no effect on the returned pool after
+// ConnectContext returns.
+func (instance *Instance) ConnectContext(ctx context.Context, defaultSchema string, params string) (*sqlx.DB, error) {
 	fullParams := instance.buildParamString(params)
 	key := fmt.Sprintf("%s?%s", defaultSchema, fullParams)
 
@@ -134,7 +298,7 @@ func (instance *Instance) Connect(defaultSchema string, params string) (*sqlx.DB
 	}
 
 	fullDSN := instance.BaseDSN + key
-	db, err := sqlx.Connect(instance.Driver, fullDSN)
+	db, err := sqlx.ConnectContext(ctx, instance.Driver, fullDSN)
 	if err != nil {
 		return nil, err
 	}
@@ -148,7 +312,7 @@ func (instance *Instance) Connect(defaultSchema string, params string) (*sqlx.DB
 	waitTimeout, _ := strconv.Atoi(parsedParams.Get("wait_timeout"))
 	if waitTimeout == 0 {
 		// Ignoring errors here, since this will keep maxLifetime at 30s sane default
-		db.QueryRow("SELECT @@wait_timeout").Scan(&waitTimeout)
+		db.QueryRowContext(ctx, "SELECT @@wait_timeout").Scan(&waitTimeout)
 	}
 	if waitTimeout > 1 && waitTimeout <= 30 {
 		maxLifetime = time.Duration(waitTimeout-1) * time.Second
@@ -163,8 +327,16 @@ func (instance *Instance) Connect(defaultSchema string, params string) (*sqlx.DB
 	return instance.connectionPool[key], nil
 }
 
-// CanConnect verifies that the Instance can be connected to
+// CanConnect verifies that the Instance can be connected to. If the attempt
+// fails during TLS or authentication plugin negotiation, the returned error
+// will be a *NegotiationError.
 func (instance *Instance) CanConnect() (bool, error) {
+	return instance.CanConnectContext(context.Background())
+}
+
+// CanConnectContext is like CanConnect, but the supplied context is used for
+// the connection attempt.
+func (instance *Instance) CanConnectContext(ctx context.Context) (bool, error) {
 	var err error
 	instance.Lock()
 
@@ -177,7 +349,7 @@ func (instance *Instance) CanConnect() (bool, error) {
 	if db, ok := instance.connectionPool[key]; ok {
 		db.SetMaxIdleConns(0)
 		var conn *sql.Conn
-		conn, err = db.Conn(context.Background())
+		conn, err = db.Conn(ctx)
 		if conn != nil {
 			conn.Close()
 		}
@@ -185,9 +357,14 @@ func (instance *Instance) CanConnect() (bool, error) {
 		instance.Unlock()
 	} else {
 		instance.Unlock()
-		_, err = instance.Connect("", "")
+		_, err = instance.ConnectContext(ctx, "", "")
 	}
 
+	if IsTLSError(err) {
+		err = &NegotiationError{TLS: true, Err: err}
+	} else if IsAuthNegotiationError(err) {
+		err = &NegotiationError{TLS: false, Err: err}
+	}
 	return err == nil, err
 }
 
@@ -207,8 +384,14 @@ func (instance *Instance) CloseAll() {
 // distribution/fork/vendor as well as major and minor version. If this is
 // unable to be determined or an error occurs, FlavorUnknown will be returned.
 func (instance *Instance) Flavor() Flavor {
+	return instance.flavorContext(context.Background())
+}
+
+// flavorContext is like Flavor, but the supplied context is used for querying
+// the flavor if it has not already been hydrated.
+func (instance *Instance) flavorContext(ctx context.Context) Flavor {
 	if instance.flavor == FlavorUnknown {
-		instance.hydrateFlavorAndVersion()
+		instance.hydrateFlavorAndVersion(ctx)
 	}
 	return instance.flavor
 }
@@ -237,18 +420,18 @@ func (instance *Instance) ForceFlavor(flavor Flavor) {
 // will be returned.
 func (instance *Instance) Version() (int, int, int) {
 	if instance.version[0] == 0 {
-		instance.hydrateFlavorAndVersion()
+		instance.hydrateFlavorAndVersion(context.Background())
 	}
 	return instance.version[0], instance.version[1], instance.version[2]
 }
 
-func (instance *Instance) hydrateFlavorAndVersion() {
-	db, err := instance.Connect("", "")
+func (instance *Instance) hydrateFlavorAndVersion(ctx context.Context) {
+	db, err := instance.ConnectContext(ctx, "", "")
 	if err != nil {
 		return
 	}
 	var vendorString, versionString string
-	if err = db.QueryRow("SELECT @@global.version_comment, @@global.version").Scan(&vendorString, &versionString); err != nil {
+	if err = db.QueryRowContext(ctx, "SELECT @@global.version_comment, @@global.version").Scan(&vendorString, &versionString); err != nil {
 		return
 	}
 	instance.version = ParseVersion(versionString)
@@ -264,7 +447,13 @@ func (instance *Instance) hydrateFlavorAndVersion() {
 // SchemaNames returns a slice of all schema name strings on the instance
 // visible to the user. System schemas are excluded.
 func (instance *Instance) SchemaNames() ([]string, error) {
-	db, err := instance.Connect("information_schema", "")
+	return instance.SchemaNamesContext(context.Background())
+}
+
+// SchemaNamesContext is like SchemaNames, but the supplied context is used for
+// all queries.
+func (instance *Instance) SchemaNamesContext(ctx context.Context) ([]string, error) {
+	db, err := instance.ConnectContext(ctx, "information_schema", "")
 	if err != nil {
 		return nil, err
 	}
@@ -273,7 +462,7 @@ func (instance *Instance) SchemaNames() ([]string, error) {
 		SELECT schema_name
 		FROM   schemata
 		WHERE  schema_name NOT IN ('information_schema', 'performance_schema', 'mysql', 'test', 'sys')`
-	if err := db.Select(&result, query); err != nil {
+	if err := db.SelectContext(ctx, &result, query); err != nil {
 		return nil, err
 	}
 	return result, nil
@@ -284,7 +473,22 @@ func (instance *Instance) SchemaNames() ([]string, error) {
 // more schema names as args to filter the result to just those schemas.
 // Note that the ordering of the resulting slice is not guaranteed.
 func (instance *Instance) Schemas(onlyNames ...string) ([]*Schema, error) {
-	db, err := instance.Connect("information_schema", "")
+	return instance.SchemasContext(context.Background(), onlyNames...)
+}
+
+// SchemasContext is like Schemas, but the supplied context is used for all
+// introspection queries. If the context is canceled or its deadline expires,
+// introspection is aborted and the context's error is returned.
+func (instance *Instance) SchemasContext(ctx context.Context, onlyNames ...string) ([]*Schema, error) {
+	return instance.SchemasWithOptions(ctx, IntrospectionOptions{}, onlyNames...)
+}
+
+// SchemasWithOptions is like SchemasContext, but permits control over
+// concurrency and progress reporting of introspection. Information_schema
+// queries are batched across multiple schemas, and then SHOW CREATE queries for
+// all tables and routines are run using a bounded pool of worker goroutines.
+func (instance *Instance) SchemasWithOptions(ctx context.Context, opts IntrospectionOptions, onlyNames ...string) ([]*Schema, error) {
+	db, err := instance.ConnectContext(ctx, "information_schema", "")
 	if err != nil {
 		return nil, err
 	}
@@ -314,7 +518,7 @@ func (instance *Instance) Schemas(onlyNames ...string) ([]*Schema, error) {
 			WHERE  schema_name IN (?)`
 		query, args, err = sqlx.In(query, onlyNames)
 	}
-	if err := db.Select(&rawSchemas, query, args...); err != nil {
+	if err := db.SelectContext(ctx, &rawSchemas, query, args...); err != nil {
 		return nil, err
 	}
 
@@ -325,12 +529,17 @@ func (instance *Instance) Schemas(onlyNames ...string) ([]*Schema, error) {
 			CharSet:   rawSchema.CharSet,
 			Collation: rawSchema.Collation,
 		}
-		if schemas[n].Tables, err = instance.querySchemaTables(rawSchema.Name); err != nil {
-			return nil, err
-		}
-		if schemas[n].Routines, err = instance.querySchemaRoutines(rawSchema.Name); err != nil {
-			return nil, err
-		}
+	}
+	instance.RLock()
+	cache := instance.schemaCache
+	instance.RUnlock()
+	if cache != nil {
+		err = instance.introspectSchemasCached(ctx, cache, schemas, opts)
+	} else if err = instance.querySchemaObjects(ctx, schemas); err == nil {
+		err = instance.hydrateCreateStatements(ctx, schemas, opts)
+	}
+	if err != nil {
+		return nil, err
 	}
 	return schemas, nil
 }
@@ -339,7 +548,13 @@ func (instance *Instance) Schemas(onlyNames ...string) ([]*Schema, error) {
 // called with no args, all non-system schemas will be returned. Or pass one or
 // more schema names as args to filter the result to just those schemas.
 func (instance *Instance) SchemasByName(onlyNames ...string) (map[string]*Schema, error) {
-	schemas, err := instance.Schemas(onlyNames...)
+	return instance.SchemasByNameContext(context.Background(), onlyNames...)
+}
+
+// SchemasByNameContext is like SchemasByName, but the supplied context is used
+// for all introspection queries.
+func (instance *Instance) SchemasByNameContext(ctx context.Context, onlyNames ...string) (map[string]*Schema, error) {
+	schemas, err := instance.SchemasContext(ctx, onlyNames...)
 	if err != nil {
 		return nil, err
 	}
@@ -353,7 +568,13 @@ func (instance *Instance) SchemasByName(onlyNames ...string) (map[string]*Schema
 // Schema returns a single schema by name. If the schema does not exist, nil
 // will be returned along with a sql.ErrNoRows error.
 func (instance *Instance) Schema(name string) (*Schema, error) {
-	schemas, err := instance.Schemas(name)
+	return instance.SchemaContext(context.Background(), name)
+}
+
+// SchemaContext is like Schema, but the supplied context is used for all
+// introspection queries.
+func (instance *Instance) SchemaContext(ctx context.Context, name string) (*Schema, error) {
+	schemas, err := instance.SchemasContext(ctx, name)
 	if err != nil {
 		return nil, err
 	} else if len(schemas) == 0 {
@@ -367,7 +588,13 @@ func (instance *Instance) Schema(name string) (*Schema, error) {
 // returned if a connection or query failed entirely and we weren't able to
 // determine whether the schema exists.
 func (instance *Instance) HasSchema(name string) (bool, error) {
-	db, err := instance.Connect("information_schema", "")
+	return instance.HasSchemaContext(context.Background(), name)
+}
+
+// HasSchemaContext is like HasSchema, but the supplied context is used for the
+// query.
+func (instance *Instance) HasSchemaContext(ctx context.Context, name string) (bool, error) {
+	db, err := instance.ConnectContext(ctx, "information_schema", "")
 	if err != nil {
 		return false, err
 	}
@@ -376,7 +603,7 @@ func (instance *Instance) HasSchema(name string) (bool, error) {
 		SELECT 1
 		FROM   schemata
 		WHERE  schema_name = ?`
-	err = db.Get(&exists, query, name)
+	err = db.GetContext(ctx, &exists, query, name)
 	if err == nil {
 		return true, nil
 	} else if err == sql.ErrNoRows {
@@ -389,20 +616,33 @@ func (instance *Instance) HasSchema(name string) (bool, error) {
 // ShowCreateTable returns a string with a CREATE TABLE statement, representing
 // how the instance views the specified table as having been created.
 func (instance *Instance) ShowCreateTable(schema, table string) (string, error) {
-	db, err := instance.Connect(schema, "")
+	return instance.ShowCreateTableContext(context.Background(), schema, table)
+}
+
+// ShowCreateTableContext is like ShowCreateTable, but the supplied context is
+// used for the query.
+func (instance *Instance) ShowCreateTableContext(ctx context.Context, schema, table string) (string, error) {
+	db, err := instance.ConnectContext(ctx, schema, "")
 	if err != nil {
 		return "", err
 	}
-	return showCreateTable(db, table)
+	return showCreateTable(ctx, db, "", table)
 }
 
-func showCreateTable(db *sqlx.DB, table string) (string, error) {
+// showCreateTable returns the SHOW CREATE TABLE output for the supplied table.
+// If schema is blank, the table name is not qualified, so db's default schema
+// is used.
+func showCreateTable(ctx context.Context, db *sqlx.DB, schema, table string) (string, error) {
 	var createRows []struct {
 		TableName       string `db:"Table"`
 		CreateStatement string `db:"Create Table"`
 	}
-	query := fmt.Sprintf("SHOW CREATE TABLE %s", EscapeIdentifier(table))
-	if err := db.Select(&createRows, query); err != nil {
+	name := EscapeIdentifier(table)
+	if schema != "" {
+		name = fmt.Sprintf("%s.%s", EscapeIdentifier(schema), name)
+	}
+	query := fmt.Sprintf("SHOW CREATE TABLE %s", name)
+	if err := db.SelectContext(ctx, &createRows, query); err != nil {
 		return "", err
 	}
 	if len(createRows) != 1 {
@@ -417,12 +657,18 @@ func showCreateTable(db *sqlx.DB, table string) (string, error) {
 // Please note that use of innodb_stats_persistent may negatively impact the
 // accuracy. For example, see https://bugs.mysql.com/bug.php?id=75428.
 func (instance *Instance) TableSize(schema, table string) (int64, error) {
+	return instance.TableSizeContext(context.Background(), schema, table)
+}
+
+// TableSizeContext is like TableSize, but the supplied context is used for the
+// query.
+func (instance *Instance) TableSizeContext(ctx context.Context, schema, table string) (int64, error) {
 	var result int64
-	db, err := instance.Connect("information_schema", "")
+	db, err := instance.ConnectContext(ctx, "information_schema", "")
 	if err != nil {
 		return 0, err
 	}
-	err = db.Get(&result, `
+	err = db.GetContext(ctx, &result, `
 		SELECT  data_length + index_length + data_free
 		FROM    tables
 		WHERE   table_schema = ? and table_name = ?`,
@@ -434,17 +680,23 @@ func (instance *Instance) TableSize(schema, table string) (int64, error) {
 // occurs in querying, also returns true (along with the error) since a false
 // positive is generally less dangerous in this case than a false negative.
 func (instance *Instance) TableHasRows(schema, table string) (bool, error) {
-	db, err := instance.Connect(schema, "")
+	return instance.TableHasRowsContext(context.Background(), schema, table)
+}
+
+// TableHasRowsContext is like TableHasRows, but the supplied context is used
+// for the query.
+func (instance *Instance) TableHasRowsContext(ctx context.Context, schema, table string) (bool, error) {
+	db, err := instance.ConnectContext(ctx, schema, "")
 	if err != nil {
 		return true, err
 	}
-	return tableHasRows(db, table)
+	return tableHasRows(ctx, db, table)
 }
 
-func tableHasRows(db *sqlx.DB, table string) (bool, error) {
+func tableHasRows(ctx context.Context, db *sqlx.DB, table string) (bool, error) {
 	var result []int
 	query := fmt.Sprintf("SELECT 1 FROM %s LIMIT 1", EscapeIdentifier(table))
-	if err := db.Select(&result, query); err != nil {
+	if err := db.SelectContext(ctx, &result, query); err != nil {
 		return true, err
 	}
 	return len(result) != 0, nil
@@ -454,7 +706,13 @@ func tableHasRows(db *sqlx.DB, table string) (bool, error) {
 // optionally the supplied default charSet and collation. (Leave charSet and
 // collation blank to use server defaults.)
 func (instance *Instance) CreateSchema(name, charSet, collation string) (*Schema, error) {
-	db, err := instance.Connect("", "")
+	return instance.CreateSchemaContext(context.Background(), name, charSet, collation)
+}
+
+// CreateSchemaContext is like CreateSchema, but the supplied context is used
+// for all queries.
+func (instance *Instance) CreateSchemaContext(ctx context.Context, name, charSet, collation string) (*Schema, error) {
+	db, err := instance.ConnectContext(ctx, "", "")
 	if err != nil {
 		return nil, err
 	}
@@ -462,7 +720,7 @@ func (instance *Instance) CreateSchema(name, charSet, collation string) (*Schema
 	// blank, but we need the returned Schema value to reflect the correct values,
 	// and we can avoid re-querying this way
 	if charSet == "" || collation == "" {
-		defCharSet, defCollation, err := instance.DefaultCharSetAndCollation()
+		defCharSet, defCollation, err := instance.DefaultCharSetAndCollationContext(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -479,10 +737,11 @@ func (instance *Instance) CreateSchema(name, charSet, collation string) (*Schema
 		Collation: collation,
 		Tables:    []*Table{},
 	}
-	_, err = db.Exec(schema.CreateStatement())
+	_, err = db.ExecContext(ctx, schema.CreateStatement())
 	if err != nil {
 		return nil, err
 	}
+	instance.InvalidateSchemaCache(name)
 	return schema, nil
 }
 
@@ -490,7 +749,13 @@ func (instance *Instance) CreateSchema(name, charSet, collation string) (*Schema
 // schema itself. If onlyIfEmpty==true, returns an error if any of the tables
 // have any rows.
 func (instance *Instance) DropSchema(schema string, onlyIfEmpty bool) error {
-	err := instance.DropTablesInSchema(schema, onlyIfEmpty)
+	return instance.DropSchemaContext(context.Background(), schema, onlyIfEmpty)
+}
+
+// DropSchemaContext is like DropSchema, but the supplied context is used for
+// all queries, including the concurrent table drops.
+func (instance *Instance) DropSchemaContext(ctx context.Context, schema string, onlyIfEmpty bool) error {
+	err := instance.DropTablesInSchemaContext(ctx, schema, onlyIfEmpty)
 	if err != nil {
 		return err
 	}
@@ -501,14 +766,15 @@ func (instance *Instance) DropSchema(schema string, onlyIfEmpty bool) error {
 	s := &Schema{
 		Name: schema,
 	}
-	db, err := instance.Connect("", "")
+	db, err := instance.ConnectContext(ctx, "", "")
 	if err != nil {
 		return err
 	}
-	_, err = db.Exec(s.DropStatement())
+	_, err = db.ExecContext(ctx, s.DropStatement())
 	if err != nil {
 		return err
 	}
+	instance.InvalidateSchemaCache(schema)
 
 	prefix := fmt.Sprintf("%s?", schema)
 	instance.Lock()
@@ -528,7 +794,13 @@ func (instance *Instance) DropSchema(schema string, onlyIfEmpty bool) error {
 // collation of newCharSet. (Supplying an empty string for both is also allowed,
 // but is a no-op.)
 func (instance *Instance) AlterSchema(schema, newCharSet, newCollation string) error {
-	s, err := instance.Schema(schema)
+	return instance.AlterSchemaContext(context.Background(), schema, newCharSet, newCollation)
+}
+
+// AlterSchemaContext is like AlterSchema, but the supplied context is used for
+// all queries.
+func (instance *Instance) AlterSchemaContext(ctx context.Context, schema, newCharSet, newCollation string) error {
+	s, err := instance.SchemaContext(ctx, schema)
 	if err != nil {
 		return err
 	}
@@ -536,23 +808,32 @@ func (instance *Instance) AlterSchema(schema, newCharSet, newCollation string) e
 	if statement == "" {
 		return nil
 	}
-	db, err := instance.Connect("", "")
+	db, err := instance.ConnectContext(ctx, "", "")
 	if err != nil {
 		return err
 	}
-	if _, err = db.Exec(statement); err != nil {
+	if _, err = db.ExecContext(ctx, statement); err != nil {
 		return err
 	}
+	instance.InvalidateSchemaCache(schema)
 	return nil
 }
 
 // DropTablesInSchema drops all tables in a schema. If onlyIfEmpty==true,
 // returns an error if any of the tables have any rows.
 func (instance *Instance) DropTablesInSchema(schema string, onlyIfEmpty bool) error {
-	db, err := instance.Connect(schema, "foreign_key_checks=0")
+	return instance.DropTablesInSchemaContext(context.Background(), schema, onlyIfEmpty)
+}
+
+// DropTablesInSchemaContext is like DropTablesInSchema, but the supplied
+// context is used for all queries. If any concurrent query fails, or the
+// context is canceled, remaining queries are canceled as well.
+func (instance *Instance) DropTablesInSchemaContext(ctx context.Context, schema string, onlyIfEmpty bool) error {
+	db, err := instance.ConnectContext(ctx, schema, "foreign_key_checks=0")
 	if err != nil {
 		return err
 	}
+	defer instance.InvalidateSchemaCache(schema)
 
 	// Obtain table names directly; faster than going through instance.Schema(schema)
 	// since we don't need other info besides the names
@@ -562,21 +843,21 @@ func (instance *Instance) DropTablesInSchema(schema string, onlyIfEmpty bool) er
 		FROM   information_schema.tables
 		WHERE  table_schema = ?
 		AND    table_type = 'BASE TABLE'`
-	if err := db.Select(&names, query, schema); err != nil {
+	if err := db.SelectContext(ctx, &names, query, schema); err != nil {
 		return err
 	} else if len(names) == 0 {
 		return nil
 	}
 
-	var g errgroup.Group
 	defer db.SetMaxOpenConns(0)
 
 	if onlyIfEmpty {
 		db.SetMaxOpenConns(15)
+		g, checkCtx := errgroup.WithContext(ctx)
 		for _, name := range names {
 			name := name
 			g.Go(func() error {
-				hasRows, err := tableHasRows(db, name)
+				hasRows, err := tableHasRows(checkCtx, db, name)
 				if err == nil && hasRows {
 					err = fmt.Errorf("DropTablesInSchema: table %s.%s has at least one row", EscapeIdentifier(schema), EscapeIdentifier(name))
 				}
@@ -589,11 +870,12 @@ func (instance *Instance) DropTablesInSchema(schema string, onlyIfEmpty bool) er
 	}
 
 	db.SetMaxOpenConns(10)
+	g, dropCtx := errgroup.WithContext(ctx)
 	retries := make(chan string, len(names))
 	for _, name := range names {
 		name := name
 		g.Go(func() error {
-			_, err := db.Exec(fmt.Sprintf("DROP TABLE %s", EscapeIdentifier(name)))
+			_, err := db.ExecContext(dropCtx, fmt.Sprintf("DROP TABLE %s", EscapeIdentifier(name)))
 			// With the new data dictionary added in MySQL 8.0, attempting to
 			// concurrently drop two tables that have a foreign key constraint between
 			// them can deadlock.
@@ -607,7 +889,7 @@ func (instance *Instance) DropTablesInSchema(schema string, onlyIfEmpty bool) er
 	err = g.Wait()
 	close(retries)
 	for name := range retries {
-		if _, err := db.Exec(fmt.Sprintf("DROP TABLE %s", EscapeIdentifier(name))); err != nil {
+		if _, err := db.ExecContext(ctx, fmt.Sprintf("DROP TABLE %s", EscapeIdentifier(name))); err != nil {
 			return err
 		}
 	}
@@ -617,11 +899,17 @@ func (instance *Instance) DropTablesInSchema(schema string, onlyIfEmpty bool) er
 // DefaultCharSetAndCollation returns the instance's default character set and
 // collation
 func (instance *Instance) DefaultCharSetAndCollation() (serverCharSet, serverCollation string, err error) {
-	db, err := instance.Connect("information_schema", "")
+	return instance.DefaultCharSetAndCollationContext(context.Background())
+}
+
+// DefaultCharSetAndCollationContext is like DefaultCharSetAndCollation, but
+// the supplied context is used for the query.
+func (instance *Instance) DefaultCharSetAndCollationContext(ctx context.Context) (serverCharSet, serverCollation string, err error) {
+	db, err := instance.ConnectContext(ctx, "information_schema", "")
 	if err != nil {
 		return
 	}
-	err = db.QueryRow("SELECT @@global.character_set_server, @@global.collation_server").Scan(&serverCharSet, &serverCollation)
+	err = db.QueryRowContext(ctx, "SELECT @@global.character_set_server, @@global.collation_server").Scan(&serverCharSet, &serverCollation)
 	return
 }
 
@@ -629,23 +917,29 @@ func (instance *Instance) DefaultCharSetAndCollation() (serverCharSet, serverCol
 // if re-created on instance, would comply with innodb_strict_mode and a
 // sql_mode including STRICT_TRANS_TABLES,NO_ZERO_DATE.
 // This method does not currently detect invalid-but-nonzero dates in default
-// values, although it may in the future.
+// values; see ValidateSQLMode for a more thorough check of sql_mode issues.
 func (instance *Instance) StrictModeCompliant(schemas []*Schema) (bool, error) {
+	return instance.StrictModeCompliantContext(context.Background(), schemas)
+}
+
+// StrictModeCompliantContext is like StrictModeCompliant, but the supplied
+// context is used for any queries.
+func (instance *Instance) StrictModeCompliantContext(ctx context.Context, schemas []*Schema) (bool, error) {
 	var hasFilePerTable, hasBarracuda, alreadyPopulated bool
 	getFormatVars := func() (fpt, barracuda bool, err error) {
 		if alreadyPopulated {
 			return hasFilePerTable, hasBarracuda, nil
 		}
-		db, err := instance.Connect("", "")
+		db, err := instance.ConnectContext(ctx, "", "")
 		if err != nil {
 			return false, false, err
 		}
 		var ifpt, iff string
-		if instance.Flavor().HasInnoFileFormat() {
-			err = db.QueryRow("SELECT @@global.innodb_file_per_table, @@global.innodb_file_format").Scan(&ifpt, &iff)
+		if instance.flavorContext(ctx).HasInnoFileFormat() {
+			err = db.QueryRowContext(ctx, "SELECT @@global.innodb_file_per_table, @@global.innodb_file_format").Scan(&ifpt, &iff)
 			hasBarracuda = (strings.ToLower(iff) == "barracuda")
 		} else {
-			err = db.QueryRow("SELECT @@global.innodb_file_per_table").Scan(&ifpt)
+			err = db.QueryRowContext(ctx, "SELECT @@global.innodb_file_per_table").Scan(&ifpt)
 			hasBarracuda = true
 		}
 		hasFilePerTable = (ifpt == "1")
@@ -663,7 +957,7 @@ func (instance *Instance) StrictModeCompliant(schemas []*Schema) (bool, error) {
 				}
 			}
 			if format := t.RowFormatClause(); format != "" {
-				needFilePerTable, needBarracuda := instance.Flavor().InnoRowFormatReqs(format)
+				needFilePerTable, needBarracuda := instance.flavorContext(ctx).InnoRowFormatReqs(format)
 				if needFilePerTable || needBarracuda {
 					haveFilePerTable, haveBarracuda, err := getFormatVars()
 					if err != nil {
@@ -679,22 +973,39 @@ func (instance *Instance) StrictModeCompliant(schemas []*Schema) (bool, error) {
 	return true, nil
 }
 
-func (instance *Instance) querySchemaTables(schema string) ([]*Table, error) {
-	db, err := instance.Connect("information_schema", "")
+// querySchemaTables introspects the tables of the supplied schemas, returning
+// a map of schema name to tables. Information_schema queries are batched
+// across all of the supplied schemas. If any tableNames are supplied, only
+// tables with those names are introspected. The returned tables do not yet
+// have their CreateStatement hydrated; see Instance.hydrateCreateStatements.
+func (instance *Instance) querySchemaTables(ctx context.Context, schemaNames []string, tableNames ...string) (map[string][]*Table, error) {
+	db, err := instance.ConnectContext(ctx, "information_schema", "")
 	if err != nil {
 		return nil, err
 	}
+	schemaList := strings.Join(schemaNames, ", ")
+	inArgs := []interface{}{schemaNames}
+	tableFilter := func(column string) string {
+		if len(tableNames) == 0 {
+			return ""
+		}
+		return fmt.Sprintf("AND %s IN (?)", column)
+	}
+	if len(tableNames) > 0 {
+		inArgs = append(inArgs, tableNames)
+	}
 
 	// Obtain flavor and version info. MariaDB changed how default values are
 	// represented in information_schema in 10.2+.
-	flavor := instance.Flavor()
+	flavor := instance.flavorContext(ctx)
 
 	// Note on these queries: MySQL 8.0 changes information_schema column names to
 	// come back from queries in all caps, so we need to explicitly use AS clauses
 	// in order to get them back as lowercase and have sqlx Select() work
 
-	// Obtain the tables in the schema
+	// Obtain the tables in the schemas
 	var rawTables []struct {
+		Schema             string         `db:"table_schema"`
 		Name               string         `db:"table_name"`
 		Type               string         `db:"table_type"`
 		Engine             sql.NullString `db:"engine"`
@@ -705,24 +1016,31 @@ func (instance *Instance) querySchemaTables(schema string) ([]*Table, error) {
 		CharSet            string         `db:"character_set_name"`
 		CollationIsDefault string         `db:"is_default"`
 	}
-	query := `
-		SELECT t.table_name AS table_name, t.table_type AS table_type, t.engine AS engine,
+	query, args, err := sqlx.In(fmt.Sprintf(`
+		SELECT t.table_schema AS table_schema, t.table_name AS table_name,
+		       t.table_type AS table_type, t.engine AS engine,
 		       t.auto_increment AS auto_increment, t.table_collation AS table_collation,
 		       UPPER(t.create_options) AS create_options, t.table_comment AS table_comment,
 		       c.character_set_name AS character_set_name, c.is_default AS is_default
 		FROM   tables t
 		JOIN   collations c ON t.table_collation = c.collation_name
-		WHERE  t.table_schema = ?
-		AND    t.table_type = 'BASE TABLE'`
-	if err := db.Select(&rawTables, query, schema); err != nil {
-		return nil, fmt.Errorf("Error querying information_schema.tables for schema %s: %s", schema, err)
+		WHERE  t.table_schema IN (?) %s
+		AND    t.table_type = 'BASE TABLE'`, tableFilter("t.table_name")), inArgs...)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.SelectContext(ctx, &rawTables, query, args...); err != nil {
+		return nil, fmt.Errorf("Error querying information_schema.tables for schemas %s: %s", schemaList, err)
 	}
+	tablesBySchema := make(map[string][]*Table, len(schemaNames))
 	if len(rawTables) == 0 {
-		return []*Table{}, nil
+		return tablesBySchema, nil
 	}
-	tables := make([]*Table, len(rawTables))
-	for n, rawTable := range rawTables {
-		tables[n] = &Table{
+	// Key tables by schema and name separately, since either may contain dots
+	type tableKey struct{ schema, name string }
+	tablesByFullName := make(map[tableKey]*Table, len(rawTables))
+	for _, rawTable := range rawTables {
+		t := &Table{
 			Name:               rawTable.Name,
 			Engine:             rawTable.Engine.String,
 			CharSet:            rawTable.CharSet,
@@ -731,7 +1049,7 @@ func (instance *Instance) querySchemaTables(schema string) ([]*Table, error) {
 			Comment:            rawTable.Comment,
 		}
 		if rawTable.AutoIncrement.Valid {
-			tables[n].NextAutoIncrement = uint64(rawTable.AutoIncrement.Int64)
+			t.NextAutoIncrement = uint64(rawTable.AutoIncrement.Int64)
 		}
 		if rawTable.CreateOptions.Valid && rawTable.CreateOptions.String != "" && rawTable.CreateOptions.String != "PARTITIONED" {
 			// information_schema.tables.create_options annoyingly contains "partitioned"
@@ -740,15 +1058,18 @@ func (instance *Instance) querySchemaTables(schema string) ([]*Table, error) {
 			// Currently in mysql-server/sql/sql_show.cc, it's always at the *end* of
 			// create_options... but just to code defensively we handle any location.
 			if strings.HasPrefix(rawTable.CreateOptions.String, "PARTITIONED ") {
-				tables[n].CreateOptions = strings.Replace(rawTable.CreateOptions.String, "PARTITIONED ", "", 1)
+				t.CreateOptions = strings.Replace(rawTable.CreateOptions.String, "PARTITIONED ", "", 1)
 			} else {
-				tables[n].CreateOptions = strings.Replace(rawTable.CreateOptions.String, " PARTITIONED", "", 1)
+				t.CreateOptions = strings.Replace(rawTable.CreateOptions.String, " PARTITIONED", "", 1)
 			}
 		}
+		tablesBySchema[rawTable.Schema] = append(tablesBySchema[rawTable.Schema], t)
+		tablesByFullName[tableKey{rawTable.Schema, rawTable.Name}] = t
 	}
 
-	// Obtain the columns in all tables in the schema
+	// Obtain the columns in all tables in the schemas
 	var rawColumns []struct {
+		Schema             string         `db:"table_schema"`
 		Name               string         `db:"column_name"`
 		TableName          string         `db:"table_name"`
 		Type               string         `db:"column_type"`
@@ -760,8 +1081,9 @@ func (instance *Instance) querySchemaTables(schema string) ([]*Table, error) {
 		Collation          sql.NullString `db:"collation_name"`
 		CollationIsDefault sql.NullString `db:"is_default"`
 	}
-	query = `
-		SELECT    c.table_name AS table_name, c.column_name AS column_name,
+	query, args, err = sqlx.In(fmt.Sprintf(`
+		SELECT    c.table_schema AS table_schema, c.table_name AS table_name,
+		          c.column_name AS column_name,
 		          c.column_type AS column_type, c.is_nullable AS is_nullable,
 		          c.column_default AS column_default, c.extra AS extra,
 		          c.column_comment AS column_comment,
@@ -769,12 +1091,14 @@ func (instance *Instance) querySchemaTables(schema string) ([]*Table, error) {
 		          c.collation_name AS collation_name, co.is_default AS is_default
 		FROM      columns c
 		LEFT JOIN collations co ON co.collation_name = c.collation_name
-		WHERE     c.table_schema = ?
-		ORDER BY  c.table_name, c.ordinal_position`
-	if err := db.Select(&rawColumns, query, schema); err != nil {
-		return nil, fmt.Errorf("Error querying information_schema.columns for schema %s: %s", schema, err)
+		WHERE     c.table_schema IN (?) %s
+		ORDER BY  c.table_schema, c.table_name, c.ordinal_position`, tableFilter("c.table_name")), inArgs...)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.SelectContext(ctx, &rawColumns, query, args...); err != nil {
+		return nil, fmt.Errorf("Error querying information_schema.columns for schemas %s: %s", schemaList, err)
 	}
-	columnsByTableName := make(map[string][]*Column)
 	columnsByTableAndName := make(map[string]*Column)
 	for _, rawColumn := range rawColumns {
 		col := &Column{
@@ -814,29 +1138,26 @@ func (instance *Instance) querySchemaTables(schema string) ([]*Table, error) {
 			col.Collation = rawColumn.Collation.String
 			col.CollationIsDefault = (rawColumn.CollationIsDefault.String != "")
 		}
-		if columnsByTableName[rawColumn.TableName] == nil {
-			columnsByTableName[rawColumn.TableName] = make([]*Column, 0)
+		if t, ok := tablesByFullName[tableKey{rawColumn.Schema, rawColumn.TableName}]; ok {
+			t.Columns = append(t.Columns, col)
 		}
-		columnsByTableName[rawColumn.TableName] = append(columnsByTableName[rawColumn.TableName], col)
-		fullNameStr := fmt.Sprintf("%s.%s.%s", schema, rawColumn.TableName, rawColumn.Name)
+		fullNameStr := fmt.Sprintf("%s.%s.%s", rawColumn.Schema, rawColumn.TableName, rawColumn.Name)
 		columnsByTableAndName[fullNameStr] = col
 	}
-	for n, t := range tables {
-		// Put the columns into the table
-		tables[n].Columns = columnsByTableName[t.Name]
-
+	for _, t := range tablesByFullName {
 		// Avoid issues from data dictionary weirdly caching a NULL next auto-inc
 		if t.NextAutoIncrement == 0 && t.HasAutoIncrement() {
-			tables[n].NextAutoIncrement = 1
+			t.NextAutoIncrement = 1
 		}
 	}
 
-	// Obtain the indexes of all tables in the schema. Since multi-column indexes
+	// Obtain the indexes of all tables in the schemas. Since multi-column indexes
 	// have multiple rows in the result set, we do two passes over the result: one
 	// to figure out which indexes exist, and one to stitch together the col info.
 	// We cannot use an ORDER BY on this query, since only the unsorted result
 	// matches the same order of secondary indexes as the CREATE TABLE statement.
 	var rawIndexes []struct {
+		Schema     string         `db:"table_schema"`
 		Name       string         `db:"index_name"`
 		TableName  string         `db:"table_name"`
 		NonUnique  uint8          `db:"non_unique"`
@@ -845,23 +1166,29 @@ func (instance *Instance) querySchemaTables(schema string) ([]*Table, error) {
 		SubPart    sql.NullInt64  `db:"sub_part"`
 		Comment    sql.NullString `db:"index_comment"`
 	}
-	query = `
-		SELECT   index_name AS index_name, table_name AS table_name,
+	query, args, err = sqlx.In(fmt.Sprintf(`
+		SELECT   table_schema AS table_schema, index_name AS index_name,
+		         table_name AS table_name,
 		         non_unique AS non_unique, seq_in_index AS seq_in_index,
 		         column_name AS column_name, sub_part AS sub_part,
 		         index_comment AS index_comment
 		FROM     statistics
-		WHERE    table_schema = ?`
-	if err := db.Select(&rawIndexes, query, schema); err != nil {
-		return nil, fmt.Errorf("Error querying information_schema.statistics for schema %s: %s", schema, err)
+		WHERE    table_schema IN (?) %s`, tableFilter("table_name")), inArgs...)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.SelectContext(ctx, &rawIndexes, query, args...); err != nil {
+		return nil, fmt.Errorf("Error querying information_schema.statistics for schemas %s: %s", schemaList, err)
 	}
-	primaryKeyByTableName := make(map[string]*Index)
-	secondaryIndexesByTableName := make(map[string][]*Index)
 	indexesByTableAndName := make(map[string]*Index)
 	for _, rawIndex := range rawIndexes {
 		if rawIndex.SeqInIndex > 1 {
 			continue
 		}
+		t, ok := tablesByFullName[tableKey{rawIndex.Schema, rawIndex.TableName}]
+		if !ok {
+			continue
+		}
 		index := &Index{
 			Name:     rawIndex.Name,
 			Unique:   rawIndex.NonUnique == 0,
@@ -870,24 +1197,21 @@ func (instance *Instance) querySchemaTables(schema string) ([]*Table, error) {
 			Comment:  rawIndex.Comment.String,
 		}
 		if strings.ToUpper(index.Name) == "PRIMARY" {
-			primaryKeyByTableName[rawIndex.TableName] = index
+			t.PrimaryKey = index
 			index.PrimaryKey = true
 		} else {
-			if secondaryIndexesByTableName[rawIndex.TableName] == nil {
-				secondaryIndexesByTableName[rawIndex.TableName] = make([]*Index, 0)
-			}
-			secondaryIndexesByTableName[rawIndex.TableName] = append(secondaryIndexesByTableName[rawIndex.TableName], index)
+			t.SecondaryIndexes = append(t.SecondaryIndexes, index)
 		}
-		fullNameStr := fmt.Sprintf("%s.%s.%s", schema, rawIndex.TableName, rawIndex.Name)
+		fullNameStr := fmt.Sprintf("%s.%s.%s", rawIndex.Schema, rawIndex.TableName, rawIndex.Name)
 		indexesByTableAndName[fullNameStr] = index
 	}
 	for _, rawIndex := range rawIndexes {
-		fullIndexNameStr := fmt.Sprintf("%s.%s.%s", schema, rawIndex.TableName, rawIndex.Name)
+		fullIndexNameStr := fmt.Sprintf("%s.%s.%s", rawIndex.Schema, rawIndex.TableName, rawIndex.Name)
 		index, ok := indexesByTableAndName[fullIndexNameStr]
 		if !ok {
-			panic(fmt.Errorf("Cannot find index %s", fullIndexNameStr))
+			continue // index of a non-base table, which we skip
 		}
-		fullColNameStr := fmt.Sprintf("%s.%s.%s", schema, rawIndex.TableName, rawIndex.ColumnName)
+		fullColNameStr := fmt.Sprintf("%s.%s.%s", rawIndex.Schema, rawIndex.TableName, rawIndex.ColumnName)
 		col, ok := columnsByTableAndName[fullColNameStr]
 		if !ok {
 			panic(fmt.Errorf("Cannot find indexed column %s for index %s", fullColNameStr, fullIndexNameStr))
@@ -901,13 +1225,10 @@ func (instance *Instance) querySchemaTables(schema string) ([]*Table, error) {
 			index.SubParts[rawIndex.SeqInIndex-1] = uint16(rawIndex.SubPart.Int64)
 		}
 	}
-	for _, t := range tables {
-		t.PrimaryKey = primaryKeyByTableName[t.Name]
-		t.SecondaryIndexes = secondaryIndexesByTableName[t.Name]
-	}
 
-	// Obtain the foreign keys of the tables in the schema
+	// Obtain the foreign keys of the tables in the schemas
 	var rawForeignKeys []struct {
+		Schema               string `db:"constraint_schema"`
 		Name                 string `db:"constraint_name"`
 		TableName            string `db:"table_name"`
 		UpdateRule           string `db:"update_rule"`
@@ -917,8 +1238,9 @@ func (instance *Instance) querySchemaTables(schema string) ([]*Table, error) {
 		ReferencedColumnName string `db:"referenced_column_name"`
 		ColumnLookupKey      string `db:"col_lookup_key"`
 	}
-	query = `
-		SELECT   rc.constraint_name AS constraint_name, rc.table_name AS table_name,
+	query, args, err = sqlx.In(fmt.Sprintf(`
+		SELECT   rc.constraint_schema AS constraint_schema,
+		         rc.constraint_name AS constraint_name, rc.table_name AS table_name,
 		         rc.update_rule AS update_rule, rc.delete_rule AS delete_rule,
 		         rc.referenced_table_name AS referenced_table_name,
 		         IF(rc.constraint_schema=rc.unique_constraint_schema, '', rc.unique_constraint_schema) AS referenced_schema,
@@ -928,19 +1250,22 @@ func (instance *Instance) querySchemaTables(schema string) ([]*Table, error) {
 		JOIN     key_column_usage kcu ON kcu.constraint_name = rc.constraint_name AND
 		                                 kcu.constraint_schema = rc.constraint_schema AND
 		                                 kcu.referenced_column_name IS NOT NULL
-		WHERE    rc.constraint_schema = ?
-		ORDER BY BINARY rc.constraint_name, kcu.ordinal_position`
-	if err := db.Select(&rawForeignKeys, query, schema); err != nil {
-		return nil, fmt.Errorf("Error querying foreign key constraints for schema %s: %s", schema, err)
+		WHERE    rc.constraint_schema IN (?) %s
+		ORDER BY BINARY rc.constraint_name, kcu.ordinal_position`, tableFilter("rc.table_name")), inArgs...)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.SelectContext(ctx, &rawForeignKeys, query, args...); err != nil {
+		return nil, fmt.Errorf("Error querying foreign key constraints for schemas %s: %s", schemaList, err)
 	}
-	foreignKeysByTableName := make(map[string][]*ForeignKey)
 	foreignKeysByName := make(map[string]*ForeignKey)
 	for _, rawForeignKey := range rawForeignKeys {
 		col := columnsByTableAndName[rawForeignKey.ColumnLookupKey]
-		if fk, already := foreignKeysByName[rawForeignKey.Name]; already {
+		fullNameStr := fmt.Sprintf("%s.%s", rawForeignKey.Schema, rawForeignKey.Name)
+		if fk, already := foreignKeysByName[fullNameStr]; already {
 			fk.Columns = append(fk.Columns, col)
 			fk.ReferencedColumnNames = append(fk.ReferencedColumnNames, rawForeignKey.ReferencedColumnName)
-		} else {
+		} else if t, ok := tablesByFullName[tableKey{rawForeignKey.Schema, rawForeignKey.TableName}]; ok {
 			foreignKey := &ForeignKey{
 				Name:                  rawForeignKey.Name,
 				ReferencedSchemaName:  rawForeignKey.ReferencedSchemaName,
@@ -950,51 +1275,38 @@ func (instance *Instance) querySchemaTables(schema string) ([]*Table, error) {
 				Columns:               []*Column{col},
 				ReferencedColumnNames: []string{rawForeignKey.ReferencedColumnName},
 			}
-			foreignKeysByName[rawForeignKey.Name] = foreignKey
-			foreignKeysByTableName[rawForeignKey.TableName] = append(foreignKeysByTableName[rawForeignKey.TableName], foreignKey)
+			foreignKeysByName[fullNameStr] = foreignKey
+			t.ForeignKeys = append(t.ForeignKeys, foreignKey)
 		}
 	}
-	for _, t := range tables {
-		t.ForeignKeys = foreignKeysByTableName[t.Name]
-	}
+	return tablesBySchema, nil
+}
 
-	// Obtain actual SHOW CREATE TABLE output and store in each table. Since
-	// there's no way in MySQL to bulk fetch this for multiple tables at once,
-	// use multiple goroutines to make this faster.
-	db, err = instance.Connect(schema, "")
-	if err != nil {
-		return nil, err
+// hydrateTable obtains the actual SHOW CREATE TABLE output for t, which must
+// be in the supplied schema, and stores it in t. The DDL is also compared to
+// what tengo expects, in order to determine if diffing is supported for t.
+func hydrateTable(ctx context.Context, db *sqlx.DB, schema string, t *Table, flavor Flavor) (err error) {
+	if t.CreateStatement, err = showCreateTable(ctx, db, schema, t.Name); err != nil {
+		return fmt.Errorf("Error executing SHOW CREATE TABLE for %s.%s: %s", EscapeIdentifier(schema), EscapeIdentifier(t.Name), err)
+	}
+	if t.Engine == "InnoDB" {
+		t.CreateStatement = NormalizeCreateOptions(t.CreateStatement)
+	}
+	// Index order is unpredictable with new MySQL 8 data dictionary, so reorder
+	// indexes based on parsing SHOW CREATE TABLE if needed
+	if flavor.HasDataDictionary() && len(t.SecondaryIndexes) > 1 {
+		fixIndexOrder(t)
+	}
+	// Compare what we expect the create DDL to be, to determine if we support
+	// diffing for the table. Ignore next-auto-increment differences in this
+	// comparison, since the value may have changed between our previous
+	// information_schema introspection and our current SHOW CREATE TABLE call!
+	actual, _ := ParseCreateAutoInc(t.CreateStatement)
+	expected, _ := ParseCreateAutoInc(t.GeneratedCreateStatement(flavor))
+	if actual != expected {
+		t.UnsupportedDDL = true
 	}
-	defer db.SetMaxOpenConns(0)
-	db.SetMaxOpenConns(10)
-	var g errgroup.Group
-	for _, t := range tables {
-		t := t
-		g.Go(func() (err error) {
-			if t.CreateStatement, err = showCreateTable(db, t.Name); err != nil {
-				return fmt.Errorf("Error executing SHOW CREATE TABLE for %s.%s: %s", EscapeIdentifier(schema), EscapeIdentifier(t.Name), err)
-			}
-			if t.Engine == "InnoDB" {
-				t.CreateStatement = NormalizeCreateOptions(t.CreateStatement)
-			}
-			// Index order is unpredictable with new MySQL 8 data dictionary, so reorder
-			// indexes based on parsing SHOW CREATE TABLE if needed
-			if flavor.HasDataDictionary() && len(t.SecondaryIndexes) > 1 {
-				fixIndexOrder(t)
-			}
-			// Compare what we expect the create DDL to be, to determine if we support
-			// diffing for the table. Ignore next-auto-increment differences in this
-			// comparison, since the value may have changed between our previous
-			// information_schema introspection and our current SHOW CREATE TABLE call!
-			actual, _ := ParseCreateAutoInc(t.CreateStatement)
-			expected, _ := ParseCreateAutoInc(t.GeneratedCreateStatement(flavor))
-			if actual != expected {
-				t.UnsupportedDDL = true
-			}
-			return nil
-		})
-	}
-	return tables, g.Wait()
+	return nil
 }
 
 var reIndexLine = regexp.MustCompile("^\\s+(?:UNIQUE )?KEY `(.+)` \\(`")
@@ -1013,17 +1325,25 @@ func fixIndexOrder(t *Table) {
 	}
 }
 
-func (instance *Instance) querySchemaRoutines(schema string) ([]*Routine, error) {
-	db, err := instance.Connect("information_schema", "")
+// querySchemaRoutines introspects the routines of the supplied schemas,
+// returning a map of schema name to routines. Information_schema queries are
+// batched across all of the supplied schemas. In flavors without the new data
+// dictionary, routines are hydrated using a bulk query of mysql.proc if
+// possible; any routines lacking a CreateStatement afterwards must be hydrated
+// using hydrateRoutine.
+func (instance *Instance) querySchemaRoutines(ctx context.Context, schemaNames ...string) (map[string][]*Routine, error) {
+	db, err := instance.ConnectContext(ctx, "information_schema", "")
 	if err != nil {
 		return nil, err
 	}
+	flavor := instance.flavorContext(ctx)
 
 	// Note on this query: MySQL 8.0 changes information_schema column names to
 	// come back from queries in all caps, so we need to explicitly use AS clauses
 	// in order to get them back as lowercase and have sqlx Select() work
-	// Obtain the routines in the schema
+	// Obtain the routines in the schemas
 	var rawRoutines []struct {
+		Schema            string         `db:"routine_schema"`
 		Name              string         `db:"routine_name"`
 		Type              string         `db:"routine_type"`
 		Body              sql.NullString `db:"routine_definition"`
@@ -1035,8 +1355,9 @@ func (instance *Instance) querySchemaRoutines(schema string) ([]*Routine, error)
 		Definer           string         `db:"definer"`
 		DatabaseCollation string         `db:"database_collation"`
 	}
-	query := `
-		SELECT r.routine_name AS routine_name, UPPER(r.routine_type) AS routine_type,
+	query, args, err := sqlx.In(`
+		SELECT r.routine_schema AS routine_schema,
+		       r.routine_name AS routine_name, UPPER(r.routine_type) AS routine_type,
 		       r.routine_definition AS routine_definition,
 		       UPPER(r.is_deterministic) AS is_deterministic,
 		       UPPER(r.sql_data_access) AS sql_data_access,
@@ -1044,17 +1365,20 @@ func (instance *Instance) querySchemaRoutines(schema string) ([]*Routine, error)
 		       r.sql_mode AS sql_mode, r.routine_comment AS routine_comment,
 		       r.definer AS definer, r.database_collation AS database_collation
 		FROM   routines r
-		WHERE  r.routine_schema = ?`
-	if err := db.Select(&rawRoutines, query, schema); err != nil {
-		return nil, fmt.Errorf("Error querying information_schema.routines for schema %s: %s", schema, err)
+		WHERE  r.routine_schema IN (?)`, schemaNames)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.SelectContext(ctx, &rawRoutines, query, args...); err != nil {
+		return nil, fmt.Errorf("Error querying information_schema.routines for schemas %s: %s", strings.Join(schemaNames, ", "), err)
 	}
+	routinesBySchema := make(map[string][]*Routine, len(schemaNames))
 	if len(rawRoutines) == 0 {
-		return []*Routine{}, nil
+		return routinesBySchema, nil
 	}
-	routines := make([]*Routine, len(rawRoutines))
-	dict := make(map[ObjectKey]*Routine, len(rawRoutines))
-	for n, rawRoutine := range rawRoutines {
-		routines[n] = &Routine{
+	dict := make(map[string]*Routine, len(rawRoutines)) // key is "schema.type.name"
+	for _, rawRoutine := range rawRoutines {
+		r := &Routine{
 			Name:              rawRoutine.Name,
 			Type:              ObjectType(strings.ToLower(rawRoutine.Type)),
 			Body:              rawRoutine.Body.String, // This contains incorrect formatting conversions; overwritten later
@@ -1066,11 +1390,11 @@ func (instance *Instance) querySchemaRoutines(schema string) ([]*Routine, error)
 			SecurityType:      rawRoutine.SecurityType,
 			SQLMode:           rawRoutine.SQLMode,
 		}
-		if routines[n].Type != ObjectTypeProc && routines[n].Type != ObjectTypeFunc {
-			return nil, fmt.Errorf("Unsupported routine type %s found in %s.%s", rawRoutine.Type, schema, rawRoutine.Name)
+		if r.Type != ObjectTypeProc && r.Type != ObjectTypeFunc {
+			return nil, fmt.Errorf("Unsupported routine type %s found in %s.%s", rawRoutine.Type, rawRoutine.Schema, rawRoutine.Name)
 		}
-		key := ObjectKey{Type: routines[n].Type, Name: routines[n].Name}
-		dict[key] = routines[n]
+		routinesBySchema[rawRoutine.Schema] = append(routinesBySchema[rawRoutine.Schema], r)
+		dict[fmt.Sprintf("%s.%s.%s", rawRoutine.Schema, r.Type, r.Name)] = r
 	}
 
 	// Obtain param string, return type string, and full create statement:
@@ -1080,80 +1404,82 @@ func (instance *Instance) querySchemaRoutines(schema string) ([]*Routine, error)
 	// In flavors without the new data dictionary, we first try querying mysql.proc
 	// to bulk-fetch sufficient info to rebuild the CREATE without needing to run
 	// a SHOW CREATE per routine.
-	// If mysql.proc doesn't exist or that query fails, we then run a SHOW CREATE
-	// per routine, using multiple goroutines for performance reasons.
-	db, err = instance.Connect(schema, "")
-	if err != nil {
-		return nil, err
-	}
-	if !instance.Flavor().HasDataDictionary() {
+	// If mysql.proc doesn't exist or that query fails, the caller must run a SHOW
+	// CREATE per routine.
+	if !flavor.HasDataDictionary() {
 		var rawRoutineMeta []struct {
+			Schema    string `db:"db"`
 			Name      string `db:"name"`
 			Type      string `db:"type"`
 			Body      string `db:"body"`
 			ParamList string `db:"param_list"`
 			Returns   string `db:"returns"`
 		}
-		query := `
-			SELECT name, type, body, param_list, returns
+		query, args, err := sqlx.In(`
+			SELECT db, name, type, body, param_list, returns
 			FROM   mysql.proc
-			WHERE  db = ?`
+			WHERE  db IN (?)`, schemaNames)
+		if err != nil {
+			return nil, err
+		}
 		// Errors here are non-fatal. No need to even check; slice will be empty which is fine
-		db.Select(&rawRoutineMeta, query, schema)
+		db.SelectContext(ctx, &rawRoutineMeta, query, args...)
 		for _, meta := range rawRoutineMeta {
-			key := ObjectKey{Type: ObjectType(strings.ToLower(meta.Type)), Name: meta.Name}
+			key := fmt.Sprintf("%s.%s.%s", meta.Schema, strings.ToLower(meta.Type), meta.Name)
 			if routine, ok := dict[key]; ok {
 				routine.ParamString = meta.ParamList
 				routine.ReturnDataType = meta.Returns
 				routine.Body = strings.Replace(meta.Body, "\r\n", "\n", -1)
-				routine.CreateStatement = routine.Definition(instance.Flavor())
+				routine.CreateStatement = routine.Definition(flavor)
 			}
 		}
 	}
-	defer db.SetMaxOpenConns(0)
-	db.SetMaxOpenConns(10)
-	var g errgroup.Group
-	for _, r := range routines {
-		if r.CreateStatement != "" {
-			continue // already hydrated from mysql.proc query above
-		}
-		r := r
-		g.Go(func() (err error) {
-			if r.CreateStatement, err = showCreateRoutine(db, r.Name, r.Type); err != nil {
-				return fmt.Errorf("Error executing SHOW CREATE %s for %s.%s: %s", r.Type.Caps(), EscapeIdentifier(schema), EscapeIdentifier(r.Name), err)
-			}
-			r.CreateStatement = strings.Replace(r.CreateStatement, "\r\n", "\n", -1)
-			var returnsClause string
-			if r.Type == ObjectTypeFunc {
-				returnsClause = " RETURNS ([^\n]+)"
-			}
-			reTemplate := fmt.Sprintf("^CREATE[^\n]* %s %s\\(([^\n]*)\\)%s\n", r.Type.Caps(), EscapeIdentifier(r.Name), returnsClause)
-			var reCreateRoutine = regexp.MustCompile(reTemplate)
-			matches := reCreateRoutine.FindStringSubmatch(r.CreateStatement)
-			if matches == nil {
-				return fmt.Errorf("Failed to parse %s", r.CreateStatement)
-			}
-			r.ParamString = matches[1]
-			if r.Type == ObjectTypeFunc {
-				r.ReturnDataType = matches[2]
-			}
-			// Attempt to replace r.Body with one that doesn't have character conversion problems
-			if header := r.head(instance.Flavor()); strings.HasPrefix(r.CreateStatement, header) {
-				r.Body = r.CreateStatement[len(header):]
-			}
-			return nil
-		})
+	return routinesBySchema, nil
+}
+
+// hydrateRoutine obtains the actual SHOW CREATE output for r, which must be in
+// the supplied schema, and uses it to populate r's CreateStatement, Body,
+// ParamString, and ReturnDataType.
+func hydrateRoutine(ctx context.Context, db *sqlx.DB, schema string, r *Routine, flavor Flavor) (err error) {
+	if r.CreateStatement, err = showCreateRoutine(ctx, db, schema, r.Name, r.Type); err != nil {
+		return fmt.Errorf("Error executing SHOW CREATE %s for %s.%s: %s", r.Type.Caps(), EscapeIdentifier(schema), EscapeIdentifier(r.Name), err)
+	}
+	r.CreateStatement = strings.Replace(r.CreateStatement, "\r\n", "\n", -1)
+	var returnsClause string
+	if r.Type == ObjectTypeFunc {
+		returnsClause = " RETURNS ([^\n]+)"
 	}
-	return routines, g.Wait()
+	reTemplate := fmt.Sprintf("^CREATE[^\n]* %s %s\\(([^\n]*)\\)%s\n", r.Type.Caps(), EscapeIdentifier(r.Name), returnsClause)
+	var reCreateRoutine = regexp.MustCompile(reTemplate)
+	matches := reCreateRoutine.FindStringSubmatch(r.CreateStatement)
+	if matches == nil {
+		return fmt.Errorf("Failed to parse %s", r.CreateStatement)
+	}
+	r.ParamString = matches[1]
+	if r.Type == ObjectTypeFunc {
+		r.ReturnDataType = matches[2]
+	}
+	// Attempt to replace r.Body with one that doesn't have character conversion problems
+	if header := r.head(flavor); strings.HasPrefix(r.CreateStatement, header) {
+		r.Body = r.CreateStatement[len(header):]
+	}
+	return nil
 }
 
-func showCreateRoutine(db *sqlx.DB, routine string, ot ObjectType) (create string, err error) {
-	query := fmt.Sprintf("SHOW CREATE %s %s", ot.Caps(), EscapeIdentifier(routine))
+// showCreateRoutine returns the SHOW CREATE output for the supplied routine. If
+// schema is blank, the routine name is not qualified, so db's default schema
+// is used.
+func showCreateRoutine(ctx context.Context, db *sqlx.DB, schema, routine string, ot ObjectType) (create string, err error) {
+	name := EscapeIdentifier(routine)
+	if schema != "" {
+		name = fmt.Sprintf("%s.%s", EscapeIdentifier(schema), name)
+	}
+	query := fmt.Sprintf("SHOW CREATE %s %s", ot.Caps(), name)
 	if ot == ObjectTypeProc {
 		var createRows []struct {
 			CreateStatement sql.NullString `db:"Create Procedure"`
 		}
-		err = db.Select(&createRows, query)
+		err = db.SelectContext(ctx, &createRows, query)
 		if (err == nil && len(createRows) != 1) || IsDatabaseError(err, mysqlerr.ER_SP_DOES_NOT_EXIST) {
 			err = sql.ErrNoRows
 		} else if err == nil {
@@ -1163,7 +1489,7 @@ func showCreateRoutine(db *sqlx.DB, routine string, ot ObjectType) (create strin
 		var createRows []struct {
 			CreateStatement sql.NullString `db:"Create Function"`
 		}
-		err = db.Select(&createRows, query)
+		err = db.SelectContext(ctx, &createRows, query)
 		if (err == nil && len(createRows) != 1) || IsDatabaseError(err, mysqlerr.ER_SP_DOES_NOT_EXIST) {
 			err = sql.ErrNoRows
 		} else if err == nil {
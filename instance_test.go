@@ -1,13 +1,26 @@
 package tengo
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"database/sql"
+	"encoding/pem"
 	"fmt"
+	"io/ioutil"
+	"math/big"
 	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
 )
 
@@ -76,6 +89,114 @@ func TestNewInstance(t *testing.T) {
 	assertInstance(dsn, expected)
 }
 
+func TestNewInstanceWithOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tengo-tls")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Generate a key pair and self-signed cert, usable as both CA and client cert
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("Unable to generate key: %s", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "tengo"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Unable to create certificate: %s", err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("Unable to marshal public key: %s", err)
+	}
+	writePEM := func(name, blockType string, der []byte) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+			t.Fatalf("Unable to write %s: %s", path, err)
+		}
+		return path
+	}
+	certFile := writePEM("cert.pem", "CERTIFICATE", certDER)
+	keyFile := writePEM("key.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key))
+	pubKeyFile := writePEM("pub.pem", "PUBLIC KEY", pubDER)
+
+	// Zero value options should behave identically to NewInstance
+	dsn := "username:password@tcp(1.2.3.4:3306)/?wait_timeout=20"
+	expected, _ := NewInstance("mysql", dsn)
+	instance, err := NewInstanceWithOptions("mysql", dsn, InstanceOptions{})
+	if err != nil || !reflect.DeepEqual(instance.defaultParams, expected.defaultParams) {
+		t.Errorf("Expected zero-value options to match NewInstance; instead found params %v, err %v", instance.defaultParams, err)
+	}
+
+	opts := InstanceOptions{
+		TLSConfig:               &tls.Config{MinVersion: tls.VersionTLS12},
+		CACertFile:              certFile,
+		ClientCertFile:          certFile,
+		ClientKeyFile:           keyFile,
+		ServerPubKeyFile:        pubKeyFile,
+		AllowCleartextPasswords: true,
+	}
+	instance, err = NewInstanceWithOptions("mysql", dsn, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error from NewInstanceWithOptions: %s", err)
+	}
+	for _, param := range []string{"tls", "serverPubKey", "allowCleartextPasswords", "wait_timeout"} {
+		if instance.defaultParams[param] == "" {
+			t.Errorf("Expected param %s to be set, but it was not; params=%v", param, instance.defaultParams)
+		}
+	}
+	if opts.TLSConfig.RootCAs != nil || len(opts.TLSConfig.Certificates) > 0 {
+		t.Error("NewInstanceWithOptions unexpectedly modified caller's TLSConfig")
+	}
+	fullDSN := instance.BaseDSN + "?" + instance.buildParamString("")
+	cfg, err := mysql.ParseDSN(fullDSN)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing DSN %s: %s", fullDSN, err)
+	}
+	if cfg.TLSConfig != instance.defaultParams["tls"] {
+		t.Errorf("Expected DSN to use registered TLS config %s, instead found %s", instance.defaultParams["tls"], cfg.TLSConfig)
+	}
+	if tlsConfig, _ := opts.tlsConfig(); len(tlsConfig.Certificates) != 1 || tlsConfig.RootCAs == nil || tlsConfig.MinVersion != tls.VersionTLS12 {
+		t.Errorf("TLS config does not match expectations: %+v", tlsConfig)
+	}
+	if !cfg.AllowCleartextPasswords {
+		t.Error("Expected DSN to allow cleartext passwords")
+	}
+
+	// Each instance should register a distinct name
+	other, err := NewInstanceWithOptions("mysql", dsn, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error from NewInstanceWithOptions: %s", err)
+	} else if other.defaultParams["tls"] == instance.defaultParams["tls"] {
+		t.Errorf("Expected distinct TLS config names, but both are %s", other.defaultParams["tls"])
+	}
+
+	// Test error conditions
+	badOpts := []InstanceOptions{
+		{ClientCertFile: certFile},
+		{CACertFile: filepath.Join(dir, "doesnt-exist.pem")},
+		{CACertFile: pubKeyFile},
+		{ServerPubKeyFile: certFile},
+		{ServerPubKeyFile: filepath.Join(dir, "doesnt-exist.pem")},
+	}
+	for _, bad := range badOpts {
+		if _, err := NewInstanceWithOptions("mysql", dsn, bad); err == nil {
+			t.Errorf("Expected error from NewInstanceWithOptions with options %+v, but err was nil", bad)
+		}
+	}
+	if _, err := NewInstanceWithOptions("mysql", dsn+"&tls=true", opts); err == nil {
+		t.Error("Expected error from NewInstanceWithOptions with conflicting DSN param, but err was nil")
+	}
+}
+
 func TestInstanceBuildParamString(t *testing.T) {
 	assertParamString := func(defaultOptions, addOptions, expectOptions string) {
 		dsn := "username:password@tcp(1.2.3.4:3306)/"
@@ -460,6 +581,35 @@ func (s TengoIntegrationSuite) TestInstanceDropTablesDeadlock(t *testing.T) {
 	}
 }
 
+func (s TengoIntegrationSuite) TestInstanceContext(t *testing.T) {
+	// Introspection with a reasonable timeout should succeed
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	schema, err := s.d.SchemaContext(ctx, "testing")
+	cancel()
+	if err != nil {
+		t.Fatalf("Unexpected error from SchemaContext: %s", err)
+	} else if len(schema.Tables) == 0 {
+		t.Error("Expected SchemaContext to return tables, but it did not")
+	}
+
+	// Methods called with an already-canceled context should fail, without
+	// making any changes
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.d.SchemasContext(ctx, "testing"); err == nil {
+		t.Error("Expected SchemasContext with canceled context to return an error, but it did not")
+	}
+	if _, err := s.d.ShowCreateTableContext(ctx, "testing", "actor"); err == nil {
+		t.Error("Expected ShowCreateTableContext with canceled context to return an error, but it did not")
+	}
+	if err := s.d.DropSchemaContext(ctx, "testing", false); err == nil {
+		t.Error("Expected DropSchemaContext with canceled context to return an error, but it did not")
+	}
+	if has, err := s.d.HasSchema("testing"); !has || err != nil {
+		t.Errorf("Expected schema to still exist after canceled DropSchemaContext; instead found %t, %v", has, err)
+	}
+}
+
 func (s TengoIntegrationSuite) TestInstanceAlterSchema(t *testing.T) {
 	assertNoError := func(schemaName, newCharSet, newCollation, expectCharSet, expectCollation string) {
 		t.Helper()
@@ -580,6 +730,90 @@ func (s TengoIntegrationSuite) TestInstanceSchemaIntrospection(t *testing.T) {
 
 }
 
+func (s TengoIntegrationSuite) TestInstanceSchemasWithOptions(t *testing.T) {
+	expected := s.GetSchema(t, "testing")
+	var calls, lastCompleted, total int
+	opts := IntrospectionOptions{
+		Workers: 1,
+		Progress: func(p IntrospectionProgress) {
+			calls++
+			lastCompleted, total = p.Completed, p.Total
+			if p.SchemaName != "testing" || p.Object.Name == "" {
+				t.Errorf("Unexpected progress value %+v", p)
+			}
+		},
+	}
+	schemas, err := s.d.SchemasWithOptions(context.Background(), opts, "testing")
+	if err != nil {
+		t.Fatalf("Unexpected error from SchemasWithOptions: %s", err)
+	} else if len(schemas) != 1 {
+		t.Fatalf("Expected 1 schema, instead found %d", len(schemas))
+	}
+	objectCount := len(expected.Tables) + len(expected.Routines)
+	if calls != objectCount || lastCompleted != objectCount || total != objectCount {
+		t.Errorf("Expected %d progress calls, instead found calls=%d completed=%d total=%d", objectCount, calls, lastCompleted, total)
+	}
+	if diff := NewSchemaDiff(expected, schemas[0]); len(diff.TableDiffs) > 0 || len(diff.RoutineDiffs) > 0 {
+		t.Errorf("Schema introspected with a single worker unexpectedly differs from default introspection: %+v", diff)
+	}
+
+	// Introspecting many schemas at once should yield the same results as
+	// introspecting them individually
+	all, err := s.d.SchemasByName()
+	if err != nil {
+		t.Fatalf("Unexpected error from SchemasByName: %s", err)
+	}
+	for name, schema := range all {
+		individual := s.GetSchema(t, name)
+		if schema.Fingerprint(FingerprintOptions{}) != individual.Fingerprint(FingerprintOptions{}) {
+			t.Errorf("Schema %s introspected in batch differs from individual introspection", name)
+		}
+	}
+}
+
+func (s TengoIntegrationSuite) TestInstanceSchemasDottedNames(t *testing.T) {
+	// Schema "dots.a" with table "b", and schema "dots" with table "a.b", must
+	// not have their columns or indexes mixed up with each other
+	db, err := s.d.Connect("", "")
+	if err != nil {
+		t.Fatalf("Unable to connect: %s", err)
+	}
+	for _, name := range []string{"dots", "dots.a"} {
+		if _, err := s.d.CreateSchema(name, "", ""); err != nil {
+			t.Fatalf("Unable to create schema: %s", err)
+		}
+	}
+	for _, stmt := range []string{
+		"CREATE TABLE `dots.a`.`b` (x int NOT NULL, PRIMARY KEY (x))",
+		"CREATE TABLE `dots`.`a.b` (y int NOT NULL, z int, KEY (z))",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Unable to create table: %s", err)
+		}
+	}
+	schemas, err := s.d.SchemasByName("dots", "dots.a")
+	if err != nil {
+		t.Fatalf("Unexpected error from SchemasByName: %s", err)
+	}
+	expected := map[string][]string{"dots.a": {"x"}, "dots": {"y", "z"}}
+	for schemaName, colNames := range expected {
+		tables := schemas[schemaName].Tables
+		if len(tables) != 1 {
+			t.Fatalf("Expected schema %s to have 1 table, instead found %d", schemaName, len(tables))
+		}
+		var actual []string
+		for _, col := range tables[0].Columns {
+			actual = append(actual, col.Name)
+		}
+		if !reflect.DeepEqual(actual, colNames) {
+			t.Errorf("Expected table %s.%s to have columns %v, instead found %v", schemaName, tables[0].Name, colNames, actual)
+		}
+		if tables[0].UnsupportedDDL {
+			t.Errorf("Table %s.%s unexpectedly has unsupported DDL", schemaName, tables[0].Name)
+		}
+	}
+}
+
 func (s TengoIntegrationSuite) TestInstanceRoutineIntrospection(t *testing.T) {
 	schema := s.GetSchema(t, "testing")
 	db, err := s.d.Connect("testing", "")
@@ -613,20 +847,28 @@ func (s TengoIntegrationSuite) TestInstanceRoutineIntrospection(t *testing.T) {
 	if actualFunc1.Equals(actualProc1) {
 		t.Error("Equals not behaving as expected, proc1 and func1 should not be equal")
 	}
+	expectParams, _ := expectProc1.Params()
+	if actualParams, err := actualProc1.Params(); err != nil || !reflect.DeepEqual(actualParams, expectParams) {
+		t.Errorf("Unexpected result from Params: %v / %v", actualParams, err)
+	}
+	if ret, err := actualFunc1.ReturnType(); err != nil || ret.TypeInDB != "float" {
+		t.Errorf("Unexpected result from ReturnType: %v / %v", ret, err)
+	}
 
 	// If this flavor supports using mysql.proc to bulk-fetch routines, confirm
 	// the result is identical to using the individual SHOW CREATE queries
 	if !s.d.Flavor().HasDataDictionary() {
-		fastResults, err := s.d.querySchemaRoutines("testing")
-		if err != nil {
-			t.Fatalf("Unexpected error from querySchemaRoutines: %s", err)
-		}
+		fastResults := schema.Routines
 		oldFlavor := s.d.Flavor()
 		s.d.ForceFlavor(FlavorMySQL80)
-		slowResults, err := s.d.querySchemaRoutines("testing")
+		slowSchema, err := s.d.Schema("testing")
 		s.d.ForceFlavor(oldFlavor)
 		if err != nil {
-			t.Fatalf("Unexpected error from querySchemaRoutines: %s", err)
+			t.Fatalf("Unexpected error from Schema: %s", err)
+		}
+		slowResults := slowSchema.Routines
+		if len(fastResults) != len(slowResults) {
+			t.Fatalf("Expected %d routines from slow path, instead found %d", len(fastResults), len(slowResults))
 		}
 		for n, r := range fastResults {
 			if !r.Equals(slowResults[n]) {
@@ -644,17 +886,86 @@ func (s TengoIntegrationSuite) TestInstanceRoutineIntrospection(t *testing.T) {
 	if actualFunc1.Equals(r) || !r.Equals(r) {
 		t.Error("Equals not behaving as expected")
 	}
-	if _, err = showCreateRoutine(db, actualProc1.Name, ObjectTypeFunc); err != sql.ErrNoRows {
+	if _, err = showCreateRoutine(context.Background(), db, "", actualProc1.Name, ObjectTypeFunc); err != sql.ErrNoRows {
 		t.Errorf("Unexpected error return from showCreateRoutine: expected sql.ErrNoRows, found %s", err)
 	}
-	if _, err = showCreateRoutine(db, actualFunc1.Name, ObjectTypeProc); err != sql.ErrNoRows {
+	if _, err = showCreateRoutine(context.Background(), db, "", actualFunc1.Name, ObjectTypeProc); err != sql.ErrNoRows {
 		t.Errorf("Unexpected error return from showCreateRoutine: expected sql.ErrNoRows, found %s", err)
 	}
-	if _, err = showCreateRoutine(db, actualFunc1.Name, ObjectTypeTable); err == nil {
+	if _, err = showCreateRoutine(context.Background(), db, "", actualFunc1.Name, ObjectTypeTable); err == nil {
 		t.Error("Expected non-nil error return from showCreateRoutine with invalid type, instead found nil")
 	}
 }
 
+func (s TengoIntegrationSuite) TestInstanceRoutineReplace(t *testing.T) {
+	db, err := s.d.Connect("testing", "")
+	if err != nil {
+		t.Fatalf("Unexpected error from Connect: %s", err)
+	}
+	mods := StatementModifiers{Flavor: s.d.Flavor(), AllowUnsafe: true}
+
+	// execDiff runs the diff between schema and a copy of it with func1 replaced
+	// by newFunc, executing each ObjectDiff's single statement in order
+	execDiff := func(schema *Schema, newFunc *Routine) error {
+		to := *schema
+		to.Routines = []*Routine{newFunc}
+		for _, r := range schema.Routines {
+			if r.Name != newFunc.Name || r.Type != newFunc.Type {
+				to.Routines = append(to.Routines, r)
+			}
+		}
+		for _, od := range NewSchemaDiff(schema, &to).ObjectDiffs() {
+			stmt, err := od.Statement(mods)
+			if err != nil {
+				t.Fatalf("Unexpected error from Statement: %s", err)
+			}
+			if stmt == "" {
+				continue
+			}
+			if _, err := db.Exec(stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	schema := s.GetSchema(t, "testing")
+	from := schema.FunctionsByName()["func1"]
+
+	// A body which fails to compile should not cause the existing routine to be
+	// dropped
+	broken := *from
+	broken.Body = "RETURN (SELECT"
+	broken.CreateStatement = from.CreateStatement[0:len(from.CreateStatement)-len(from.Body)] + broken.Body
+	if err := execDiff(schema, &broken); err == nil {
+		t.Fatal("Expected error executing replacement with broken body, but err was nil")
+	}
+	if schema = s.GetSchema(t, "testing"); !schema.FunctionsByName()["func1"].Equals(from) {
+		t.Fatal("Existing function was modified or dropped despite failed replacement")
+	}
+
+	// Leftover temporary routines from a previous failed run should not prevent
+	// a subsequent replacement
+	if !s.d.Flavor().AllowCreateOrReplace() {
+		tempCreate, _ := from.renameInCreate(from.CreateStatement, from.validationName())
+		if _, err := db.Exec(tempCreate); err != nil {
+			t.Fatalf("Unexpected error creating temporary routine: %s", err)
+		}
+	}
+
+	fixed := *from
+	fixed.Body = "return mult * 3.0"
+	fixed.CreateStatement = from.CreateStatement[0:len(from.CreateStatement)-len(from.Body)] + fixed.Body
+	if err := execDiff(schema, &fixed); err != nil {
+		t.Fatalf("Unexpected error executing replacement: %s", err)
+	}
+	schema = s.GetSchema(t, "testing")
+	if actual := schema.FunctionsByName()["func1"]; actual == nil || actual.Body != fixed.Body {
+		t.Errorf("Function was not replaced as expected: %+v", actual)
+	} else if len(schema.Routines) != 3 {
+		t.Errorf("Expected temporary routine to be dropped, instead found %d routines", len(schema.Routines))
+	}
+}
+
 func (s TengoIntegrationSuite) TestInstanceStrictModeCompliant(t *testing.T) {
 	assertCompliance := func(expected bool) {
 		t.Helper()
@@ -708,3 +1019,58 @@ func (s TengoIntegrationSuite) TestInstanceStrictModeCompliant(t *testing.T) {
 	}
 	assertCompliance(expect)
 }
+
+// BenchmarkSchemasIntrospection measures introspection performance of many
+// schemas and tables, using various worker pool sizes. It uses the first image
+// listed in the SKEEMA_TEST_IMAGES env var, and is skipped if none is set.
+func BenchmarkSchemasIntrospection(b *testing.B) {
+	images := SplitEnv("SKEEMA_TEST_IMAGES")
+	if len(images) == 0 {
+		b.Skip("SKEEMA_TEST_IMAGES env var is not set")
+	}
+	manager, err := NewDockerClient(DockerClientOptions{})
+	if err != nil {
+		b.Fatalf("Unable to create sandbox manager: %s", err)
+	}
+	suite := &TengoIntegrationSuite{manager: manager}
+	if err := suite.Setup(images[0]); err != nil {
+		b.Fatalf("Unable to set up instance: %s", err)
+	}
+	if err := suite.d.NukeData(); err != nil {
+		b.Fatalf("Unable to nuke data: %s", err)
+	}
+	defer suite.d.NukeData()
+
+	const schemaCount, tablesPerSchema = 20, 50
+	db, err := suite.d.Connect("", "")
+	if err != nil {
+		b.Fatalf("Unable to connect: %s", err)
+	}
+	table := anotherTable()
+	for n := 0; n < schemaCount; n++ {
+		schemaName := fmt.Sprintf("bench%d", n)
+		if _, err := suite.d.CreateSchema(schemaName, "", ""); err != nil {
+			b.Fatalf("Unable to create schema: %s", err)
+		}
+		for m := 0; m < tablesPerSchema; m++ {
+			create := strings.Replace(table.CreateStatement, EscapeIdentifier(table.Name), fmt.Sprintf("%s.t%d", EscapeIdentifier(schemaName), m), 1)
+			if _, err := db.Exec(create); err != nil {
+				b.Fatalf("Unable to create table: %s", err)
+			}
+		}
+	}
+
+	for _, workers := range []int{1, 4, 10, 20} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			opts := IntrospectionOptions{Workers: workers}
+			for n := 0; n < b.N; n++ {
+				schemas, err := suite.d.SchemasWithOptions(context.Background(), opts)
+				if err != nil {
+					b.Fatalf("Unexpected error from SchemasWithOptions: %s", err)
+				} else if len(schemas) != schemaCount {
+					b.Fatalf("Expected %d schemas, instead found %d", schemaCount, len(schemas))
+				}
+			}
+		})
+	}
+}
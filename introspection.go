@@ -0,0 +1,175 @@
+package tengo
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// introspectionBatchSize is the maximum number of schemas included in a single
+// batch of information_schema queries.
+const introspectionBatchSize = 100
+
+// defaultIntrospectionWorkers is the number of concurrent SHOW CREATE queries
+// used if IntrospectionOptions.Workers is not set.
+const defaultIntrospectionWorkers = 10
+
+// IntrospectionOptions controls the behavior of Instance.SchemasWithOptions.
+type IntrospectionOptions struct {
+	// Workers is the maximum number of concurrent SHOW CREATE queries. If zero
+	// or negative, a default of 10 is used.
+	Workers int
+
+	// Progress, if non-nil, is called once per table or routine as soon as it
+	// has been fully introspected. Calls are serialized, but may come from
+	// goroutines other than the caller's, and in any order.
+	Progress func(IntrospectionProgress)
+}
+
+// IntrospectionProgress describes the progress of an introspection operation.
+type IntrospectionProgress struct {
+	SchemaName string    // schema containing the object that was just introspected
+	Object     ObjectKey // object that was just introspected
+	Completed  int       // number of objects introspected so far, including this one
+	Total      int       // total number of objects being introspected
+}
+
+// querySchemaObjects populates the Tables and Routines fields of each of the
+// supplied schemas, using information_schema queries batched across multiple
+// schemas. The CREATE statements of the objects are not hydrated.
+func (instance *Instance) querySchemaObjects(ctx context.Context, schemas []*Schema) error {
+	for start := 0; start < len(schemas); start += introspectionBatchSize {
+		end := start + introspectionBatchSize
+		if end > len(schemas) {
+			end = len(schemas)
+		}
+		names := make([]string, 0, end-start)
+		for _, s := range schemas[start:end] {
+			names = append(names, s.Name)
+		}
+		tablesBySchema, err := instance.querySchemaTables(ctx, names)
+		if err != nil {
+			return err
+		}
+		routinesBySchema, err := instance.querySchemaRoutines(ctx, names...)
+		if err != nil {
+			return err
+		}
+		for _, s := range schemas[start:end] {
+			if s.Tables = tablesBySchema[s.Name]; s.Tables == nil {
+				s.Tables = []*Table{}
+			}
+			if s.Routines = routinesBySchema[s.Name]; s.Routines == nil {
+				s.Routines = []*Routine{}
+			}
+		}
+	}
+	return nil
+}
+
+// introspectionJob is a single table or routine whose CREATE statement must
+// be obtained.
+type introspectionJob struct {
+	schema  string
+	table   *Table
+	routine *Routine
+}
+
+// hydrateCreateStatements obtains the CREATE statement of every table and
+// routine in schemas, using a bounded pool of worker goroutines. Routines that
+// already have a CreateStatement are skipped, but still counted for progress
+// reporting purposes. If any query fails or ctx is canceled, remaining work is
+// abandoned and an error is returned.
+func (instance *Instance) hydrateCreateStatements(ctx context.Context, schemas []*Schema, opts IntrospectionOptions) error {
+	var jobs []introspectionJob
+	var total int
+	for _, s := range schemas {
+		total += len(s.Tables) + len(s.Routines)
+		for _, t := range s.Tables {
+			jobs = append(jobs, introspectionJob{schema: s.Name, table: t})
+		}
+		for _, r := range s.Routines {
+			if r.CreateStatement == "" {
+				jobs = append(jobs, introspectionJob{schema: s.Name, routine: r})
+			}
+		}
+	}
+
+	var progressLock sync.Mutex
+	var completed int
+	report := func(schema string, key ObjectKey) {
+		if opts.Progress == nil {
+			return
+		}
+		progressLock.Lock()
+		defer progressLock.Unlock()
+		completed++
+		opts.Progress(IntrospectionProgress{
+			SchemaName: schema,
+			Object:     key,
+			Completed:  completed,
+			Total:      total,
+		})
+	}
+	for _, s := range schemas {
+		for _, r := range s.Routines {
+			if r.CreateStatement != "" { // already hydrated from mysql.proc
+				report(s.Name, ObjectKey{Type: r.Type, Name: r.Name})
+			}
+		}
+	}
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	// All SHOW CREATE queries use fully-qualified names, permitting a single
+	// connection pool to be shared across all schemas
+	db, err := instance.ConnectContext(ctx, "", "")
+	if err != nil {
+		return err
+	}
+	flavor := instance.flavorContext(ctx)
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = defaultIntrospectionWorkers
+	}
+	if workers > len(jobs) {
+		workers = len(jobs)
+	}
+	defer db.SetMaxOpenConns(0)
+	db.SetMaxOpenConns(workers)
+
+	g, workCtx := errgroup.WithContext(ctx)
+	jobChan := make(chan introspectionJob)
+	g.Go(func() error {
+		defer close(jobChan)
+		for _, job := range jobs {
+			select {
+			case jobChan <- job:
+			case <-workCtx.Done():
+				return workCtx.Err()
+			}
+		}
+		return nil
+	})
+	for n := 0; n < workers; n++ {
+		g.Go(func() error {
+			for job := range jobChan {
+				if job.table != nil {
+					if err := hydrateTable(workCtx, db, job.schema, job.table, flavor); err != nil {
+						return err
+					}
+					report(job.schema, ObjectKey{Type: ObjectTypeTable, Name: job.table.Name})
+				} else {
+					if err := hydrateRoutine(workCtx, db, job.schema, job.routine, flavor); err != nil {
+						return err
+					}
+					report(job.schema, ObjectKey{Type: job.routine.Type, Name: job.routine.Name})
+				}
+			}
+			return nil
+		})
+	}
+	return g.Wait()
+}
@@ -0,0 +1,259 @@
+// Package lint checks tengo schema models against a configurable set of rules,
+// in order to enforce schema design standards. A number of rules are built-in,
+// and additional rules may be registered by callers.
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/skeema/tengo"
+)
+
+// Severity indicates how seriously a rule violation should be treated.
+type Severity int
+
+// Constants enumerating valid severity levels. SeverityIgnore disables a rule
+// entirely.
+const (
+	SeverityIgnore Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+func (sev Severity) String() string {
+	switch sev {
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return "ignore"
+	}
+}
+
+// ParseSeverity converts a string such as "warning" or "error" into a
+// Severity. An error is returned if the string is not recognized.
+func ParseSeverity(value string) (Severity, error) {
+	switch strings.ToLower(value) {
+	case "ignore", "":
+		return SeverityIgnore, nil
+	case "warning", "warn":
+		return SeverityWarning, nil
+	case "error":
+		return SeverityError, nil
+	}
+	return SeverityIgnore, fmt.Errorf("Unknown lint severity \"%s\"", value)
+}
+
+// Options controls which rules are run, their severities, and any rule-specific
+// settings.
+type Options struct {
+	RuleSeverity    map[string]Severity // overrides Rule.DefaultSeverity; SeverityIgnore disables a rule
+	AllowedEngines  []string            // storage engines permitted by the engine rule; defaults to InnoDB
+	AllowedCharSets []string            // character sets permitted by the charset rule; defaults to utf8mb4
+	AllowedDefiners []string            // routine definers permitted by the definer rule; if empty, rule is skipped
+	Flavor          tengo.Flavor        // target server flavor, if known; used by the zero-date rule to report sql_mode incompatibility
+}
+
+// Severity returns the effective severity for the supplied rule.
+func (opts Options) Severity(rule *Rule) Severity {
+	if sev, ok := opts.RuleSeverity[rule.Name]; ok {
+		return sev
+	}
+	return rule.DefaultSeverity
+}
+
+// Annotation is a single finding produced by a rule.
+type Annotation struct {
+	RuleName   string
+	Severity   Severity
+	SchemaName string
+	ObjectKey  tengo.ObjectKey
+	Subject    string // sub-object such as a column or index; blank if the finding is about the object itself
+	Message    string
+}
+
+func (a *Annotation) String() string {
+	location := a.ObjectKey.String()
+	if a.Subject != "" {
+		location = fmt.Sprintf("%s %s", location, a.Subject)
+	}
+	return fmt.Sprintf("[%s] %s: %s (%s)", a.Severity, location, a.Message, a.RuleName)
+}
+
+// Result is the set of annotations produced by linting one or more schemas.
+type Result struct {
+	Annotations []*Annotation
+}
+
+// Merge appends the annotations from other into r.
+func (r *Result) Merge(other *Result) {
+	if other != nil {
+		r.Annotations = append(r.Annotations, other.Annotations...)
+	}
+}
+
+// ErrorCount returns the number of annotations with SeverityError.
+func (r *Result) ErrorCount() int {
+	return r.countSeverity(SeverityError)
+}
+
+// WarningCount returns the number of annotations with SeverityWarning.
+func (r *Result) WarningCount() int {
+	return r.countSeverity(SeverityWarning)
+}
+
+func (r *Result) countSeverity(sev Severity) (count int) {
+	for _, a := range r.Annotations {
+		if a.Severity == sev {
+			count++
+		}
+	}
+	return count
+}
+
+// Sort orders the annotations by schema name, object type, object name, rule
+// name, and subject.
+func (r *Result) Sort() {
+	sort.SliceStable(r.Annotations, func(i, j int) bool {
+		a, b := r.Annotations[i], r.Annotations[j]
+		if a.SchemaName != b.SchemaName {
+			return a.SchemaName < b.SchemaName
+		} else if a.ObjectKey.Type != b.ObjectKey.Type {
+			return a.ObjectKey.Type > b.ObjectKey.Type // tables before routines
+		} else if a.ObjectKey.Name != b.ObjectKey.Name {
+			return a.ObjectKey.Name < b.ObjectKey.Name
+		} else if a.RuleName != b.RuleName {
+			return a.RuleName < b.RuleName
+		}
+		return a.Subject < b.Subject
+	})
+}
+
+// Rule represents a single lint check. A rule may supply any combination of
+// checker functions; each one is called for every object of its type. Checker
+// functions return a blank string if the object is acceptable, or a message
+// describing the problem otherwise.
+type Rule struct {
+	Name            string
+	Description     string
+	DefaultSeverity Severity
+	CheckTable      func(table *tengo.Table, schema *tengo.Schema, opts Options) string
+	CheckColumn     func(col *tengo.Column, table *tengo.Table, opts Options) string
+	CheckIndex      func(idx *tengo.Index, table *tengo.Table, opts Options) string
+	CheckForeignKey func(fk *tengo.ForeignKey, table *tengo.Table, opts Options) string
+	CheckRoutine    func(routine *tengo.Routine, schema *tengo.Schema, opts Options) string
+}
+
+var (
+	rulesByName = make(map[string]*Rule)
+	rulesMutex  sync.RWMutex
+)
+
+// RegisterRule adds a rule to the set run by Lint. An error is returned if
+// the rule has no name, has no checker functions, or if another rule with the
+// same name has already been registered.
+func RegisterRule(rule Rule) error {
+	if rule.Name == "" {
+		return fmt.Errorf("RegisterRule: rule name cannot be blank")
+	}
+	if rule.CheckTable == nil && rule.CheckColumn == nil && rule.CheckIndex == nil && rule.CheckForeignKey == nil && rule.CheckRoutine == nil {
+		return fmt.Errorf("RegisterRule: rule %s has no checker functions", rule.Name)
+	}
+	rulesMutex.Lock()
+	defer rulesMutex.Unlock()
+	if _, already := rulesByName[rule.Name]; already {
+		return fmt.Errorf("RegisterRule: rule %s is already registered", rule.Name)
+	}
+	rulesByName[rule.Name] = &rule
+	return nil
+}
+
+// Rules returns all registered rules, sorted by name.
+func Rules() []*Rule {
+	rulesMutex.RLock()
+	defer rulesMutex.RUnlock()
+	result := make([]*Rule, 0, len(rulesByName))
+	for _, rule := range rulesByName {
+		result = append(result, rule)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// Lint runs all registered rules against the supplied schema, returning a
+// sorted Result.
+func Lint(schema *tengo.Schema, opts Options) *Result {
+	result := &Result{}
+	if schema == nil {
+		return result
+	}
+	for _, rule := range Rules() {
+		sev := opts.Severity(rule)
+		if sev == SeverityIgnore {
+			continue
+		}
+		note := func(key tengo.ObjectKey, subject, message string) {
+			if message == "" {
+				return
+			}
+			result.Annotations = append(result.Annotations, &Annotation{
+				RuleName:   rule.Name,
+				Severity:   sev,
+				SchemaName: schema.Name,
+				ObjectKey:  key,
+				Subject:    subject,
+				Message:    message,
+			})
+		}
+		for _, table := range schema.Tables {
+			key := tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: table.Name}
+			if rule.CheckTable != nil {
+				note(key, "", rule.CheckTable(table, schema, opts))
+			}
+			if rule.CheckColumn != nil {
+				for _, col := range table.Columns {
+					note(key, "column "+tengo.EscapeIdentifier(col.Name), rule.CheckColumn(col, table, opts))
+				}
+			}
+			if rule.CheckIndex != nil {
+				indexes := table.SecondaryIndexes
+				if table.PrimaryKey != nil {
+					indexes = append([]*tengo.Index{table.PrimaryKey}, indexes...)
+				}
+				for _, idx := range indexes {
+					note(key, "index "+tengo.EscapeIdentifier(idx.Name), rule.CheckIndex(idx, table, opts))
+				}
+			}
+			if rule.CheckForeignKey != nil {
+				for _, fk := range table.ForeignKeys {
+					note(key, "foreign key "+tengo.EscapeIdentifier(fk.Name), rule.CheckForeignKey(fk, table, opts))
+				}
+			}
+		}
+		if rule.CheckRoutine != nil {
+			for _, routine := range schema.Routines {
+				key := tengo.ObjectKey{Type: routine.Type, Name: routine.Name}
+				note(key, "", rule.CheckRoutine(routine, schema, opts))
+			}
+		}
+	}
+	result.Sort()
+	return result
+}
+
+// LintSchemas runs Lint on each of the supplied schemas, returning a single
+// combined Result.
+func LintSchemas(schemas []*tengo.Schema, opts Options) *Result {
+	result := &Result{}
+	for _, schema := range schemas {
+		result.Merge(Lint(schema, opts))
+	}
+	result.Sort()
+	return result
+}
@@ -0,0 +1,192 @@
+package lint
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/skeema/tengo"
+)
+
+func TestLintBuiltinRules(t *testing.T) {
+	schema := lintSchema()
+	result := Lint(schema, Options{AllowedDefiners: []string{"app@%"}})
+
+	expected := map[string]int{
+		"pk":         1,
+		"engine":     1,
+		"float-type": 2,
+		"zero-date":  1,
+		"definer":    1,
+		"charset":    2,
+	}
+	actual := make(map[string]int)
+	for _, a := range result.Annotations {
+		actual[a.RuleName]++
+		if a.SchemaName != "lintme" {
+			t.Errorf("Unexpected schema name in annotation %s", a)
+		}
+	}
+	for ruleName, count := range expected {
+		if actual[ruleName] != count {
+			t.Errorf("Expected %d annotations for rule %s, instead found %d", count, ruleName, actual[ruleName])
+		}
+	}
+	if result.ErrorCount() != 1 || result.WarningCount() != len(result.Annotations)-1 {
+		t.Errorf("Unexpected error/warning counts: %d / %d", result.ErrorCount(), result.WarningCount())
+	}
+
+	// Confirm sort order: tables first, alphabetically, then routines
+	if first := result.Annotations[0]; first.ObjectKey.Name != "legacy" {
+		t.Errorf("Unexpected first annotation %s", first)
+	}
+	if last := result.Annotations[len(result.Annotations)-1]; last.ObjectKey.Type != tengo.ObjectTypeProc {
+		t.Errorf("Unexpected last annotation %s", last)
+	}
+}
+
+func TestLintOptions(t *testing.T) {
+	schema := lintSchema()
+	opts := Options{
+		RuleSeverity:    map[string]Severity{"float-type": SeverityIgnore, "pk": SeverityError},
+		AllowedEngines:  []string{"innodb", "MyISAM"},
+		AllowedCharSets: []string{"utf8mb4", "latin1"},
+	}
+	result := Lint(schema, opts)
+	for _, a := range result.Annotations {
+		switch a.RuleName {
+		case "float-type", "engine", "charset", "definer":
+			t.Errorf("Expected rule %s to be suppressed by options, but found %s", a.RuleName, a)
+		case "pk":
+			if a.Severity != SeverityError {
+				t.Errorf("Expected severity override to apply to %s", a)
+			}
+		}
+	}
+}
+
+func TestLintZeroDateFlavor(t *testing.T) {
+	schema := lintSchema()
+	for _, flavor := range []tengo.Flavor{tengo.FlavorUnknown, tengo.FlavorMySQL56, tengo.FlavorMySQL57, tengo.FlavorMariaDB103} {
+		result := Lint(schema, Options{Flavor: flavor})
+		for _, a := range result.Annotations {
+			if a.RuleName != "zero-date" {
+				continue
+			}
+			expectMention := (flavor == tengo.FlavorMySQL57)
+			if mentioned := strings.Contains(a.Message, "sql_mode of "+flavor.String()); mentioned != expectMention {
+				t.Errorf("Flavor %s: unexpected zero-date message %q", flavor, a.Message)
+			}
+		}
+	}
+}
+
+func TestRegisterRule(t *testing.T) {
+	if err := RegisterRule(Rule{Name: "pk", CheckTable: checkPrimaryKey}); err == nil {
+		t.Error("Expected error registering duplicate rule name, but err is nil")
+	}
+	if err := RegisterRule(Rule{Name: "no-checkers"}); err == nil {
+		t.Error("Expected error registering rule without checkers, but err is nil")
+	}
+	rule := Rule{
+		Name:            "test-comment",
+		DefaultSeverity: SeverityWarning,
+		CheckTable: func(table *tengo.Table, _ *tengo.Schema, _ Options) string {
+			if table.Comment == "" {
+				return "Table has no comment"
+			}
+			return ""
+		},
+	}
+	if err := RegisterRule(rule); err != nil {
+		t.Fatalf("Unexpected error from RegisterRule: %s", err)
+	}
+	defer func() {
+		rulesMutex.Lock()
+		delete(rulesByName, rule.Name)
+		rulesMutex.Unlock()
+	}()
+	result := Lint(lintSchema(), Options{})
+	var count int
+	for _, a := range result.Annotations {
+		if a.RuleName == rule.Name {
+			count++
+		}
+	}
+	if count != 2 {
+		t.Errorf("Expected custom rule to generate 2 annotations, instead found %d", count)
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	cases := map[string]Severity{
+		"":        SeverityIgnore,
+		"ignore":  SeverityIgnore,
+		"WARNING": SeverityWarning,
+		"warn":    SeverityWarning,
+		"error":   SeverityError,
+	}
+	for input, expected := range cases {
+		if actual, err := ParseSeverity(input); err != nil || actual != expected {
+			t.Errorf("Expected ParseSeverity(%q) to return %s, nil; instead found %s, %v", input, expected, actual, err)
+		}
+	}
+	if _, err := ParseSeverity("fatal"); err == nil {
+		t.Error("Expected error from ParseSeverity with invalid input, but err is nil")
+	}
+}
+
+func TestAnnotationString(t *testing.T) {
+	a := &Annotation{
+		RuleName:  "float-type",
+		Severity:  SeverityWarning,
+		ObjectKey: tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: "prices"},
+		Subject:   "column `amount`",
+		Message:   "Column uses imprecise type float",
+	}
+	if str := a.String(); !strings.HasPrefix(str, "[warning] table `prices` column `amount`: ") {
+		t.Errorf("Unexpected result from Annotation.String(): %s", str)
+	}
+}
+
+func lintSchema() *tengo.Schema {
+	idCol := &tengo.Column{Name: "id", TypeInDB: "int(10) unsigned", AutoIncrement: true, Default: tengo.ColumnDefaultNull}
+	prices := &tengo.Table{
+		Name:      "prices",
+		Engine:    "InnoDB",
+		CharSet:   "utf8mb4",
+		Collation: "utf8mb4_general_ci",
+		Columns: []*tengo.Column{
+			idCol,
+			{Name: "amount", TypeInDB: "float(10,2)", Default: tengo.ColumnDefaultNull},
+			{Name: "ratio", TypeInDB: "double", Nullable: true, Default: tengo.ColumnDefaultNull},
+			{Name: "created", TypeInDB: "datetime", Default: tengo.ColumnDefaultValue("0000-00-00 00:00:00")},
+			{Name: "code", TypeInDB: "char(3)", CharSet: "latin1", Collation: "latin1_swedish_ci", Default: tengo.ColumnDefaultNull},
+		},
+		PrimaryKey: &tengo.Index{
+			Name:       "PRIMARY",
+			Columns:    []*tengo.Column{idCol},
+			SubParts:   []uint16{0},
+			PrimaryKey: true,
+			Unique:     true,
+		},
+	}
+	legacy := &tengo.Table{
+		Name:      "legacy",
+		Engine:    "MyISAM",
+		CharSet:   "latin1",
+		Collation: "latin1_swedish_ci",
+		Columns: []*tengo.Column{
+			{Name: "name", TypeInDB: "varchar(20)", CharSet: "latin1", Collation: "latin1_swedish_ci", Default: tengo.ColumnDefaultNull},
+		},
+	}
+	proc := &tengo.Routine{
+		Name:    "proc1",
+		Type:    tengo.ObjectTypeProc,
+		Definer: "root@localhost",
+	}
+	return &tengo.Schema{
+		Name:     "lintme",
+		Tables:   []*tengo.Table{prices, legacy},
+		Routines: []*tengo.Routine{proc},
+	}
+}
@@ -0,0 +1,162 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/skeema/tengo"
+)
+
+// This file contains the built-in rules, which are registered automatically.
+
+func init() {
+	builtins := []Rule{
+		{
+			Name:            "pk",
+			Description:     "Flag tables lacking a primary key",
+			DefaultSeverity: SeverityWarning,
+			CheckTable:      checkPrimaryKey,
+		},
+		{
+			Name:            "engine",
+			Description:     "Flag tables using a storage engine other than those allowed",
+			DefaultSeverity: SeverityWarning,
+			CheckTable:      checkEngine,
+		},
+		{
+			Name:            "float-type",
+			Description:     "Flag columns using imprecise FLOAT or DOUBLE types",
+			DefaultSeverity: SeverityWarning,
+			CheckColumn:     checkFloatType,
+		},
+		{
+			Name:            "zero-date",
+			Description:     "Flag date and time columns with zero-date default values",
+			DefaultSeverity: SeverityWarning,
+			CheckColumn:     checkZeroDate,
+		},
+		{
+			Name:            "definer",
+			Description:     "Flag routines with a definer other than those allowed",
+			DefaultSeverity: SeverityError,
+			CheckRoutine:    checkDefiner,
+		},
+		{
+			Name:            "charset",
+			Description:     "Flag tables or columns using a character set other than those allowed",
+			DefaultSeverity: SeverityWarning,
+			CheckTable:      checkTableCharSet,
+			CheckColumn:     checkColumnCharSet,
+		},
+	}
+	for _, rule := range builtins {
+		if err := RegisterRule(rule); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func checkPrimaryKey(table *tengo.Table, _ *tengo.Schema, _ Options) string {
+	if table.PrimaryKey != nil {
+		return ""
+	}
+	return "Table does not have a primary key"
+}
+
+func checkEngine(table *tengo.Table, _ *tengo.Schema, opts Options) string {
+	allowed := opts.AllowedEngines
+	if len(allowed) == 0 {
+		allowed = []string{"InnoDB"}
+	}
+	if containsFold(allowed, table.Engine) {
+		return ""
+	}
+	return fmt.Sprintf("Table uses storage engine %s; allowed engines: %s", table.Engine, strings.Join(allowed, ", "))
+}
+
+func checkFloatType(col *tengo.Column, _ *tengo.Table, _ Options) string {
+	typ := strings.ToLower(col.TypeInDB)
+	if strings.HasPrefix(typ, "float") || strings.HasPrefix(typ, "double") || strings.HasPrefix(typ, "real") {
+		return fmt.Sprintf("Column uses imprecise type %s; consider DECIMAL instead", col.TypeInDB)
+	}
+	return ""
+}
+
+func checkZeroDate(col *tengo.Column, _ *tengo.Table, opts Options) string {
+	typ := strings.ToLower(col.TypeInDB)
+	if !strings.HasPrefix(typ, "date") && !strings.HasPrefix(typ, "timestamp") {
+		return ""
+	}
+	if col.Default.Null || !strings.HasPrefix(col.Default.Value, "0000-00-00") {
+		return ""
+	}
+	message := fmt.Sprintf("Column has zero-date default value '%s'", col.Default.Value)
+	// MySQL 5.7+ enables strict mode and NO_ZERO_DATE by default, so the column
+	// cannot be created on such servers unless sql_mode has been relaxed
+	if opts.Flavor.Known() && opts.Flavor.MySQLishMinVersion(5, 7) {
+		message += fmt.Sprintf(", which is rejected by the default sql_mode of %s", opts.Flavor)
+	}
+	return message
+}
+
+func checkDefiner(routine *tengo.Routine, _ *tengo.Schema, opts Options) string {
+	if len(opts.AllowedDefiners) == 0 {
+		return ""
+	}
+	for _, definer := range opts.AllowedDefiners {
+		if definerMatches(definer, routine.Definer) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("Routine has definer %s; allowed definers: %s", routine.Definer, strings.Join(opts.AllowedDefiners, ", "))
+}
+
+// definerMatches compares a user@host definer against a pattern, which may use
+// % as a wildcard for the host portion.
+func definerMatches(pattern, definer string) bool {
+	if pattern == definer {
+		return true
+	}
+	patternAt, definerAt := strings.LastIndex(pattern, "@"), strings.LastIndex(definer, "@")
+	if patternAt < 0 || definerAt < 0 {
+		return false
+	}
+	return pattern[:patternAt] == definer[:definerAt] && pattern[patternAt+1:] == "%"
+}
+
+func allowedCharSets(opts Options) []string {
+	if len(opts.AllowedCharSets) == 0 {
+		return []string{"utf8mb4"}
+	}
+	return opts.AllowedCharSets
+}
+
+func checkTableCharSet(table *tengo.Table, _ *tengo.Schema, opts Options) string {
+	allowed := allowedCharSets(opts)
+	if table.CharSet == "" || containsFold(allowed, table.CharSet) {
+		return ""
+	}
+	return fmt.Sprintf("Table has default character set %s; allowed character sets: %s", table.CharSet, strings.Join(allowed, ", "))
+}
+
+func checkColumnCharSet(col *tengo.Column, table *tengo.Table, opts Options) string {
+	// Columns matching the table's default are already reported at the table
+	// level, so avoid redundant annotations for them
+	if col.CharSet == "" || col.CharSet == table.CharSet {
+		return ""
+	}
+	allowed := allowedCharSets(opts)
+	if containsFold(allowed, col.CharSet) {
+		return ""
+	}
+	return fmt.Sprintf("Column uses character set %s; allowed character sets: %s", col.CharSet, strings.Join(allowed, ", "))
+}
+
+func containsFold(haystack []string, needle string) bool {
+	for _, s := range haystack {
+		if strings.EqualFold(s, needle) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package tengo
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// MigrationLayout enumerates file naming schemes used by versioned migration
+// tools.
+type MigrationLayout int
+
+// Constants enumerating supported migration file layouts.
+const (
+	MigrationLayoutGolangMigrate MigrationLayout = iota // NNN_name.up.sql and NNN_name.down.sql
+	MigrationLayoutFlyway                               // VN__name.sql and UN__name.sql
+)
+
+// FileNames returns the base names of the up and down migration files for the
+// supplied version number and name.
+func (ml MigrationLayout) FileNames(version uint64, name string) (up, down string) {
+	name = migrationFileName(name)
+	switch ml {
+	case MigrationLayoutFlyway:
+		return fmt.Sprintf("V%d__%s.sql", version, name), fmt.Sprintf("U%d__%s.sql", version, name)
+	default:
+		return fmt.Sprintf("%03d_%s.up.sql", version, name), fmt.Sprintf("%03d_%s.down.sql", version, name)
+	}
+}
+
+var migrationNameInvalidChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// migrationFileName converts a free-form migration name into a form suitable
+// for use in a file name.
+func migrationFileName(name string) string {
+	name = migrationNameInvalidChars.ReplaceAllString(strings.ToLower(name), "_")
+	name = strings.Trim(name, "_")
+	if name == "" {
+		name = "migration"
+	}
+	return name
+}
+
+// Migration represents a versioned pair of up and down migration scripts.
+type Migration struct {
+	Version uint64
+	Name    string
+	Up      string // SQL to transform the source schema into the target schema
+	Down    string // SQL to transform the target schema back into the source schema
+}
+
+// NewMigration returns a Migration which transforms schema from into schema
+// to. The up script is generated from a SchemaDiff of from to to; the down
+// script is generated from a SchemaDiff in the reverse direction. Either
+// schema may be nil, to represent a schema that does not exist.
+//
+// Statements which are not permitted by mods (for example, DROP TABLE when
+// mods.AllowUnsafe is false) are still included in the scripts, but commented
+// out. An error is returned if any object cannot be diff'ed due to use of
+// unsupported features. Each script begins with a header comment recording
+// mods.Flavor as well as snapshot digests of the source and target schemas.
+func NewMigration(from, to *Schema, version uint64, name string, mods StatementModifiers) (*Migration, error) {
+	m := &Migration{
+		Version: version,
+		Name:    name,
+	}
+	var err error
+	if m.Up, err = migrationScript(NewSchemaDiff(from, to), mods); err != nil {
+		return nil, err
+	}
+	if m.Down, err = migrationScript(NewSchemaDiff(to, from), mods); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// migrationScript returns the full text of a migration script for the diff.
+func migrationScript(diff *SchemaDiff, mods StatementModifiers) (string, error) {
+	var b bytes.Buffer
+	b.WriteString("-- Generated by tengo\n")
+	fmt.Fprintf(&b, "-- Flavor: %s\n", mods.Flavor)
+	fmt.Fprintf(&b, "-- Source: %s\n", migrationSnapshot(diff.FromSchema))
+	fmt.Fprintf(&b, "-- Target: %s\n", migrationSnapshot(diff.ToSchema))
+
+	var statementCount int
+	for _, od := range diff.ObjectDiffs() {
+		stmt, err := od.Statement(mods)
+		if IsForbiddenDiff(err) {
+			fmt.Fprintf(&b, "\n-- Skipped %s: %s\n", od.ObjectKey(), err)
+			for _, line := range strings.Split(stmt, "\n") {
+				fmt.Fprintf(&b, "-- %s\n", line)
+			}
+			continue
+		} else if err != nil {
+			return "", err
+		} else if stmt == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "\n%s;\n", stmt)
+		statementCount++
+	}
+	if statementCount == 0 {
+		b.WriteString("\n-- No changes\n")
+	}
+	return b.String(), nil
+}
+
+// migrationSnapshot returns a description of a schema, including its
+// fingerprint, for use in migration script headers.
+func migrationSnapshot(s *Schema) string {
+	if s == nil {
+		return "(none)"
+	}
+	return fmt.Sprintf("%s (%d tables, %d routines, sha256:%s)", EscapeIdentifier(s.Name), len(s.Tables), len(s.Routines), s.Fingerprint(FingerprintOptions{})[:16])
+}
+
+// WriteFiles writes the migration's up and down scripts to files in dir, using
+// the naming scheme of the supplied layout. It returns the paths of the files
+// written. An error is returned if either file already exists.
+func (m *Migration) WriteFiles(dir string, layout MigrationLayout) (upPath, downPath string, err error) {
+	upName, downName := layout.FileNames(m.Version, m.Name)
+	upPath, downPath = filepath.Join(dir, upName), filepath.Join(dir, downName)
+	if err = writeNewFile(upPath, m.Up); err != nil {
+		return "", "", err
+	}
+	if err = writeNewFile(downPath, m.Down); err != nil {
+		os.Remove(upPath)
+		return "", "", err
+	}
+	return upPath, downPath, nil
+}
+
+// writeNewFile writes contents to a new file at path, returning an error if a
+// file already exists there.
+func writeNewFile(path, contents string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+	if err != nil {
+		return err
+	}
+	if _, err = f.WriteString(contents); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,112 @@
+package tengo
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrationLayoutFileNames(t *testing.T) {
+	cases := []struct {
+		layout   MigrationLayout
+		version  uint64
+		name     string
+		expectUp string
+		expectDn string
+	}{
+		{MigrationLayoutGolangMigrate, 7, "Add users table", "007_add_users_table.up.sql", "007_add_users_table.down.sql"},
+		{MigrationLayoutGolangMigrate, 20190101120000, "x", "20190101120000_x.up.sql", "20190101120000_x.down.sql"},
+		{MigrationLayoutFlyway, 3, "drop old-stuff!", "V3__drop_old_stuff.sql", "U3__drop_old_stuff.sql"},
+		{MigrationLayoutFlyway, 4, "***", "V4__migration.sql", "U4__migration.sql"},
+	}
+	for _, c := range cases {
+		up, down := c.layout.FileNames(c.version, c.name)
+		if up != c.expectUp || down != c.expectDn {
+			t.Errorf("Expected FileNames(%d, %q) to return %q, %q; instead found %q, %q", c.version, c.name, c.expectUp, c.expectDn, up, down)
+		}
+	}
+}
+
+func TestNewMigration(t *testing.T) {
+	t1, t2 := aTable(1), anotherTable()
+	from := aSchema("s1", &t1)
+	to := aSchema("s1", &t1, &t2)
+	mods := StatementModifiers{Flavor: FlavorMySQL57}
+
+	m, err := NewMigration(&from, &to, 2, "add actor_in_film", mods)
+	if err != nil {
+		t.Fatalf("Unexpected error from NewMigration: %s", err)
+	}
+	for _, expected := range []string{"-- Flavor: mysql:5.7\n", "-- Source: `s1` (1 tables, 0 routines, sha256:", "-- Target: `s1` (2 tables, 0 routines, sha256:"} {
+		if !strings.Contains(m.Up, expected) {
+			t.Errorf("Expected up script to contain %q, but it did not:\n%s", expected, m.Up)
+		}
+	}
+	if !strings.Contains(m.Up, "\n"+t2.CreateStatement+";\n") {
+		t.Errorf("Expected up script to contain CREATE TABLE, but it did not:\n%s", m.Up)
+	}
+
+	// Down script drops the table, which is unsafe
+	if strings.Contains(m.Down, "\nDROP TABLE") || !strings.Contains(m.Down, "-- Skipped table `actor_in_film`: DROP TABLE not permitted\n-- DROP TABLE `actor_in_film`\n") {
+		t.Errorf("Expected down script to contain commented-out DROP TABLE, but it did not:\n%s", m.Down)
+	}
+	mods.AllowUnsafe = true
+	if m, err = NewMigration(&from, &to, 2, "add actor_in_film", mods); err != nil {
+		t.Fatalf("Unexpected error from NewMigration: %s", err)
+	} else if !strings.Contains(m.Down, "\nDROP TABLE `actor_in_film`;\n") {
+		t.Errorf("Expected down script to contain DROP TABLE, but it did not:\n%s", m.Down)
+	}
+
+	// Snapshot digests should be independent of next auto-increment values
+	t1Copy := aTable(123)
+	fromCopy := aSchema("s1", &t1Copy)
+	if migrationSnapshot(&from) != migrationSnapshot(&fromCopy) {
+		t.Error("Expected snapshot to ignore next auto-increment value")
+	}
+	if migrationSnapshot(&from) == migrationSnapshot(&to) || migrationSnapshot(nil) != "(none)" {
+		t.Error("Unexpected result from migrationSnapshot")
+	}
+
+	// No changes
+	m, err = NewMigration(&from, &from, 3, "noop", mods)
+	if err != nil || !strings.HasSuffix(m.Up, "\n-- No changes\n") || !strings.HasSuffix(m.Down, "\n-- No changes\n") {
+		t.Errorf("Unexpected result from no-op NewMigration: %+v, %v", m, err)
+	}
+
+	// Unsupported tables return an error
+	t3 := unsupportedTable()
+	t3Mod := unsupportedTable()
+	t3Mod.CreateStatement += " COMMENT='changed'"
+	t3Mod.Comment = "changed"
+	unsupportedFrom, unsupportedTo := aSchema("s1", &t3), aSchema("s1", &t3Mod)
+	if _, err := NewMigration(&unsupportedFrom, &unsupportedTo, 4, "bad", mods); !IsUnsupportedDiff(err) {
+		t.Errorf("Expected unsupported diff error, instead found %v", err)
+	}
+}
+
+func TestMigrationWriteFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tengo-migration")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	m := &Migration{Version: 1, Name: "init", Up: "CREATE TABLE foo (id int);\n", Down: "DROP TABLE foo;\n"}
+	upPath, downPath, err := m.WriteFiles(dir, MigrationLayoutFlyway)
+	if err != nil {
+		t.Fatalf("Unexpected error from WriteFiles: %s", err)
+	}
+	if upPath != filepath.Join(dir, "V1__init.sql") || downPath != filepath.Join(dir, "U1__init.sql") {
+		t.Errorf("Unexpected paths from WriteFiles: %s, %s", upPath, downPath)
+	}
+	if contents, err := ioutil.ReadFile(downPath); err != nil || string(contents) != m.Down {
+		t.Errorf("Unexpected contents of %s: %q, %v", downPath, contents, err)
+	}
+
+	// Existing files should not be overwritten
+	if _, _, err := m.WriteFiles(dir, MigrationLayoutFlyway); err == nil {
+		t.Error("Expected error from WriteFiles when files already exist, but err was nil")
+	}
+}
@@ -0,0 +1,294 @@
+package tengo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RedundancyType enumerates reasons why an index may be considered redundant.
+type RedundancyType int
+
+// Constants representing the types of index redundancy.
+const (
+	RedundancyDuplicate     RedundancyType = iota // same columns and sub-parts as another index
+	RedundancyLeftPrefix                          // columns are a left prefix of another index
+	RedundancyClusteredKey                        // columns are a left prefix of another index, once the implicit clustered index key columns are considered
+	RedundancyImpliedUnique                       // unique constraint is already enforced by a unique index on a subset of its columns, and its leading columns are still covered for lookups
+)
+
+func (rt RedundancyType) String() string {
+	switch rt {
+	case RedundancyDuplicate:
+		return "duplicate"
+	case RedundancyLeftPrefix:
+		return "left-prefix"
+	case RedundancyClusteredKey:
+		return "clustered-key"
+	case RedundancyImpliedUnique:
+		return "implied-unique"
+	default:
+		panic(fmt.Errorf("Unsupported redundancy type %d", rt))
+	}
+}
+
+// RedundantIndex represents a secondary index that may be dropped without
+// losing any lookup capability or uniqueness constraint, since another index
+// in the same table already provides it.
+type RedundantIndex struct {
+	Index     *Index // the index which may be dropped
+	CoveredBy *Index // the index which makes Index redundant
+	Type      RedundancyType
+}
+
+// DropClause returns a DropIndex clause for removing the redundant index.
+func (ri RedundantIndex) DropClause() DropIndex {
+	return DropIndex{Index: ri.Index}
+}
+
+func (ri RedundantIndex) String() string {
+	var covering string
+	if ri.CoveredBy.PrimaryKey {
+		covering = "PRIMARY KEY"
+	} else {
+		covering = fmt.Sprintf("index %s", EscapeIdentifier(ri.CoveredBy.Name))
+	}
+	return fmt.Sprintf("index %s is redundant (%s) with %s", EscapeIdentifier(ri.Index.Name), ri.Type, covering)
+}
+
+// RedundantIndexes returns information on any secondary indexes in the table
+// which are made redundant by other indexes, including the primary key. The
+// primary key itself is never considered redundant. Each redundant index is
+// returned at most once, in the same order as t.SecondaryIndexes.
+// For InnoDB tables, secondary indexes implicitly contain the columns of the
+// clustered index key (see Table.ClusteredIndexKey), and this is taken into
+// account when comparing non-unique indexes.
+func (t *Table) RedundantIndexes() []RedundantIndex {
+	clusteredKey := t.ClusteredIndexKey()
+	candidates := make([]*Index, 0, len(t.SecondaryIndexes)+1)
+	if t.PrimaryKey != nil {
+		candidates = append(candidates, t.PrimaryKey)
+	}
+	candidates = append(candidates, t.SecondaryIndexes...)
+	position := make(map[*Index]int, len(candidates))
+	for n, idx := range candidates {
+		position[idx] = n
+	}
+
+	result := make([]RedundantIndex, 0)
+	redundant := make(map[*Index]bool)
+	for _, idx := range t.SecondaryIndexes {
+		var best *RedundantIndex
+		for _, other := range candidates {
+			if other == idx || redundant[other] {
+				continue
+			}
+			typ, ok := idx.redundantWith(other, clusteredKey, position[idx] > position[other])
+			if !ok {
+				continue
+			}
+			// A unique index on a superset of other's columns is only redundant if
+			// lookups on its leading columns can still use some remaining index. For
+			// example, UNIQUE(a,b) cannot be dropped in favor of UNIQUE(b) unless
+			// another index begins with a.
+			if typ == RedundancyImpliedUnique && !idx.leadingPartsCovered(other, candidates, redundant) {
+				continue
+			}
+			// Prefer the covering index with the most columns, since this is least
+			// likely to itself be redundant
+			if best == nil || len(other.Columns) > len(best.CoveredBy.Columns) {
+				best = &RedundantIndex{Index: idx, CoveredBy: other, Type: typ}
+			}
+		}
+		if best != nil {
+			redundant[idx] = true
+			result = append(result, *best)
+		}
+	}
+
+	// If a covering index is itself redundant, point to whichever index covers
+	// it instead, so that the result remains accurate once everything is dropped
+	coveredBy := make(map[*Index]*Index, len(result))
+	for _, ri := range result {
+		coveredBy[ri.Index] = ri.CoveredBy
+	}
+	for n := range result {
+		for redundant[result[n].CoveredBy] {
+			result[n].CoveredBy = coveredBy[result[n].CoveredBy]
+		}
+	}
+	return result
+}
+
+// RedundantIndexAlterStatement returns an ALTER TABLE statement which drops all
+// indexes returned by RedundantIndexes, or a blank string if there are none.
+func (t *Table) RedundantIndexAlterStatement(mods StatementModifiers) string {
+	redundant := t.RedundantIndexes()
+	if len(redundant) == 0 {
+		return ""
+	}
+	clauses := make([]string, len(redundant))
+	for n, ri := range redundant {
+		clauses[n] = ri.DropClause().Clause(mods)
+	}
+	return fmt.Sprintf("%s %s", t.AlterStatement(), strings.Join(clauses, ", "))
+}
+
+// redundantWith determines whether idx is made redundant by other. The
+// clusteredKey arg should be the table's clustered index key, or nil if not
+// applicable. The isLater arg should be true if idx appears after other in the
+// table's list of indexes; this is used to only report one side of a
+// duplicate pair.
+func (idx *Index) redundantWith(other, clusteredKey *Index, isLater bool) (RedundancyType, bool) {
+	if idx.PrimaryKey {
+		return 0, false
+	}
+	if idx.Unique {
+		if !other.Unique {
+			return 0, false
+		}
+		if idx.sameParts(other) {
+			return RedundancyDuplicate, isLater || other.PrimaryKey
+		}
+		if other.impliesUniqueness(idx) {
+			return RedundancyImpliedUnique, true
+		}
+		return 0, false
+	}
+
+	// For the remaining checks, idx is a non-unique secondary index
+	if idx.sameParts(other) {
+		return RedundancyDuplicate, isLater || other.Unique
+	}
+	idxCols, idxSubParts := idx.effectiveParts(clusteredKey)
+	otherCols, otherSubParts := other.effectiveParts(clusteredKey)
+	if clusteredKey != nil && !other.Unique && len(idxCols) == len(otherCols) && partsArePrefix(idxCols, idxSubParts, otherCols, otherSubParts) {
+		// Both indexes are equivalent once the clustered key is included. Keep the
+		// one with fewer explicit columns; or if same, keep the earlier one.
+		if len(idx.Columns) > len(other.Columns) || (len(idx.Columns) == len(other.Columns) && isLater) {
+			return RedundancyClusteredKey, true
+		}
+		return 0, false
+	}
+	if len(idx.Columns) < len(other.Columns) && partsArePrefix(idx.Columns, idx.SubParts, other.Columns, other.SubParts) {
+		return RedundancyLeftPrefix, true
+	}
+	if clusteredKey != nil && !other.PrimaryKey && partsArePrefix(idx.Columns, idx.SubParts, otherCols, otherSubParts) {
+		return RedundancyClusteredKey, true
+	}
+	return 0, false
+}
+
+// sameParts returns true if idx and other have identical columns and sub-parts,
+// regardless of name, comment, or uniqueness.
+func (idx *Index) sameParts(other *Index) bool {
+	if len(idx.Columns) != len(other.Columns) {
+		return false
+	}
+	for n := range idx.Columns {
+		if idx.Columns[n].Name != other.Columns[n].Name || idx.SubParts[n] != other.SubParts[n] {
+			return false
+		}
+	}
+	return true
+}
+
+// effectiveParts returns the columns and sub-parts actually stored in idx's
+// index entries. For a secondary index in an InnoDB table, this includes any
+// columns of the clustered index key not already present in idx.
+func (idx *Index) effectiveParts(clusteredKey *Index) ([]*Column, []uint16) {
+	if clusteredKey == nil || idx == clusteredKey {
+		return idx.Columns, idx.SubParts
+	}
+	cols := make([]*Column, len(idx.Columns), len(idx.Columns)+len(clusteredKey.Columns))
+	subParts := make([]uint16, len(idx.SubParts), cap(cols))
+	copy(cols, idx.Columns)
+	copy(subParts, idx.SubParts)
+	for n, ckCol := range clusteredKey.Columns {
+		var already bool
+		for m, col := range idx.Columns {
+			if col.Name == ckCol.Name && idx.SubParts[m] == 0 {
+				already = true
+				break
+			}
+		}
+		if !already {
+			cols = append(cols, ckCol)
+			subParts = append(subParts, clusteredKey.SubParts[n])
+		}
+	}
+	return cols, subParts
+}
+
+// impliesUniqueness returns true if the unique index idx enforces a stricter
+// constraint than the unique index other: every column of idx is present in
+// other, and idx has fewer columns.
+func (idx *Index) impliesUniqueness(other *Index) bool {
+	if len(idx.Columns) >= len(other.Columns) {
+		return false
+	}
+	for n, col := range idx.Columns {
+		var found bool
+		for m, otherCol := range other.Columns {
+			// A unique prefix of a column also enforces uniqueness of any longer
+			// prefix, or of the full column
+			if col.Name == otherCol.Name && (other.SubParts[m] == 0 || (idx.SubParts[n] != 0 && idx.SubParts[n] <= other.SubParts[m])) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// leadingPartsCovered is used when the unique index idx is made redundant by
+// the unique index other via RedundancyImpliedUnique. It returns true if the
+// leading parts of idx preceding the point where all of other's columns have
+// appeared are a left prefix of some index in candidates, excluding idx itself
+// and any indexes already marked redundant. This ensures that dropping idx
+// does not lose the ability to look up rows by those leading columns.
+func (idx *Index) leadingPartsCovered(other *Index, candidates []*Index, redundant map[*Index]bool) bool {
+	remaining := len(other.Columns)
+	var needed int
+	for needed = 0; needed < len(idx.Columns) && remaining > 0; needed++ {
+		for m, otherCol := range other.Columns {
+			if idx.Columns[needed].Name == otherCol.Name && (other.SubParts[m] == 0 || (idx.SubParts[needed] != 0 && idx.SubParts[needed] <= other.SubParts[m])) {
+				remaining--
+				break
+			}
+		}
+	}
+	// Lookups on the first needed parts of idx are served by other, since
+	// all of other's columns are present; only shorter prefixes need coverage
+	needed--
+	if needed <= 0 {
+		return true
+	}
+	for _, candidate := range candidates {
+		if candidate != idx && !redundant[candidate] && partsArePrefix(idx.Columns[:needed], idx.SubParts[:needed], candidate.Columns, candidate.SubParts) {
+			return true
+		}
+	}
+	return false
+}
+
+// partsArePrefix returns true if the index parts described by cols and subParts
+// are a left prefix of (or identical to) the parts described by otherCols and
+// otherSubParts. A sub-part in the prefix is satisfied by an equal or longer
+// sub-part, or by the full column.
+func partsArePrefix(cols []*Column, subParts []uint16, otherCols []*Column, otherSubParts []uint16) bool {
+	if len(cols) > len(otherCols) {
+		return false
+	}
+	for n := range cols {
+		if cols[n].Name != otherCols[n].Name {
+			return false
+		}
+		if otherSubParts[n] != 0 && (subParts[n] == 0 || subParts[n] > otherSubParts[n]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,125 @@
+package tengo
+
+import (
+	"testing"
+)
+
+func TestTableRedundantIndexes(t *testing.T) {
+	table := aTable(1)
+	if redundant := table.RedundantIndexes(); len(redundant) != 0 {
+		t.Fatalf("Expected no redundant indexes in fixture table, instead found %v", redundant)
+	}
+	if stmt := table.RedundantIndexAlterStatement(StatementModifiers{}); stmt != "" {
+		t.Errorf("Expected blank statement, instead found %s", stmt)
+	}
+
+	cols := table.Columns
+	newIndex := func(name string, unique bool, parts ...*Column) *Index {
+		return &Index{
+			Name:     name,
+			Columns:  parts,
+			SubParts: make([]uint16, len(parts)),
+			Unique:   unique,
+		}
+	}
+	dupLastName := &Index{
+		Name:     "dup_actor_name",
+		Columns:  []*Column{cols[2], cols[1]},
+		SubParts: []uint16{10, 1},
+	}
+	prefixLastName := &Index{
+		Name:     "lastname_prefix",
+		Columns:  []*Column{cols[2]},
+		SubParts: []uint16{5},
+	}
+	pkPrefix := newIndex("pk_prefix", false, cols[0])
+	aliveIdx := newIndex("alive", false, cols[5])
+	aliveWithPK := newIndex("alive_pk", false, cols[5], cols[0])
+	uniqueSSNAlive := newIndex("uniq_ssn_alive", true, cols[4], cols[5])
+	table.SecondaryIndexes = append(table.SecondaryIndexes, dupLastName, prefixLastName, pkPrefix, aliveIdx, aliveWithPK, uniqueSSNAlive)
+
+	expected := []RedundantIndex{
+		{Index: dupLastName, CoveredBy: table.SecondaryIndexes[1], Type: RedundancyDuplicate},
+		{Index: prefixLastName, CoveredBy: table.SecondaryIndexes[1], Type: RedundancyLeftPrefix},
+		{Index: pkPrefix, CoveredBy: table.PrimaryKey, Type: RedundancyDuplicate},
+		{Index: aliveWithPK, CoveredBy: aliveIdx, Type: RedundancyClusteredKey},
+		{Index: uniqueSSNAlive, CoveredBy: table.SecondaryIndexes[0], Type: RedundancyImpliedUnique},
+	}
+	actual := table.RedundantIndexes()
+	if len(actual) != len(expected) {
+		t.Fatalf("Expected %d redundant indexes, instead found %d: %v", len(expected), len(actual), actual)
+	}
+	for n := range expected {
+		if actual[n] != expected[n] {
+			t.Errorf("Expected redundant index[%d] to be %s, instead found %s", n, expected[n], actual[n])
+		}
+	}
+
+	expectStmt := "ALTER TABLE `actor` DROP KEY `dup_actor_name`, DROP KEY `lastname_prefix`, DROP KEY `pk_prefix`, DROP KEY `alive_pk`, DROP KEY `uniq_ssn_alive`"
+	if stmt := table.RedundantIndexAlterStatement(StatementModifiers{}); stmt != expectStmt {
+		t.Errorf("Unexpected result from RedundantIndexAlterStatement: %s", stmt)
+	}
+
+	// Without a clustered index, alive_pk is a distinct index
+	table.Engine = "MyISAM"
+	for _, ri := range table.RedundantIndexes() {
+		if ri.Index == aliveWithPK {
+			t.Errorf("Expected %s to not be redundant for non-InnoDB table", aliveWithPK.Name)
+		}
+		if ri.Index == aliveIdx && ri.Type != RedundancyLeftPrefix {
+			t.Errorf("Expected %s to be a left-prefix of %s, instead found %s", aliveIdx.Name, aliveWithPK.Name, ri)
+		}
+	}
+
+	// UNIQUE(a,b) is not redundant with UNIQUE(b), since it is the only index
+	// which can be used for lookups on a alone
+	table = aTable(1)
+	cols = table.Columns
+	uniqueB := newIndex("ub", true, cols[2])
+	uniqueAB := newIndex("uab", true, cols[1], cols[2])
+	table.SecondaryIndexes = []*Index{uniqueB, uniqueAB}
+	if redundant := table.RedundantIndexes(); len(redundant) != 0 {
+		t.Errorf("Expected no redundant indexes, instead found %v", redundant)
+	}
+	if stmt := table.RedundantIndexAlterStatement(StatementModifiers{}); stmt != "" {
+		t.Errorf("Expected blank statement, instead found %s", stmt)
+	}
+
+	// ... but it is redundant once another index begins with a
+	indexA := newIndex("a", false, cols[1])
+	table.SecondaryIndexes = append(table.SecondaryIndexes, indexA)
+	expected = []RedundantIndex{
+		{Index: uniqueAB, CoveredBy: uniqueB, Type: RedundancyImpliedUnique},
+	}
+	if actual := table.RedundantIndexes(); len(actual) != 1 || actual[0] != expected[0] {
+		t.Errorf("Expected %v, instead found %v", expected, actual)
+	}
+}
+
+func TestTableRedundantIndexesChain(t *testing.T) {
+	table := anotherTable()
+	cols := table.Columns
+	extraCol := &Column{Name: "year", TypeInDB: "smallint(6)", Default: ColumnDefaultNull}
+	extraCol2 := &Column{Name: "rating", TypeInDB: "tinyint(4)", Default: ColumnDefaultNull}
+	table.Columns = append(table.Columns, extraCol, extraCol2)
+	longer := &Index{
+		Name:     "film_year",
+		Columns:  []*Column{cols[1], extraCol},
+		SubParts: []uint16{0, 0},
+	}
+	longest := &Index{
+		Name:     "film_year_rating",
+		Columns:  []*Column{cols[1], extraCol, extraCol2},
+		SubParts: []uint16{0, 0, 0},
+	}
+	table.SecondaryIndexes = append(table.SecondaryIndexes, longer, longest)
+	actual := table.RedundantIndexes()
+	if len(actual) != 2 {
+		t.Fatalf("Expected 2 redundant indexes, instead found %d: %v", len(actual), actual)
+	}
+	for _, ri := range actual {
+		if ri.CoveredBy != longest {
+			t.Errorf("Expected %s to be covered by %s, instead found %s", ri.Index.Name, longest.Name, ri)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package tengo
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ReplicationState describes an instance's position in a replication
+// topology, along with settings relevant to whether it should accept writes.
+type ReplicationState struct {
+	Source        *ReplicationSource // nil if the instance is not a replica
+	Replicas      []Replica          // replicas currently connected to the instance
+	GTIDMode      string             // value of gtid_mode, or "" if flavor lacks this variable
+	ReadOnly      bool               // value of read_only
+	SuperReadOnly bool               // value of super_read_only, or false if flavor lacks this variable
+}
+
+// IsReplica returns true if the instance is configured to replicate from a
+// source, regardless of whether replication is currently running.
+func (rs *ReplicationState) IsReplica() bool {
+	return rs.Source != nil
+}
+
+// ReplicationSource describes the replication source of a replica, as well as
+// the replica's status in applying changes from it. If the replica has
+// multiple replication channels, only the first channel is described.
+type ReplicationSource struct {
+	Host         string
+	Port         int
+	IORunning    bool          // true if the replica is connected to the source
+	SQLRunning   bool          // true if the replica is applying changes
+	Lag          time.Duration // only meaningful if LagKnown is true
+	LagKnown     bool          // false if lag could not be determined, e.g. replication stopped
+	LastError    string        // most recent replication error, if any
+	AutoPosition bool          // true if replicating using GTID auto-positioning
+}
+
+// Replica describes a server replicating from an instance. Replicas which do
+// not set report_host are only found by their connection in the processlist,
+// in which case ServerID and Port will be 0.
+type Replica struct {
+	ServerID uint32
+	Host     string
+	Port     int
+}
+
+// maxTopologyDepth is the maximum number of replication hops that Primary will
+// follow before concluding that the topology contains a cycle.
+const maxTopologyDepth = 10
+
+// ReplicationState returns information about the instance's replication
+// source (if any), connected replicas, GTID mode, and read-only status. The
+// user must have the REPLICATION CLIENT privilege, and should have PROCESS as
+// well in order to find replicas which do not set report_host.
+func (instance *Instance) ReplicationState() (*ReplicationState, error) {
+	return instance.ReplicationStateContext(context.Background())
+}
+
+// ReplicationStateContext is like ReplicationState, but the supplied context
+// is used for all queries.
+func (instance *Instance) ReplicationStateContext(ctx context.Context) (*ReplicationState, error) {
+	db, err := instance.ConnectContext(ctx, "", "")
+	if err != nil {
+		return nil, err
+	}
+	state := &ReplicationState{}
+
+	// MySQL 8.0.22 renamed the replication-related SHOW commands and columns. The
+	// old forms are still supported for now, but emit deprecation warnings.
+	statusQuery, hostsQuery := "SHOW SLAVE STATUS", "SHOW SLAVE HOSTS"
+	if instance.replicaTerminology(ctx) {
+		statusQuery, hostsQuery = "SHOW REPLICA STATUS", "SHOW REPLICAS"
+	}
+	if state.Source, err = instance.replicationSource(ctx, db, statusQuery); err != nil {
+		return nil, err
+	}
+
+	hostRows, err := queryRowMaps(ctx, db, hostsQuery)
+	if err != nil {
+		return nil, err
+	}
+	knownHosts := make(map[string]bool)
+	for _, row := range hostRows {
+		host, _ := rowMapValue(row, "host")
+		if host == "" {
+			continue
+		}
+		serverID, _ := rowMapValue(row, "server_id")
+		port, _ := rowMapValue(row, "port")
+		replica := Replica{Host: host}
+		if id, err := strconv.ParseUint(serverID, 10, 32); err == nil {
+			replica.ServerID = uint32(id)
+		}
+		replica.Port, _ = strconv.Atoi(port)
+		state.Replicas = append(state.Replicas, replica)
+		knownHosts[host] = true
+	}
+
+	// Replicas that don't set report_host are only visible via their binlog dump
+	// threads. The port of these connections is ephemeral, so it is discarded.
+	var clientHosts []string
+	query := `
+		SELECT host
+		FROM   information_schema.processlist
+		WHERE  command IN ('Binlog Dump', 'Binlog Dump GTID')`
+	if err := db.SelectContext(ctx, &clientHosts, query); err != nil {
+		return nil, err
+	}
+	for _, clientHost := range clientHosts {
+		host := processlistHost(clientHost)
+		if !knownHosts[host] {
+			state.Replicas = append(state.Replicas, Replica{Host: host})
+			knownHosts[host] = true
+		}
+	}
+
+	var rawVars []struct {
+		Name  string `db:"Variable_name"`
+		Value string `db:"Value"`
+	}
+	query = "SHOW GLOBAL VARIABLES WHERE Variable_name IN ('gtid_mode', 'read_only', 'super_read_only')"
+	if err := db.SelectContext(ctx, &rawVars, query); err != nil {
+		return nil, err
+	}
+	for _, rawVar := range rawVars {
+		switch rawVar.Name {
+		case "gtid_mode":
+			state.GTIDMode = rawVar.Value
+		case "read_only":
+			state.ReadOnly = (rawVar.Value == "ON" || rawVar.Value == "1")
+		case "super_read_only":
+			state.SuperReadOnly = (rawVar.Value == "ON" || rawVar.Value == "1")
+		}
+	}
+	return state, nil
+}
+
+// ReplicationSource returns information about the instance's replication
+// source and the status of replicating from it, or nil if the instance is not
+// a replica. This only requires a single query, so it is more efficient than
+// ReplicationState for frequent polling of replication lag.
+func (instance *Instance) ReplicationSource() (*ReplicationSource, error) {
+	return instance.ReplicationSourceContext(context.Background())
+}
+
+// ReplicationSourceContext is like ReplicationSource, but the supplied context
+// is used for the query.
+func (instance *Instance) ReplicationSourceContext(ctx context.Context) (*ReplicationSource, error) {
+	db, err := instance.ConnectContext(ctx, "", "")
+	if err != nil {
+		return nil, err
+	}
+	query := "SHOW SLAVE STATUS"
+	if instance.replicaTerminology(ctx) {
+		query = "SHOW REPLICA STATUS"
+	}
+	return instance.replicationSource(ctx, db, query)
+}
+
+// replicationSource runs query, which should be SHOW SLAVE STATUS or SHOW
+// REPLICA STATUS, and returns the parsed result of the first row.
+func (instance *Instance) replicationSource(ctx context.Context, db *sqlx.DB, query string) (*ReplicationSource, error) {
+	statusRows, err := queryRowMaps(ctx, db, query)
+	if err != nil || len(statusRows) == 0 {
+		return nil, err
+	}
+	return parseReplicationSource(statusRows[0]), nil
+}
+
+// Primary returns the instance at the top of the replication topology that
+// contains instance, by following replication sources upwards. If instance is
+// not a replica, it is returned as-is. Otherwise, the returned Instance uses
+// the same credentials, default params, and TLS or auth options as instance.
+// An error is returned if any source's host is not reported by its replica,
+// or if the topology appears to contain a cycle.
+func (instance *Instance) Primary() (*Instance, error) {
+	return instance.PrimaryContext(context.Background())
+}
+
+// PrimaryContext is like Primary, but the supplied context is used for all
+// queries.
+func (instance *Instance) PrimaryContext(ctx context.Context) (*Instance, error) {
+	current := instance
+	for n := 0; n < maxTopologyDepth; n++ {
+		state, err := current.ReplicationStateContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !state.IsReplica() {
+			return current, nil
+		}
+		next, err := current.sourceInstance(state.Source)
+		if current != instance {
+			current.CloseAll()
+		}
+		if err != nil {
+			return nil, err
+		}
+		current = next
+	}
+	if current != instance {
+		current.CloseAll()
+	}
+	return nil, fmt.Errorf("Unable to find primary of %s: exceeded %d levels of replication, possible cycle in topology", instance, maxTopologyDepth)
+}
+
+// sourceInstance returns a new Instance for the supplied replication source,
+// using the same credentials and default params as instance.
+func (instance *Instance) sourceInstance(source *ReplicationSource) (*Instance, error) {
+	if source.Host == "" {
+		return nil, fmt.Errorf("Unable to determine replication source of %s", instance)
+	}
+	port := source.Port
+	if port == 0 {
+		port = 3306
+	}
+	v := url.Values{}
+	for name, value := range instance.defaultParams {
+		v.Set(name, value)
+	}
+	dsn := fmt.Sprintf("%s:%s@tcp(%s)/?%s", instance.User, instance.Password, net.JoinHostPort(source.Host, strconv.Itoa(port)), v.Encode())
+	return NewInstance(instance.Driver, dsn)
+}
+
+// replicaTerminology returns true if the instance supports SHOW REPLICA STATUS
+// and SHOW REPLICAS, which were introduced in MySQL 8.0.22.
+func (instance *Instance) replicaTerminology(ctx context.Context) bool {
+	flavor := instance.flavorContext(ctx)
+	if !flavor.MySQLishMinVersion(8, 0) {
+		return false
+	}
+	return flavor.MySQLishMinVersion(8, 1) || instance.version[2] >= 22
+}
+
+// queryRowMaps runs a query, typically a SHOW command whose columns vary by
+// flavor, and returns each row as a map keyed by lowercased column name.
+func queryRowMaps(ctx context.Context, db *sqlx.DB, query string) ([]map[string]interface{}, error) {
+	rows, err := db.QueryxContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []map[string]interface{}
+	for rows.Next() {
+		row := make(map[string]interface{})
+		if err := rows.MapScan(row); err != nil {
+			return nil, err
+		}
+		lowerRow := make(map[string]interface{}, len(row))
+		for name, value := range row {
+			lowerRow[strings.ToLower(name)] = value
+		}
+		result = append(result, lowerRow)
+	}
+	return result, rows.Err()
+}
+
+// rowMapValue returns the value of the first of the supplied column names
+// present in row, converted to a string. The bool return value is false if
+// none of the columns are present, or the value is NULL.
+func rowMapValue(row map[string]interface{}, names ...string) (string, bool) {
+	for _, name := range names {
+		if value, ok := row[name]; ok {
+			switch value := value.(type) {
+			case []byte:
+				return string(value), true
+			case string:
+				return value, true
+			case int64:
+				return strconv.FormatInt(value, 10), true
+			default:
+				return "", false
+			}
+		}
+	}
+	return "", false
+}
+
+// parseReplicationSource converts a row of SHOW SLAVE STATUS or SHOW REPLICA
+// STATUS output, as returned by queryRowMaps, into a ReplicationSource. Both
+// the old and new column names are supported, as are the MariaDB-specific
+// GTID columns.
+func parseReplicationSource(status map[string]interface{}) *ReplicationSource {
+	col := func(names ...string) (string, bool) {
+		return rowMapValue(status, names...)
+	}
+	source := &ReplicationSource{}
+	source.Host, _ = col("source_host", "master_host")
+	portStr, _ := col("source_port", "master_port")
+	source.Port, _ = strconv.Atoi(portStr)
+	ioRunning, _ := col("replica_io_running", "slave_io_running")
+	source.IORunning = (ioRunning == "Yes")
+	sqlRunning, _ := col("replica_sql_running", "slave_sql_running")
+	source.SQLRunning = (sqlRunning == "Yes")
+	if lagStr, ok := col("seconds_behind_source", "seconds_behind_master"); ok {
+		if lag, err := strconv.Atoi(lagStr); err == nil {
+			source.Lag = time.Duration(lag) * time.Second
+			source.LagKnown = true
+		}
+	}
+	if ioErr, _ := col("last_io_error"); ioErr != "" {
+		source.LastError = ioErr
+	} else {
+		source.LastError, _ = col("last_sql_error", "last_error")
+	}
+	if autoPos, ok := col("auto_position"); ok {
+		source.AutoPosition = (autoPos == "1")
+	} else if usingGTID, ok := col("using_gtid"); ok { // MariaDB
+		source.AutoPosition = (usingGTID != "" && strings.ToLower(usingGTID) != "no")
+	}
+	return source
+}
+
+// processlistHost strips the port from a host value in the processlist, which
+// is in format "host:port" for TCP connections.
+func processlistHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
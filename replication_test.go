@@ -0,0 +1,129 @@
+package tengo
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseReplicationSource(t *testing.T) {
+	// Old column names, as used by MySQL prior to 8.0.22
+	status := map[string]interface{}{
+		"master_host":           []byte("10.0.0.1"),
+		"master_port":           []byte("3307"),
+		"slave_io_running":      []byte("Yes"),
+		"slave_sql_running":     []byte("Yes"),
+		"seconds_behind_master": []byte("12"),
+		"last_io_error":         []byte(""),
+		"last_sql_error":        []byte(""),
+		"auto_position":         []byte("1"),
+	}
+	expected := ReplicationSource{
+		Host:         "10.0.0.1",
+		Port:         3307,
+		IORunning:    true,
+		SQLRunning:   true,
+		Lag:          12 * time.Second,
+		LagKnown:     true,
+		AutoPosition: true,
+	}
+	if source := parseReplicationSource(status); *source != expected {
+		t.Errorf("Expected %+v, instead found %+v", expected, *source)
+	}
+
+	// New column names, with replication stopped due to an error
+	status = map[string]interface{}{
+		"source_host":           []byte("db1.example.com"),
+		"source_port":           []byte("3306"),
+		"replica_io_running":    []byte("Yes"),
+		"replica_sql_running":   []byte("No"),
+		"seconds_behind_source": nil,
+		"last_io_error":         []byte(""),
+		"last_sql_error":        []byte("Duplicate entry"),
+		"auto_position":         []byte("0"),
+	}
+	expected = ReplicationSource{
+		Host:      "db1.example.com",
+		Port:      3306,
+		IORunning: true,
+		LastError: "Duplicate entry",
+	}
+	if source := parseReplicationSource(status); *source != expected {
+		t.Errorf("Expected %+v, instead found %+v", expected, *source)
+	}
+
+	// MariaDB GTID column
+	status = map[string]interface{}{
+		"master_host": []byte("10.0.0.2"),
+		"using_gtid":  []byte("Slave_Pos"),
+	}
+	if source := parseReplicationSource(status); !source.AutoPosition || source.LagKnown {
+		t.Errorf("Unexpected result from MariaDB replication status: %+v", *source)
+	}
+	status["using_gtid"] = []byte("No")
+	if source := parseReplicationSource(status); source.AutoPosition {
+		t.Errorf("Unexpected result from MariaDB replication status: %+v", *source)
+	}
+}
+
+func TestProcesslistHost(t *testing.T) {
+	cases := map[string]string{
+		"10.0.0.1:54321":    "10.0.0.1",
+		"db2.example:1234":  "db2.example",
+		"[::1]:60000":       "::1",
+		"localhost":         "localhost",
+		"":                  "",
+		"replica-host.test": "replica-host.test",
+	}
+	for input, expected := range cases {
+		if actual := processlistHost(input); actual != expected {
+			t.Errorf("Expected processlistHost(%q) to return %q, instead found %q", input, expected, actual)
+		}
+	}
+}
+
+func TestInstanceSourceInstance(t *testing.T) {
+	instance, err := NewInstance("mysql", "username:password@tcp(1.2.3.4:3306)/?wait_timeout=20")
+	if err != nil {
+		t.Fatalf("Unexpected error from NewInstance: %s", err)
+	}
+	if _, err := instance.sourceInstance(&ReplicationSource{}); err == nil {
+		t.Error("Expected error from sourceInstance without host, but err was nil")
+	}
+	source, err := instance.sourceInstance(&ReplicationSource{Host: "10.0.0.1", Port: 3307})
+	if err != nil {
+		t.Fatalf("Unexpected error from sourceInstance: %s", err)
+	}
+	if source.String() != "10.0.0.1:3307" || source.User != "username" || source.Password != "password" {
+		t.Errorf("Unexpected source instance %s (user=%s, password=%s)", source, source.User, source.Password)
+	}
+	if source.defaultParams["wait_timeout"] != "20" {
+		t.Errorf("Expected source instance to have same default params, instead found %v", source.defaultParams)
+	}
+	source, err = instance.sourceInstance(&ReplicationSource{Host: "fe80::1"})
+	if err != nil {
+		t.Fatalf("Unexpected error from sourceInstance: %s", err)
+	} else if source.Port != 3306 || !strings.Contains(source.Host, "fe80::1") {
+		t.Errorf("Unexpected source instance %s", source)
+	}
+}
+
+func (s TengoIntegrationSuite) TestInstanceReplicationState(t *testing.T) {
+	state, err := s.d.ReplicationState()
+	if err != nil {
+		t.Fatalf("Unexpected error from ReplicationState: %s", err)
+	}
+	if state.IsReplica() || len(state.Replicas) > 0 || state.ReadOnly || state.SuperReadOnly {
+		t.Errorf("Unexpected replication state for standalone instance: %+v", *state)
+	}
+	if s.d.Flavor().MySQLishMinVersion(5, 6) && state.GTIDMode == "" {
+		t.Error("Expected gtid_mode to be populated, but it was not")
+	}
+
+	primary, err := s.d.Primary()
+	if err != nil {
+		t.Fatalf("Unexpected error from Primary: %s", err)
+	} else if primary != s.d.Instance {
+		t.Errorf("Expected Primary to return same instance, instead found %s", primary)
+	}
+}
@@ -2,6 +2,7 @@ package tengo
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 )
 
@@ -22,6 +23,35 @@ type Routine struct {
 	CreateStatement   string // complete SHOW CREATE obtained from an instance
 }
 
+// RoutineParam represents a parameter of a stored procedure or function, or
+// the return type of a function.
+type RoutineParam struct {
+	Mode      string // "IN", "OUT", or "INOUT" for procedure params; blank for functions
+	Name      string // blank for function return types
+	TypeInDB  string // normalized to the format used by SHOW CREATE TABLE
+	CharSet   string // only populated if specified explicitly or implied by Collation
+	Collation string // only populated if specified explicitly
+}
+
+// String returns the parameter's definition in canonical form.
+func (param *RoutineParam) String() string {
+	parts := make([]string, 0, 5)
+	if param.Mode != "" {
+		parts = append(parts, param.Mode)
+	}
+	if param.Name != "" {
+		parts = append(parts, EscapeIdentifier(param.Name))
+	}
+	parts = append(parts, param.TypeInDB)
+	if param.CharSet != "" {
+		parts = append(parts, fmt.Sprintf("CHARACTER SET %s", param.CharSet))
+	}
+	if param.Collation != "" {
+		parts = append(parts, fmt.Sprintf("COLLATE %s", param.Collation))
+	}
+	return strings.Join(parts, " ")
+}
+
 // Definition generates and returns a canonical CREATE PROCEDURE or CREATE
 // FUNCTION statement based on the Routine's Go field values.
 func (r *Routine) Definition(flavor Flavor) string {
@@ -30,12 +60,8 @@ func (r *Routine) Definition(flavor Flavor) string {
 
 // head returns the portion of a CREATE statement prior to the body.
 func (r *Routine) head(_ Flavor) string {
-	var definer, returnClause, characteristics string
-
-	atPos := strings.LastIndex(r.Definer, "@")
-	if atPos >= 0 {
-		definer = fmt.Sprintf("%s@%s", EscapeIdentifier(r.Definer[0:atPos]), EscapeIdentifier(r.Definer[atPos+1:]))
-	}
+	var returnClause, characteristics string
+	definer := escapeDefiner(r.Definer)
 	if r.Type == ObjectTypeFunc {
 		returnClause = fmt.Sprintf(" RETURNS %s", r.ReturnDataType)
 	}
@@ -80,7 +106,199 @@ func (r *Routine) Equals(other *Routine) bool {
 	return *r == *other
 }
 
+// CanAlterTo returns true if r can be transformed into other using ALTER
+// PROCEDURE or ALTER FUNCTION. This is the case if the routines differ only in
+// their comment, SQL SECURITY, and/or SQL data access characteristics. False
+// is returned if the routines are identical.
+func (r *Routine) CanAlterTo(other *Routine) bool {
+	if r == nil || other == nil || r.Equals(other) {
+		return false
+	}
+	copied := *other
+	copied.Comment = r.Comment
+	copied.SecurityType = r.SecurityType
+	copied.SQLDataAccess = r.SQLDataAccess
+	copied.CreateStatement = r.CreateStatement
+	return *r == copied
+}
+
+// AlterStatement returns an ALTER PROCEDURE or ALTER FUNCTION statement that,
+// if run, would change the characteristics of r to match other. It should
+// only be called if r.CanAlterTo(other) returns true; otherwise, differences
+// beyond the routines' characteristics are ignored.
+func (r *Routine) AlterStatement(other *Routine) string {
+	var clauses []string
+	if r.SQLDataAccess != other.SQLDataAccess {
+		clauses = append(clauses, other.SQLDataAccess)
+	}
+	if r.SecurityType != other.SecurityType {
+		clauses = append(clauses, fmt.Sprintf("SQL SECURITY %s", other.SecurityType))
+	}
+	if r.Comment != other.Comment {
+		clauses = append(clauses, fmt.Sprintf("COMMENT '%s'", EscapeValueForCreateTable(other.Comment)))
+	}
+	if len(clauses) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("ALTER %s %s %s", r.Type.Caps(), EscapeIdentifier(r.Name), strings.Join(clauses, " "))
+}
+
+// Params parses r.ParamString into a slice of parameters. Data types are
+// normalized, so that parameter lists differing only in formatting or type
+// synonyms yield identical results. Procedure params lacking an explicit mode
+// are given mode "IN".
+func (r *Routine) Params() ([]*RoutineParam, error) {
+	p, err := r.paramParser(r.ParamString, "parameter list")
+	if err != nil {
+		return nil, err
+	}
+	var params []*RoutineParam
+	for !p.atEnd() {
+		param := &RoutineParam{}
+		if r.Type == ObjectTypeProc {
+			if param.Mode = p.acceptAnyKeyword("INOUT", "IN", "OUT"); param.Mode == "" {
+				param.Mode = "IN"
+			}
+		}
+		if param.Name, err = p.identifier(); err != nil {
+			return nil, err
+		}
+		if err := p.parseRoutineParamType(param); err != nil {
+			return nil, err
+		}
+		params = append(params, param)
+		if p.atEnd() {
+			break
+		} else if err := p.expectSymbol(","); err != nil {
+			return nil, err
+		} else if p.atEnd() {
+			return nil, p.unexpected("parameter")
+		}
+	}
+	return params, nil
+}
+
+// ReturnType parses r.ReturnDataType into a RoutineParam, with a blank Mode
+// and Name. If r is a procedure, the result is nil.
+func (r *Routine) ReturnType() (*RoutineParam, error) {
+	if r.Type != ObjectTypeFunc {
+		return nil, nil
+	}
+	p, err := r.paramParser(r.ReturnDataType, "return type")
+	if err != nil {
+		return nil, err
+	}
+	param := &RoutineParam{}
+	if err := p.parseRoutineParamType(param); err != nil {
+		return nil, err
+	} else if !p.atEnd() {
+		return nil, p.unexpected("end of return type")
+	}
+	return param, nil
+}
+
+// paramParser returns an alterParser for parsing a routine's parameter list or
+// return type, which use the same data type syntax as column definitions.
+func (r *Routine) paramParser(input, description string) (*alterParser, error) {
+	tokens, err := tokenizeSQL(input)
+	if err != nil {
+		return nil, err
+	}
+	return &alterParser{
+		tokens: tokens,
+		input:  fmt.Sprintf("%s of %s %s", description, r.Type, EscapeIdentifier(r.Name)),
+	}, nil
+}
+
+// parseRoutineParamType parses a data type followed by optional character set
+// and collation clauses, storing the results in param.
+func (p *alterParser) parseRoutineParamType(param *RoutineParam) (err error) {
+	if param.TypeInDB, err = p.parseColumnType(); err != nil {
+		return err
+	}
+	for !p.atClauseEnd() {
+		switch {
+		case p.acceptKeywords("CHARACTER", "SET") || p.acceptKeywords("CHARSET"):
+			if param.CharSet, err = p.identifier(); err != nil {
+				return err
+			}
+		case p.acceptKeywords("COLLATE"):
+			if param.Collation, err = p.identifier(); err != nil {
+				return err
+			}
+		default:
+			return p.unexpected("CHARACTER SET or COLLATE")
+		}
+	}
+	param.CharSet, param.Collation = strings.ToLower(param.CharSet), strings.ToLower(param.Collation)
+	if param.CharSet == "" && param.Collation != "" {
+		param.CharSet = charSetForCollation(param.Collation)
+	}
+	return nil
+}
+
 // DropStatement returns a SQL statement that, if run, would drop this routine.
 func (r *Routine) DropStatement() string {
 	return fmt.Sprintf("DROP %s %s", r.Type.Caps(), EscapeIdentifier(r.Name))
 }
+
+// dropIfExistsStatement returns a SQL statement that, if run, would drop this
+// routine if it exists.
+func (r *Routine) dropIfExistsStatement() string {
+	return fmt.Sprintf("DROP %s IF EXISTS %s", r.Type.Caps(), EscapeIdentifier(r.Name))
+}
+
+// validationName returns a temporary name for r, used for confirming that a
+// new version of the routine compiles before the existing one is dropped. The
+// name is truncated to 64 characters, the max identifier length.
+func (r *Routine) validationName() string {
+	name := []rune(fmt.Sprintf("_tengo_new_%s", r.Name))
+	if len(name) > 64 {
+		name = name[0:64]
+	}
+	return string(name)
+}
+
+// renameInCreate returns createStmt, which must be a CREATE statement for r,
+// modified to use newName instead of r.Name. The bool return value is false if
+// the routine name could not be located in createStmt.
+func (r *Routine) renameInCreate(createStmt, newName string) (string, bool) {
+	oldHead := fmt.Sprintf("%s %s(", r.Type.Caps(), EscapeIdentifier(r.Name))
+	pos := strings.Index(createStmt, oldHead)
+	if pos < 0 {
+		return createStmt, false
+	}
+	newHead := fmt.Sprintf("%s %s(", r.Type.Caps(), EscapeIdentifier(newName))
+	return createStmt[0:pos] + newHead + createStmt[pos+len(oldHead):], true
+}
+
+// escapeDefiner converts a definer in "user@host" format, as stored in
+// information_schema, to the escaped form used in CREATE statements. An
+// empty string is returned if definer does not contain an @ sign.
+func escapeDefiner(definer string) string {
+	atPos := strings.LastIndex(definer, "@")
+	if atPos < 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s@%s", EscapeIdentifier(definer[0:atPos]), EscapeIdentifier(definer[atPos+1:]))
+}
+
+// reDefinerClause matches the DEFINER clause of a CREATE statement, in any of
+// the forms that SHOW CREATE or a user may supply.
+var reDefinerClause = regexp.MustCompile("(?i)^(\\s*CREATE\\s+)DEFINER\\s*=\\s*(?:`(?:[^`]|``)*`|'(?:[^']|'')*'|[^\\s@]+)(?:@(?:`(?:[^`]|``)*`|'(?:[^']|'')*'|[^\\s]+)|\\s*\\(\\s*\\))?\\s+")
+
+// rewriteDefiner returns a copy of createStatement with its DEFINER clause
+// replaced by definer, which should be in "user@host" format. If definer is
+// an empty string, the DEFINER clause is removed entirely, which causes the
+// user running the statement to become the definer.
+func rewriteDefiner(createStatement, definer string) string {
+	loc := reDefinerClause.FindStringSubmatchIndex(createStatement)
+	if loc == nil {
+		return createStatement
+	}
+	prefix := createStatement[loc[2]:loc[3]]
+	if escaped := escapeDefiner(definer); escaped != "" {
+		prefix = fmt.Sprintf("%sDEFINER=%s ", prefix, escaped)
+	}
+	return prefix + createStatement[loc[1]:]
+}
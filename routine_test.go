@@ -0,0 +1,81 @@
+package tengo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRoutineParams(t *testing.T) {
+	proc := aProc("latin1_swedish_ci", "")
+	params, err := proc.Params()
+	if err != nil {
+		t.Fatalf("Unexpected error from Params: %s", err)
+	}
+	expected := []*RoutineParam{
+		{Mode: "IN", Name: "name", TypeInDB: "varchar(30)", CharSet: "utf8mb4", Collation: "utf8mb4_bin"},
+		{Mode: "INOUT", Name: "iterations", TypeInDB: "int(10) unsigned"},
+		{Mode: "OUT", Name: "pct", TypeInDB: "decimal(5,2)"},
+	}
+	if !reflect.DeepEqual(params, expected) {
+		for _, param := range params {
+			t.Logf("Found param %+v", *param)
+		}
+		t.Error("Params did not match expectations")
+	}
+	if ret, err := proc.ReturnType(); ret != nil || err != nil {
+		t.Errorf("Expected procedure to have nil return type, instead found %v / %v", ret, err)
+	}
+
+	// Mode defaults to IN for procs; type synonyms and implied charsets are
+	// normalized; quoted names are supported
+	proc.ParamString = "`customer id` INTEGER, in flag BOOL, OUT label VARCHAR(10) COLLATE utf8mb4_unicode_ci, state enum('a','b''s')"
+	params, err = proc.Params()
+	if err != nil {
+		t.Fatalf("Unexpected error from Params: %s", err)
+	}
+	expected = []*RoutineParam{
+		{Mode: "IN", Name: "customer id", TypeInDB: "int(11)"},
+		{Mode: "IN", Name: "flag", TypeInDB: "tinyint(1)"},
+		{Mode: "OUT", Name: "label", TypeInDB: "varchar(10)", CharSet: "utf8mb4", Collation: "utf8mb4_unicode_ci"},
+		{Mode: "IN", Name: "state", TypeInDB: "enum('a','b''s')"},
+	}
+	if !reflect.DeepEqual(params, expected) {
+		for _, param := range params {
+			t.Logf("Found param %+v", *param)
+		}
+		t.Error("Params did not match expectations")
+	}
+	if str := params[2].String(); str != "OUT `label` varchar(10) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci" {
+		t.Errorf("Unexpected param String result: %s", str)
+	}
+
+	fn := aFunc("latin1_swedish_ci", "")
+	fn.ReturnDataType = "varchar(20) CHARSET utf8mb4"
+	params, err = fn.Params()
+	if err != nil {
+		t.Fatalf("Unexpected error from Params: %s", err)
+	} else if len(params) != 1 || params[0].Mode != "" || params[0].Name != "mult" || params[0].TypeInDB != "float(10,2)" {
+		t.Errorf("Unexpected result from Params: %+v", params)
+	}
+	if ret, err := fn.ReturnType(); err != nil {
+		t.Errorf("Unexpected error from ReturnType: %s", err)
+	} else if ret.String() != "varchar(20) CHARACTER SET utf8mb4" {
+		t.Errorf("Unexpected result from ReturnType: %s", ret)
+	}
+	fn.ParamString = ""
+	if params, err = fn.Params(); len(params) != 0 || err != nil {
+		t.Errorf("Expected no params and no error, instead found %v / %v", params, err)
+	}
+
+	badParams := []string{"IN", "IN x", "x int,", "x int y int", "x int CHARACTER SET"}
+	for _, input := range badParams {
+		proc.ParamString = input
+		if _, err := proc.Params(); err == nil {
+			t.Errorf("Expected error from Params with input %q, but err was nil", input)
+		}
+	}
+	fn.ReturnDataType = "int int"
+	if _, err := fn.ReturnType(); err == nil {
+		t.Error("Expected error from ReturnType with invalid input, but err was nil")
+	}
+}
@@ -0,0 +1,262 @@
+package tengo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// schemaCache stores previously-introspected schemas, permitting subsequent
+// introspection to only re-query tables and routines that have changed.
+type schemaCache struct {
+	sync.Mutex
+	entries map[string]*schemaCacheEntry // key is schema name
+}
+
+// schemaCacheEntry stores the introspected tables and routines of one schema,
+// along with version strings obtained from information_schema at the time of
+// introspection.
+type schemaCacheEntry struct {
+	tables          []*Table
+	routines        []*Routine
+	tableVersions   map[string]string    // table name => create and update times
+	routineVersions map[ObjectKey]string // routine key => created and last altered times
+}
+
+// EnableSchemaCache causes subsequent schema introspection on the instance to
+// be cached. Later introspection of the same schema will compare the
+// create_time and update_time of each table in information_schema.tables, as
+// well as created and last_altered of each routine in
+// information_schema.routines, and only re-introspect objects which have
+// changed. DDL run through the instance's methods, such as DropSchema or
+// AlterSchema, automatically invalidates the affected schema. Callers running
+// DDL directly should call InvalidateSchemaCache afterwards, since some
+// flavors do not update these timestamps for all types of ALTER TABLE.
+func (instance *Instance) EnableSchemaCache() {
+	instance.Lock()
+	defer instance.Unlock()
+	if instance.schemaCache == nil {
+		instance.schemaCache = &schemaCache{
+			entries: make(map[string]*schemaCacheEntry),
+		}
+	}
+}
+
+// DisableSchemaCache disables schema introspection caching on the instance,
+// and discards any cached schemas.
+func (instance *Instance) DisableSchemaCache() {
+	instance.Lock()
+	instance.schemaCache = nil
+	instance.Unlock()
+}
+
+// InvalidateSchemaCache discards any cached introspection of the supplied
+// schema names, or of all schemas if no names are supplied. This has no effect
+// if caching has not been enabled with EnableSchemaCache.
+func (instance *Instance) InvalidateSchemaCache(schemaNames ...string) {
+	instance.RLock()
+	cache := instance.schemaCache
+	instance.RUnlock()
+	if cache == nil {
+		return
+	}
+	cache.Lock()
+	defer cache.Unlock()
+	if len(schemaNames) == 0 {
+		cache.entries = make(map[string]*schemaCacheEntry)
+	}
+	for _, name := range schemaNames {
+		delete(cache.entries, name)
+	}
+}
+
+// introspectSchemasCached populates the Tables and Routines fields of each of
+// the supplied schemas, using cache to avoid re-introspecting objects that
+// have not changed. The cache is then updated with the results.
+func (instance *Instance) introspectSchemasCached(ctx context.Context, cache *schemaCache, schemas []*Schema, opts IntrospectionOptions) error {
+	names := make([]string, len(schemas))
+	for n, s := range schemas {
+		names[n] = s.Name
+	}
+	tableVersions, routineVersions, err := instance.queryObjectVersions(ctx, names)
+	if err != nil {
+		return err
+	}
+
+	var uncached, pending []*Schema
+	for _, s := range schemas {
+		cache.Lock()
+		entry := cache.entries[s.Name]
+		cache.Unlock()
+		if entry == nil {
+			uncached = append(uncached, s)
+			continue
+		}
+
+		// Re-introspect tables which are new or have different versions; retain
+		// copies of the others, and omit ones which no longer exist
+		var changedNames []string
+		for name, version := range tableVersions[s.Name] {
+			if cachedVersion, ok := entry.tableVersions[name]; !ok || cachedVersion != version {
+				changedNames = append(changedNames, name)
+			}
+		}
+		s.Tables = []*Table{}
+		for _, t := range entry.tables {
+			if version, ok := tableVersions[s.Name][t.Name]; ok && version == entry.tableVersions[t.Name] {
+				s.Tables = append(s.Tables, t.clone())
+			}
+		}
+		work := &Schema{Name: s.Name}
+		if len(changedNames) > 0 {
+			tablesBySchema, err := instance.querySchemaTables(ctx, []string{s.Name}, changedNames...)
+			if err != nil {
+				return err
+			}
+			work.Tables = tablesBySchema[s.Name]
+			s.Tables = append(s.Tables, work.Tables...)
+		}
+
+		// Routines are comparatively cheap to introspect, so re-introspect all of
+		// them if any were added, dropped, or altered
+		if routineVersionsEqual(entry.routineVersions, routineVersions[s.Name]) {
+			s.Routines = make([]*Routine, len(entry.routines))
+			for n, r := range entry.routines {
+				rCopy := *r
+				s.Routines[n] = &rCopy
+			}
+		} else {
+			routinesBySchema, err := instance.querySchemaRoutines(ctx, s.Name)
+			if err != nil {
+				return err
+			}
+			if s.Routines = routinesBySchema[s.Name]; s.Routines == nil {
+				s.Routines = []*Routine{}
+			}
+			work.Routines = s.Routines
+		}
+		if len(work.Tables) > 0 || len(work.Routines) > 0 {
+			pending = append(pending, work)
+		}
+	}
+
+	if err := instance.querySchemaObjects(ctx, uncached); err != nil {
+		return err
+	}
+	pending = append(pending, uncached...)
+	if err := instance.hydrateCreateStatements(ctx, pending, opts); err != nil {
+		return err
+	}
+
+	// Store copies in the cache, so that callers modifying the returned schemas
+	// do not affect the cache
+	cache.Lock()
+	defer cache.Unlock()
+	for _, s := range schemas {
+		entry := &schemaCacheEntry{
+			tables:          make([]*Table, len(s.Tables)),
+			routines:        make([]*Routine, len(s.Routines)),
+			tableVersions:   tableVersions[s.Name],
+			routineVersions: routineVersions[s.Name],
+		}
+		for n, t := range s.Tables {
+			entry.tables[n] = t.clone()
+		}
+		for n, r := range s.Routines {
+			rCopy := *r
+			entry.routines[n] = &rCopy
+		}
+		cache.entries[s.Name] = entry
+	}
+	return nil
+}
+
+// queryObjectVersions returns maps of schema name to table versions and
+// routine versions, for the supplied schema names. Versions are opaque strings
+// derived from timestamps in information_schema, which change whenever an
+// object is altered.
+func (instance *Instance) queryObjectVersions(ctx context.Context, schemaNames []string) (map[string]map[string]string, map[string]map[ObjectKey]string, error) {
+	tableVersions := make(map[string]map[string]string, len(schemaNames))
+	routineVersions := make(map[string]map[ObjectKey]string, len(schemaNames))
+	for _, name := range schemaNames {
+		tableVersions[name] = make(map[string]string)
+		routineVersions[name] = make(map[ObjectKey]string)
+	}
+	if len(schemaNames) == 0 {
+		return tableVersions, routineVersions, nil
+	}
+
+	// In flavors with the new data dictionary, information_schema.tables
+	// metadata is cached by default, so disable this in the session
+	var params string
+	if instance.flavorContext(ctx).HasDataDictionary() {
+		params = "information_schema_stats_expiry=0"
+	}
+	db, err := instance.ConnectContext(ctx, "information_schema", params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rawTables []struct {
+		Schema     string         `db:"table_schema"`
+		Name       string         `db:"table_name"`
+		CreateTime sql.NullString `db:"create_time"`
+		UpdateTime sql.NullString `db:"update_time"`
+	}
+	query, args, err := sqlx.In(`
+		SELECT table_schema AS table_schema, table_name AS table_name,
+		       create_time AS create_time, update_time AS update_time
+		FROM   tables
+		WHERE  table_schema IN (?)
+		AND    table_type = 'BASE TABLE'`, schemaNames)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := db.SelectContext(ctx, &rawTables, query, args...); err != nil {
+		return nil, nil, fmt.Errorf("Error querying information_schema.tables for schema versions: %s", err)
+	}
+	for _, rawTable := range rawTables {
+		tableVersions[rawTable.Schema][rawTable.Name] = fmt.Sprintf("%s/%s", rawTable.CreateTime.String, rawTable.UpdateTime.String)
+	}
+
+	var rawRoutines []struct {
+		Schema      string `db:"routine_schema"`
+		Name        string `db:"routine_name"`
+		Type        string `db:"routine_type"`
+		Created     string `db:"created"`
+		LastAltered string `db:"last_altered"`
+	}
+	query, args, err = sqlx.In(`
+		SELECT routine_schema AS routine_schema, routine_name AS routine_name,
+		       LOWER(routine_type) AS routine_type,
+		       created AS created, last_altered AS last_altered
+		FROM   routines
+		WHERE  routine_schema IN (?)`, schemaNames)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := db.SelectContext(ctx, &rawRoutines, query, args...); err != nil {
+		return nil, nil, fmt.Errorf("Error querying information_schema.routines for schema versions: %s", err)
+	}
+	for _, rawRoutine := range rawRoutines {
+		key := ObjectKey{Type: ObjectType(rawRoutine.Type), Name: rawRoutine.Name}
+		routineVersions[rawRoutine.Schema][key] = fmt.Sprintf("%s/%s", rawRoutine.Created, rawRoutine.LastAltered)
+	}
+	return tableVersions, routineVersions, nil
+}
+
+// routineVersionsEqual returns true if the two maps have identical contents.
+func routineVersionsEqual(a, b map[ObjectKey]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, version := range a {
+		if otherVersion, ok := b[key]; !ok || otherVersion != version {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,120 @@
+package tengo
+
+import (
+	"testing"
+)
+
+func TestInstanceSchemaCacheInvalidation(t *testing.T) {
+	instance, err := NewInstance("mysql", "username:password@tcp(1.2.3.4:3306)/")
+	if err != nil {
+		t.Fatalf("Unexpected error from NewInstance: %s", err)
+	}
+
+	// Invalidation should be a no-op without a cache
+	instance.InvalidateSchemaCache("foo")
+
+	instance.EnableSchemaCache()
+	cache := instance.schemaCache
+	if cache == nil {
+		t.Fatal("Expected schema cache to be non-nil after EnableSchemaCache")
+	}
+	instance.EnableSchemaCache()
+	if instance.schemaCache != cache {
+		t.Error("Expected repeated call to EnableSchemaCache to retain existing cache")
+	}
+	for _, name := range []string{"foo", "bar", "baz"} {
+		cache.entries[name] = &schemaCacheEntry{}
+	}
+	instance.InvalidateSchemaCache("foo", "doesnt_exist")
+	if len(cache.entries) != 2 || cache.entries["foo"] != nil {
+		t.Errorf("Unexpected cache entries after invalidating one schema: %v", cache.entries)
+	}
+	instance.InvalidateSchemaCache()
+	if len(cache.entries) != 0 {
+		t.Errorf("Expected no cache entries after invalidating all schemas, instead found %d", len(cache.entries))
+	}
+
+	instance.DisableSchemaCache()
+	if instance.schemaCache != nil {
+		t.Error("Expected schema cache to be nil after DisableSchemaCache")
+	}
+}
+
+func TestRoutineVersionsEqual(t *testing.T) {
+	procKey := ObjectKey{Type: ObjectTypeProc, Name: "foo"}
+	funcKey := ObjectKey{Type: ObjectTypeFunc, Name: "foo"}
+	a := map[ObjectKey]string{procKey: "1/1"}
+	cases := []struct {
+		b        map[ObjectKey]string
+		expected bool
+	}{
+		{map[ObjectKey]string{procKey: "1/1"}, true},
+		{map[ObjectKey]string{procKey: "1/2"}, false},
+		{map[ObjectKey]string{funcKey: "1/1"}, false},
+		{map[ObjectKey]string{procKey: "1/1", funcKey: "1/1"}, false},
+		{map[ObjectKey]string{}, false},
+	}
+	for _, c := range cases {
+		if actual := routineVersionsEqual(a, c.b); actual != c.expected {
+			t.Errorf("Expected routineVersionsEqual(%v, %v) to return %t, instead found %t", a, c.b, c.expected, actual)
+		}
+	}
+	if !routineVersionsEqual(nil, map[ObjectKey]string{}) {
+		t.Error("Expected nil and empty maps to be considered equal")
+	}
+}
+
+func (s TengoIntegrationSuite) TestInstanceSchemaCache(t *testing.T) {
+	uncached := s.GetSchema(t, "testing")
+	s.d.EnableSchemaCache()
+	defer s.d.DisableSchemaCache()
+
+	first := s.GetSchema(t, "testing")
+	second := s.GetSchema(t, "testing")
+	if first.Fingerprint(FingerprintOptions{}) != uncached.Fingerprint(FingerprintOptions{}) {
+		t.Error("Schema populating the cache unexpectedly differs from prior introspection")
+	}
+	if second.Fingerprint(FingerprintOptions{}) != first.Fingerprint(FingerprintOptions{}) {
+		t.Error("Schema introspected from cache unexpectedly differs from initial introspection")
+	}
+
+	// Modifying the returned schema must not affect the cache
+	second.Tables[0].Comment = "modified"
+	if third := s.GetSchema(t, "testing"); third.Tables[0].Comment == "modified" {
+		t.Error("Modification of returned schema unexpectedly affected cache")
+	}
+
+	// Tables created or dropped outside of tengo should be detected
+	db, err := s.d.Connect("testing", "")
+	if err != nil {
+		t.Fatalf("Unable to connect to DockerizedInstance: %s", err)
+	}
+	if _, err := db.Exec("CREATE TABLE cache_test (id int unsigned NOT NULL PRIMARY KEY)"); err != nil {
+		t.Fatalf("Unexpected error from CREATE TABLE: %s", err)
+	}
+	schema := s.GetSchema(t, "testing")
+	if !schema.HasTable("cache_test") || len(schema.Tables) != len(first.Tables)+1 {
+		t.Error("Expected newly-created table to be reflected in cached introspection")
+	}
+	if _, err := db.Exec("DROP TABLE cache_test"); err != nil {
+		t.Fatalf("Unexpected error from DROP TABLE: %s", err)
+	}
+	schema = s.GetSchema(t, "testing")
+	if schema.HasTable("cache_test") || schema.Fingerprint(FingerprintOptions{}) != first.Fingerprint(FingerprintOptions{}) {
+		t.Error("Expected dropped table to be reflected in cached introspection")
+	}
+
+	// DDL run through tengo should invalidate the cache
+	if err := s.d.DropTablesInSchema("testing", false); err != nil {
+		t.Fatalf("Unexpected error from DropTablesInSchema: %s", err)
+	}
+	s.d.schemaCache.Lock()
+	_, cached := s.d.schemaCache.entries["testing"]
+	s.d.schemaCache.Unlock()
+	if cached {
+		t.Error("Expected DropTablesInSchema to invalidate cache entry")
+	}
+	if schema = s.GetSchema(t, "testing"); len(schema.Tables) != 0 {
+		t.Errorf("Expected no tables after DropTablesInSchema, instead found %d", len(schema.Tables))
+	}
+}
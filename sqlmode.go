@@ -0,0 +1,433 @@
+package tengo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SQLModeProblem describes an object which would fail to be created, or would
+// behave differently than expected, if created under a particular sql_mode. It
+// satisfies the builtin error interface.
+type SQLModeProblem struct {
+	SchemaName string
+	Object     ObjectKey
+	Mode       string // the sql_mode value responsible for the problem
+	Fatal      bool   // true if creating the object would fail; false if it would only behave differently
+	Detail     string
+}
+
+// Error satisfies the builtin error interface.
+func (p SQLModeProblem) Error() string {
+	typ := string(p.Object.Type)
+	if typ != "" {
+		typ = strings.ToUpper(typ[0:1]) + typ[1:]
+	}
+	return fmt.Sprintf("%s %s.%s: %s: %s", typ, EscapeIdentifier(p.SchemaName), EscapeIdentifier(p.Object.Name), p.Mode, p.Detail)
+}
+
+// sqlModeCombinations maps combination modes to the individual modes they
+// represent, as of MySQL 8.0.
+var sqlModeCombinations = map[string][]string{
+	"ANSI":        {"REAL_AS_FLOAT", "PIPES_AS_CONCAT", "ANSI_QUOTES", "IGNORE_SPACE", "ONLY_FULL_GROUP_BY"},
+	"TRADITIONAL": {"STRICT_TRANS_TABLES", "STRICT_ALL_TABLES", "NO_ZERO_IN_DATE", "NO_ZERO_DATE", "ERROR_FOR_DIVISION_BY_ZERO", "NO_ENGINE_SUBSTITUTION"},
+}
+
+// parseSQLMode converts a comma-separated sql_mode string into a set of
+// individual uppercased modes, expanding any combination modes.
+func parseSQLMode(sqlMode string) map[string]bool {
+	modes := make(map[string]bool)
+	for _, mode := range strings.Split(sqlMode, ",") {
+		mode = strings.ToUpper(strings.TrimSpace(mode))
+		if mode == "" {
+			continue
+		}
+		if parts, ok := sqlModeCombinations[mode]; ok {
+			for _, part := range parts {
+				modes[part] = true
+			}
+		} else {
+			modes[mode] = true
+		}
+	}
+	return modes
+}
+
+// sqlModeDifference returns a sorted list of modes present in a but not b.
+func sqlModeDifference(a, b map[string]bool) []string {
+	var result []string
+	for mode := range a {
+		if !b[mode] {
+			result = append(result, mode)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// ValidateSQLMode checks the tables and routines in the supplied schemas for
+// problems that would occur if they were created under sqlMode. This includes
+// column default values rejected by strict mode, NO_ZERO_DATE, or
+// NO_ZERO_IN_DATE; routines whose bodies use double-quoted strings, which
+// ANSI_QUOTES would treat as identifiers; routines with queries which appear to
+// violate ONLY_FULL_GROUP_BY; and routines originally created under a
+// different sql_mode, since a routine always executes using the sql_mode in
+// effect at its creation time.
+//
+// The checks of routine bodies are heuristic: in particular, ONLY_FULL_GROUP_BY
+// detection does not consider functional dependencies on unique keys.
+func ValidateSQLMode(sqlMode string, schemas ...*Schema) []SQLModeProblem {
+	modes := parseSQLMode(sqlMode)
+	var problems []SQLModeProblem
+	for _, s := range schemas {
+		for _, t := range s.Tables {
+			key := ObjectKey{Type: ObjectTypeTable, Name: t.Name}
+			for _, col := range t.Columns {
+				if mode, detail := columnDefaultSQLModeProblem(col, modes); mode != "" {
+					problems = append(problems, SQLModeProblem{SchemaName: s.Name, Object: key, Mode: mode, Fatal: true, Detail: detail})
+				}
+			}
+		}
+		for _, r := range s.Routines {
+			key := ObjectKey{Type: r.Type, Name: r.Name}
+			for _, p := range routineSQLModeProblems(r, modes) {
+				p.SchemaName, p.Object = s.Name, key
+				problems = append(problems, p)
+			}
+		}
+	}
+	return problems
+}
+
+// ValidateSQLMode checks the supplied schemas for problems that would occur if
+// they were created on instance, using the sql_mode of a new session with the
+// instance's default connection parameters. See the package-level
+// ValidateSQLMode for more information.
+func (instance *Instance) ValidateSQLMode(schemas []*Schema) ([]SQLModeProblem, error) {
+	return instance.ValidateSQLModeContext(context.Background(), schemas)
+}
+
+// ValidateSQLModeContext is like ValidateSQLMode, but the supplied context is
+// used for querying the sql_mode.
+func (instance *Instance) ValidateSQLModeContext(ctx context.Context, schemas []*Schema) ([]SQLModeProblem, error) {
+	db, err := instance.ConnectContext(ctx, "", "")
+	if err != nil {
+		return nil, err
+	}
+	var sqlMode string
+	if err := db.QueryRowContext(ctx, "SELECT @@session.sql_mode").Scan(&sqlMode); err != nil {
+		return nil, err
+	}
+	return ValidateSQLMode(sqlMode, schemas...), nil
+}
+
+// columnDefaultSQLModeProblem checks whether col's default value would be
+// rejected under the supplied modes. If so, the responsible mode and a
+// description are returned; otherwise, both return values are blank. Since
+// NO_ZERO_DATE and NO_ZERO_IN_DATE only cause errors in combination with
+// strict mode, no problems are reported unless strict mode is enabled.
+func columnDefaultSQLModeProblem(col *Column, modes map[string]bool) (mode, detail string) {
+	var strictMode string
+	if modes["STRICT_ALL_TABLES"] {
+		strictMode = "STRICT_ALL_TABLES"
+	} else if modes["STRICT_TRANS_TABLES"] {
+		strictMode = "STRICT_TRANS_TABLES"
+	}
+	if strictMode == "" || col.Default.Null || !col.Default.Quoted {
+		return "", ""
+	}
+	value := col.Default.Value
+	colName := EscapeIdentifier(col.Name)
+	baseType := col.TypeInDB
+	if end := strings.IndexAny(baseType, "( "); end > -1 {
+		baseType = baseType[0:end]
+	}
+
+	switch baseType {
+	case "date", "datetime", "timestamp":
+		zeroDate, zeroInDate, valid := parseDefaultDate(value)
+		if zeroDate {
+			if modes["NO_ZERO_DATE"] {
+				return "NO_ZERO_DATE", fmt.Sprintf("column %s has zero date default '%s'", colName, value)
+			}
+		} else if zeroInDate {
+			if modes["NO_ZERO_IN_DATE"] {
+				return "NO_ZERO_IN_DATE", fmt.Sprintf("column %s has default '%s' with zero month or day", colName, value)
+			}
+		} else if !valid && !modes["ALLOW_INVALID_DATES"] {
+			return strictMode, fmt.Sprintf("column %s has invalid date default '%s'", colName, value)
+		}
+	case "char", "varchar", "binary", "varbinary":
+		if open := strings.IndexByte(col.TypeInDB, '('); open > -1 {
+			length, err := strconv.Atoi(strings.TrimRight(col.TypeInDB[open+1:], ") "))
+			if err == nil && len([]rune(value)) > length {
+				return strictMode, fmt.Sprintf("column %s default '%s' exceeds length %d", colName, value, length)
+			}
+		}
+	default:
+		if maxValue, ok := col.MaxIntValue(); ok {
+			_, unsigned := col.intTypeRank()
+			if n, err := strconv.ParseInt(value, 10, 64); err == nil && n < 0 {
+				if unsigned || uint64(-(n+1)) > maxValue {
+					return strictMode, fmt.Sprintf("column %s default %s is out of range", colName, value)
+				}
+			} else if n, err := strconv.ParseUint(value, 10, 64); err != nil || n > maxValue {
+				return strictMode, fmt.Sprintf("column %s default %s is out of range", colName, value)
+			}
+		}
+	}
+	return "", ""
+}
+
+// parseDefaultDate examines a date, datetime, or timestamp default value.
+// zeroDate is true if all date and time components are zero; zeroInDate is
+// true if only the month and/or day are zero; valid is true if the value is
+// otherwise a real calendar date with a valid time.
+func parseDefaultDate(value string) (zeroDate, zeroInDate, valid bool) {
+	var year, month, day, hour, min, sec int
+	datePart, timePart := value, "00:00:00"
+	if space := strings.IndexByte(value, ' '); space > -1 {
+		datePart, timePart = value[0:space], value[space+1:]
+	}
+	if dot := strings.IndexByte(timePart, '.'); dot > -1 {
+		timePart = timePart[0:dot]
+	}
+	if n, _ := fmt.Sscanf(datePart, "%d-%d-%d", &year, &month, &day); n != 3 {
+		return false, false, false
+	}
+	if n, _ := fmt.Sscanf(timePart, "%d:%d:%d", &hour, &min, &sec); n != 3 {
+		return false, false, false
+	}
+	if year == 0 && month == 0 && day == 0 && hour == 0 && min == 0 && sec == 0 {
+		return true, false, false
+	} else if month == 0 || day == 0 {
+		return false, true, false
+	}
+	t := time.Date(year, time.Month(month), day, hour, min, sec, 0, time.UTC)
+	valid = (t.Year() == year && int(t.Month()) == month && t.Day() == day && t.Hour() == hour && t.Minute() == min && t.Second() == sec)
+	return false, false, valid
+}
+
+// routineSQLModeProblems checks a routine for problems under the supplied
+// modes. The returned problems do not have their SchemaName or Object set.
+func routineSQLModeProblems(r *Routine, modes map[string]bool) (problems []SQLModeProblem) {
+	routineModes := parseSQLMode(r.SQLMode)
+	added := sqlModeDifference(modes, routineModes)
+	removed := sqlModeDifference(routineModes, modes)
+	if len(added) > 0 || len(removed) > 0 {
+		var changes []string
+		if len(added) > 0 {
+			changes = append(changes, fmt.Sprintf("adds %s", strings.Join(added, ",")))
+		}
+		if len(removed) > 0 {
+			changes = append(changes, fmt.Sprintf("removes %s", strings.Join(removed, ",")))
+		}
+		problems = append(problems, SQLModeProblem{
+			Mode:   strings.Join(append(added, removed...), ","),
+			Detail: fmt.Sprintf("routine was created with sql_mode '%s'; re-creating it %s", r.SQLMode, strings.Join(changes, " and ")),
+		})
+	}
+
+	if modes["ANSI_QUOTES"] && !routineModes["ANSI_QUOTES"] {
+		if strs, err := doubleQuotedStrings(r.Body); err == nil && len(strs) > 0 {
+			problems = append(problems, SQLModeProblem{
+				Mode:   "ANSI_QUOTES",
+				Detail: fmt.Sprintf("body contains double-quoted string \"%s\", which would be treated as an identifier", strs[0]),
+			})
+		}
+	}
+
+	if modes["ONLY_FULL_GROUP_BY"] {
+		locals := make(map[string]bool)
+		if params, err := r.Params(); err == nil {
+			for _, param := range params {
+				locals[strings.ToLower(param.Name)] = true
+			}
+		}
+		if tokens, err := tokenizeSQL(r.Body); err == nil {
+			for n, tok := range tokens {
+				if tok.isKeyword("DECLARE") {
+					for pos := n + 1; pos < len(tokens) && tokens[pos].typ != sqlTokenSymbol; pos += 2 {
+						locals[strings.ToLower(tokens[pos].val)] = true
+						if pos+1 >= len(tokens) || !tokens[pos+1].isSymbol(",") {
+							break
+						}
+					}
+				}
+			}
+			for _, col := range nonGroupedColumns(tokens, locals) {
+				problems = append(problems, SQLModeProblem{
+					Mode:   "ONLY_FULL_GROUP_BY",
+					Detail: fmt.Sprintf("query selects %s, which is neither aggregated nor in GROUP BY", col),
+				})
+			}
+		}
+	}
+	return problems
+}
+
+// aggregateFunctions contains the names of MySQL's aggregate functions.
+var aggregateFunctions = map[string]bool{
+	"AVG": true, "BIT_AND": true, "BIT_OR": true, "BIT_XOR": true, "COUNT": true,
+	"GROUP_CONCAT": true, "JSON_ARRAYAGG": true, "JSON_OBJECTAGG": true, "MAX": true,
+	"MIN": true, "STD": true, "STDDEV": true, "STDDEV_POP": true, "STDDEV_SAMP": true,
+	"SUM": true, "VAR_POP": true, "VAR_SAMP": true, "VARIANCE": true,
+}
+
+// selectClauseEnd contains keywords which end the FROM, WHERE, or GROUP BY
+// clause of a SELECT.
+var selectClauseEnd = map[string]bool{
+	"HAVING": true, "ORDER": true, "LIMIT": true, "WINDOW": true, "WITH": true,
+	"UNION": true, "INTO": true, "FOR": true, "LOCK": true, "SELECT": true,
+}
+
+// selectModifiers contains keywords which may precede the select list.
+var selectModifiers = map[string]bool{
+	"ALL": true, "DISTINCT": true, "DISTINCTROW": true, "HIGH_PRIORITY": true,
+	"STRAIGHT_JOIN": true, "SQL_SMALL_RESULT": true, "SQL_BIG_RESULT": true,
+	"SQL_BUFFER_RESULT": true, "SQL_CACHE": true, "SQL_NO_CACHE": true,
+	"SQL_CALC_FOUND_ROWS": true,
+}
+
+// nonColumnWords contains keywords which may appear alone in a select list,
+// but are not column references.
+var nonColumnWords = map[string]bool{
+	"NULL": true, "TRUE": true, "FALSE": true, "DEFAULT": true,
+	"CURRENT_DATE": true, "CURRENT_TIME": true, "CURRENT_TIMESTAMP": true,
+	"CURRENT_USER": true, "LOCALTIME": true, "LOCALTIMESTAMP": true,
+	"UTC_DATE": true, "UTC_TIME": true, "UTC_TIMESTAMP": true,
+}
+
+// nonGroupedColumns scans tokens for SELECT queries which group rows, either
+// with GROUP BY or by using an aggregate function, and returns any plain
+// column references in the select list which are neither aggregated nor
+// present in the GROUP BY clause. Names in locals, such as routine params and
+// local variables, are ignored. Only simple column references are examined;
+// other expressions are assumed to be valid.
+func nonGroupedColumns(tokens []sqlToken, locals map[string]bool) (result []string) {
+	const (
+		inSelectList = iota
+		inFrom
+		inGroupBy
+	)
+	for start := range tokens {
+		if !tokens[start].isKeyword("SELECT") {
+			continue
+		}
+		var exprs, groupBy [][]sqlToken
+		var current []sqlToken
+		var hasAggregate bool
+		var subqueryParens []bool // for each open paren, whether it begins a subquery
+		var subqueryDepth int
+		state := inSelectList
+	scan:
+		for pos := start + 1; pos < len(tokens); pos++ {
+			tok := tokens[pos]
+			depth := len(subqueryParens)
+			if tok.isSymbol("(") {
+				isSubquery := pos+1 < len(tokens) && tokens[pos+1].isKeyword("SELECT")
+				subqueryParens = append(subqueryParens, isSubquery)
+				if isSubquery {
+					subqueryDepth++
+				}
+			} else if tok.isSymbol(")") {
+				if depth == 0 {
+					break
+				}
+				if subqueryParens[depth-1] {
+					subqueryDepth--
+				}
+				subqueryParens = subqueryParens[0 : depth-1]
+			} else if depth == 0 {
+				switch {
+				case tok.isSymbol(";"):
+					break scan
+				case state == inSelectList && (tok.isKeyword("FROM") || tok.isKeyword("INTO")):
+					exprs, current, state = append(exprs, current), nil, inFrom
+					continue
+				case state == inSelectList && tok.isSymbol(","):
+					exprs, current = append(exprs, current), nil
+					continue
+				case state == inFrom && tok.isKeyword("GROUP") && pos+1 < len(tokens) && tokens[pos+1].isKeyword("BY"):
+					state = inGroupBy
+					pos++
+					continue
+				case state == inGroupBy && tok.isSymbol(","):
+					groupBy, current = append(groupBy, current), nil
+					continue
+				case state != inSelectList && tok.typ == sqlTokenWord && selectClauseEnd[strings.ToUpper(tok.val)]:
+					break scan
+				}
+			}
+			if state == inSelectList {
+				if subqueryDepth == 0 && tok.typ == sqlTokenWord && aggregateFunctions[strings.ToUpper(tok.val)] && pos+1 < len(tokens) && tokens[pos+1].isSymbol("(") {
+					hasAggregate = true
+				}
+				current = append(current, tok)
+			} else if state == inGroupBy {
+				current = append(current, tok)
+			}
+		}
+		if state == inSelectList {
+			continue // SELECT without FROM, or unterminated
+		} else if state == inGroupBy {
+			groupBy = append(groupBy, current)
+		} else if !hasAggregate {
+			continue // not a grouped query
+		}
+
+		grouped := make(map[string]bool)
+		for _, expr := range groupBy {
+			if name, _, ok := simpleColumnRef(expr); ok {
+				grouped[strings.ToLower(name)] = true
+			}
+		}
+		for _, expr := range exprs {
+			name, alias, ok := simpleColumnRef(expr)
+			lowerName := strings.ToLower(name)
+			if !ok || locals[lowerName] || grouped[lowerName] || (alias != "" && grouped[strings.ToLower(alias)]) {
+				continue
+			}
+			result = append(result, EscapeIdentifier(name))
+		}
+	}
+	return result
+}
+
+// simpleColumnRef returns the column name and alias (if any) of expr, if it
+// consists solely of an optionally-qualified column name followed by an
+// optional alias. The bool return value is false for any other expression.
+func simpleColumnRef(expr []sqlToken) (name, alias string, ok bool) {
+	isName := func(tok sqlToken) bool {
+		if tok.typ == sqlTokenIdentifier {
+			return true
+		}
+		return tok.typ == sqlTokenWord && tok.val[0] > '9' && !nonColumnWords[strings.ToUpper(tok.val)]
+	}
+	for len(expr) > 0 && expr[0].typ == sqlTokenWord && selectModifiers[strings.ToUpper(expr[0].val)] {
+		expr = expr[1:]
+	}
+	if len(expr) == 0 || !isName(expr[0]) {
+		return "", "", false
+	}
+	name = expr[0].val
+	pos := 1
+	for pos+1 < len(expr) && expr[pos].isSymbol(".") && isName(expr[pos+1]) {
+		name = expr[pos+1].val
+		pos += 2
+	}
+	if pos < len(expr) && expr[pos].isKeyword("AS") {
+		pos++
+	}
+	if pos < len(expr) && (isName(expr[pos]) || expr[pos].typ == sqlTokenString) {
+		alias = expr[pos].val
+		pos++
+	}
+	if pos != len(expr) {
+		return "", "", false
+	}
+	return name, alias, true
+}
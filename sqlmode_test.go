@@ -0,0 +1,139 @@
+package tengo
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSQLMode(t *testing.T) {
+	modes := parseSQLMode("traditional, ansi_quotes,,NO_ENGINE_SUBSTITUTION")
+	for _, mode := range []string{"STRICT_TRANS_TABLES", "STRICT_ALL_TABLES", "NO_ZERO_DATE", "ANSI_QUOTES", "NO_ENGINE_SUBSTITUTION"} {
+		if !modes[mode] {
+			t.Errorf("Expected mode %s to be set, but it was not", mode)
+		}
+	}
+	if modes["TRADITIONAL"] || modes[""] || len(modes) != 7 {
+		t.Errorf("Unexpected result from parseSQLMode: %v", modes)
+	}
+	if modes := parseSQLMode(""); len(modes) != 0 {
+		t.Errorf("Expected empty sql_mode to yield no modes, instead found %v", modes)
+	}
+}
+
+func TestColumnDefaultSQLModeProblem(t *testing.T) {
+	strict := parseSQLMode("STRICT_TRANS_TABLES,NO_ZERO_DATE,NO_ZERO_IN_DATE")
+	cases := []struct {
+		typ, value   string
+		expectedMode string
+	}{
+		{"date", "0000-00-00", "NO_ZERO_DATE"},
+		{"datetime(3)", "0000-00-00 00:00:00.000", "NO_ZERO_DATE"},
+		{"timestamp", "2019-00-10 00:00:00", "NO_ZERO_IN_DATE"},
+		{"date", "2019-02-30", "STRICT_TRANS_TABLES"},
+		{"datetime", "2019-02-28 24:00:00", "STRICT_TRANS_TABLES"},
+		{"date", "2020-02-29", ""},
+		{"varchar(3)", "abcd", "STRICT_TRANS_TABLES"},
+		{"char(4)", "abcd", ""},
+		{"tinyint(4)", "-129", "STRICT_TRANS_TABLES"},
+		{"tinyint(4)", "-128", ""},
+		{"tinyint(3) unsigned", "-1", "STRICT_TRANS_TABLES"},
+		{"tinyint(3) unsigned", "256", "STRICT_TRANS_TABLES"},
+		{"bigint(20) unsigned", "18446744073709551615", ""},
+		{"int(11)", "abc", "STRICT_TRANS_TABLES"},
+		{"decimal(5,2)", "123.45", ""},
+	}
+	for _, c := range cases {
+		col := &Column{Name: "c", TypeInDB: c.typ, Default: ColumnDefaultValue(c.value)}
+		if mode, detail := columnDefaultSQLModeProblem(col, strict); mode != c.expectedMode {
+			t.Errorf("Column type %s default '%s': expected mode %q, instead found %q (%s)", c.typ, c.value, c.expectedMode, mode, detail)
+		}
+	}
+
+	// Nothing reported without strict mode, or for non-quoted defaults
+	col := &Column{Name: "c", TypeInDB: "date", Default: ColumnDefaultValue("0000-00-00")}
+	if mode, _ := columnDefaultSQLModeProblem(col, parseSQLMode("NO_ZERO_DATE")); mode != "" {
+		t.Errorf("Expected no problem without strict mode, instead found %s", mode)
+	}
+	col = &Column{Name: "c", TypeInDB: "timestamp", Default: ColumnDefaultExpression("CURRENT_TIMESTAMP")}
+	if mode, _ := columnDefaultSQLModeProblem(col, strict); mode != "" {
+		t.Errorf("Expected no problem for CURRENT_TIMESTAMP default, instead found %s", mode)
+	}
+}
+
+func TestNonGroupedColumns(t *testing.T) {
+	cases := map[string][]string{
+		"SELECT a, COUNT(*) FROM t GROUP BY a":                                nil,
+		"SELECT a, b, COUNT(*) FROM t GROUP BY a":                             {"`b`"},
+		"SELECT t.a, t.b AS bee FROM t GROUP BY a, bee":                       nil,
+		"SELECT DISTINCT a, MAX(b) FROM t":                                    {"`a`"},
+		"SELECT a, b FROM t WHERE x = 1 ORDER BY a":                           nil,
+		"SELECT a, SUM(b) INTO x, y FROM t":                                   {"`a`"},
+		"SELECT a, (SELECT MAX(c) FROM u) FROM t":                             nil,
+		"SELECT a, UPPER(b) FROM t GROUP BY a":                                nil,
+		"SELECT NULL, COUNT(*) FROM t":                                        nil,
+		"SELECT 1, `weird col` FROM t GROUP BY 1":                             {"`weird col`"},
+		"INSERT INTO s SELECT a, b FROM t GROUP BY a; SELECT c FROM t":        {"`b`"},
+		"SELECT x FROM (SELECT a, b FROM t GROUP BY a) sub GROUP BY x":        {"`b`"},
+		"SELECT param1, c, COUNT(*) FROM t GROUP BY c WITH ROLLUP":            nil,
+		"SELECT a, MAX(b) FROM t GROUP BY a HAVING MAX(b) > 1 UNION SELECT 1": nil,
+	}
+	locals := map[string]bool{"param1": true}
+	for input, expected := range cases {
+		tokens, err := tokenizeSQL(input)
+		if err != nil {
+			t.Fatalf("Unexpected error from tokenizeSQL: %s", err)
+		}
+		if actual := nonGroupedColumns(tokens, locals); !reflect.DeepEqual(actual, expected) {
+			t.Errorf("nonGroupedColumns for %q: expected %v, found %v", input, expected, actual)
+		}
+	}
+}
+
+func TestValidateSQLMode(t *testing.T) {
+	table := aTable(1)
+	table.Columns = append(table.Columns, &Column{Name: "created", TypeInDB: "date", Default: ColumnDefaultValue("0000-00-00")})
+	s := aSchema("s1", &table)
+	proc := aProc("latin1_swedish_ci", "STRICT_TRANS_TABLES,NO_ZERO_DATE")
+	proc.Body = "BEGIN\n  SELECT name, iterations, COUNT(*) FROM t WHERE x = \"quoted\" GROUP BY iterations;\nEND"
+	s.Routines = []*Routine{&proc}
+
+	problems := ValidateSQLMode("ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_DATE,ANSI_QUOTES", &s)
+	expected := []string{
+		"Table `s1`.`actor`: NO_ZERO_DATE: column `created` has zero date default '0000-00-00'",
+		"Procedure `s1`.`proc1`: ANSI_QUOTES,ONLY_FULL_GROUP_BY: routine was created with sql_mode 'STRICT_TRANS_TABLES,NO_ZERO_DATE'; re-creating it adds ANSI_QUOTES,ONLY_FULL_GROUP_BY",
+		"Procedure `s1`.`proc1`: ANSI_QUOTES: body contains double-quoted string \"quoted\", which would be treated as an identifier",
+	}
+	var actual []string
+	for _, p := range problems {
+		actual = append(actual, p.Error())
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Unexpected result from ValidateSQLMode:\n%s", strings.Join(actual, "\n"))
+	}
+	if !problems[0].Fatal || problems[1].Fatal {
+		t.Error("Fatal field not set as expected")
+	}
+
+	// name is a param, but declared locals are also excluded, while undeclared
+	// non-grouped columns are reported
+	proc.Body = "BEGIN\n  DECLARE a, b INT;\n  SELECT a, b, c, MAX(d) FROM t;\nEND"
+	proc.SQLMode = "ONLY_FULL_GROUP_BY"
+	problems = ValidateSQLMode("ONLY_FULL_GROUP_BY", &s)
+	if len(problems) != 1 || problems[0].Mode != "ONLY_FULL_GROUP_BY" || !strings.Contains(problems[0].Detail, "`c`") {
+		t.Errorf("Unexpected result from ValidateSQLMode: %+v", problems)
+	}
+}
+
+func (s TengoIntegrationSuite) TestInstanceValidateSQLMode(t *testing.T) {
+	schema := s.GetSchema(t, "testing")
+	problems, err := s.d.ValidateSQLMode([]*Schema{schema})
+	if err != nil {
+		t.Fatalf("Unexpected error from ValidateSQLMode: %s", err)
+	}
+	for _, p := range problems {
+		if p.Fatal {
+			t.Errorf("Unexpected fatal problem in testing schema: %s", p)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package tengo
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Apply returns a new Table reflecting the result of applying the supplied
+// alter clauses to the table, as if they were all part of a single ALTER
+// TABLE statement. The receiver is not modified. The returned table's
+// CreateStatement is regenerated for the supplied flavor.
+//
+// As with a real ALTER TABLE, drops of indexes and foreign keys refer to the
+// original table, and are processed before any column changes; column changes
+// are processed in order; and new indexes and foreign keys are added last.
+// Dropping a column also removes it from any indexes, dropping indexes which
+// no longer contain any columns. New secondary indexes are placed after all
+// preexisting ones, and foreign keys are sorted by name.
+//
+// Statement modifiers are not considered, so clauses which would ordinarily be
+// suppressed (for example, an index drop and re-add which only affects index
+// order) are still applied. An error is returned if a clause cannot be applied,
+// or if the table uses features not supported by this package.
+func (t *Table) Apply(flavor Flavor, clauses ...TableAlterClause) (*Table, error) {
+	if t.UnsupportedDDL {
+		return nil, fmt.Errorf("Table %s uses unsupported features and cannot be altered in-memory", EscapeIdentifier(t.Name))
+	}
+	result := t.clone()
+
+	// Process index and foreign key drops first, since these refer to names in
+	// the original table
+	for _, clause := range clauses {
+		var err error
+		switch clause := clause.(type) {
+		case DropIndex:
+			err = result.applyDropIndex(clause)
+		case DropForeignKey:
+			err = result.applyDropForeignKey(clause)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Next process column changes and table-level options
+	for _, clause := range clauses {
+		var err error
+		switch clause := clause.(type) {
+		case DropIndex, DropForeignKey, AddIndex, AddForeignKey:
+			// handled in other loops
+		case AddColumn:
+			err = result.applyAddColumn(clause)
+		case DropColumn:
+			err = result.applyDropColumn(clause)
+		case ModifyColumn:
+			err = result.applyModifyColumn(clause)
+		case RenameColumn:
+			err = result.applyRenameColumn(clause)
+		case ChangeAutoIncrement:
+			result.NextAutoIncrement = clause.NewNextAutoIncrement
+		case ChangeCharSet:
+			result.CharSet = clause.CharSet
+			result.Collation = clause.Collation
+			if result.Collation == "" {
+				result.Collation = flavor.DefaultCollation(clause.CharSet)
+			}
+			result.CollationIsDefault = (result.Collation == flavor.DefaultCollation(clause.CharSet))
+		case ChangeCreateOptions:
+			result.CreateOptions = clause.NewCreateOptions
+		case ChangeComment:
+			result.Comment = clause.NewComment
+		case ChangeStorageEngine:
+			result.Engine = clause.NewStorageEngine
+		default:
+			err = fmt.Errorf("Unable to apply clause of type %T", clause)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Finally process index and foreign key additions, which may refer to new
+	// columns
+	for _, clause := range clauses {
+		var err error
+		switch clause := clause.(type) {
+		case AddIndex:
+			err = result.applyAddIndex(clause)
+		case AddForeignKey:
+			err = result.applyAddForeignKey(clause)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(result.ForeignKeys, func(i, j int) bool {
+		return result.ForeignKeys[i].Name < result.ForeignKeys[j].Name
+	})
+
+	if !result.HasAutoIncrement() {
+		result.NextAutoIncrement = 0
+	}
+	result.CreateStatement = result.GeneratedCreateStatement(flavor)
+	return result, nil
+}
+
+// clone returns a deep copy of the table. Indexes and foreign keys in the copy
+// point to the copy's columns.
+func (t *Table) clone() *Table {
+	result := *t
+	result.Columns = make([]*Column, len(t.Columns))
+	for n, col := range t.Columns {
+		colCopy := *col
+		result.Columns[n] = &colCopy
+	}
+	if t.PrimaryKey != nil {
+		result.PrimaryKey = result.remapIndex(t.PrimaryKey)
+	}
+	result.SecondaryIndexes = make([]*Index, len(t.SecondaryIndexes))
+	for n, idx := range t.SecondaryIndexes {
+		result.SecondaryIndexes[n] = result.remapIndex(idx)
+	}
+	result.ForeignKeys = make([]*ForeignKey, len(t.ForeignKeys))
+	for n, fk := range t.ForeignKeys {
+		result.ForeignKeys[n] = result.remapForeignKey(fk)
+	}
+	return &result
+}
+
+// remapIndex returns a copy of idx which points to the table's columns of the
+// same names. Columns not found in the table are left as-is.
+func (t *Table) remapIndex(idx *Index) *Index {
+	idxCopy := *idx
+	idxCopy.Columns = make([]*Column, len(idx.Columns))
+	idxCopy.SubParts = make([]uint16, len(idx.Columns))
+	copy(idxCopy.SubParts, idx.SubParts)
+	colsByName := t.ColumnsByName()
+	for n, col := range idx.Columns {
+		if ownCol, ok := colsByName[col.Name]; ok {
+			idxCopy.Columns[n] = ownCol
+		} else {
+			idxCopy.Columns[n] = col
+		}
+	}
+	return &idxCopy
+}
+
+// remapForeignKey returns a copy of fk which points to the table's columns of
+// the same names. Columns not found in the table are left as-is.
+func (t *Table) remapForeignKey(fk *ForeignKey) *ForeignKey {
+	fkCopy := *fk
+	fkCopy.Columns = make([]*Column, len(fk.Columns))
+	fkCopy.ReferencedColumnNames = make([]string, len(fk.ReferencedColumnNames))
+	copy(fkCopy.ReferencedColumnNames, fk.ReferencedColumnNames)
+	colsByName := t.ColumnsByName()
+	for n, col := range fk.Columns {
+		if ownCol, ok := colsByName[col.Name]; ok {
+			fkCopy.Columns[n] = ownCol
+		} else {
+			fkCopy.Columns[n] = col
+		}
+	}
+	return &fkCopy
+}
+
+// columnPosition returns the position of the named column in t.Columns, or -1
+// if no such column exists.
+func (t *Table) columnPosition(name string) int {
+	for n, col := range t.Columns {
+		if col.Name == name {
+			return n
+		}
+	}
+	return -1
+}
+
+// insertColumn inserts col into t.Columns at the requested position: first, after
+// another column, or at the end by default.
+func (t *Table) insertColumn(col *Column, first bool, after *Column) error {
+	pos := len(t.Columns)
+	if first {
+		pos = 0
+	} else if after != nil {
+		if pos = t.columnPosition(after.Name); pos < 0 {
+			return fmt.Errorf("Unable to position column %s after nonexistent column %s", EscapeIdentifier(col.Name), EscapeIdentifier(after.Name))
+		}
+		pos++
+	}
+	t.Columns = append(t.Columns, nil)
+	copy(t.Columns[pos+1:], t.Columns[pos:])
+	t.Columns[pos] = col
+	return nil
+}
+
+func (t *Table) applyAddColumn(clause AddColumn) error {
+	if t.columnPosition(clause.Column.Name) >= 0 {
+		return fmt.Errorf("Unable to add column %s: column already exists", EscapeIdentifier(clause.Column.Name))
+	}
+	colCopy := *clause.Column
+	return t.insertColumn(&colCopy, clause.PositionFirst, clause.PositionAfter)
+}
+
+func (t *Table) applyDropColumn(clause DropColumn) error {
+	pos := t.columnPosition(clause.Column.Name)
+	if pos < 0 {
+		return fmt.Errorf("Unable to drop column %s: column does not exist", EscapeIdentifier(clause.Column.Name))
+	}
+	col := t.Columns[pos]
+	for _, fk := range t.ForeignKeys {
+		for _, fkCol := range fk.Columns {
+			if fkCol == col {
+				return fmt.Errorf("Unable to drop column %s: column is used by foreign key %s", EscapeIdentifier(col.Name), EscapeIdentifier(fk.Name))
+			}
+		}
+	}
+	t.Columns = append(t.Columns[:pos], t.Columns[pos+1:]...)
+
+	// Remove the column from any indexes containing it, and remove any indexes
+	// which no longer have any columns
+	if t.PrimaryKey != nil && !t.PrimaryKey.removeColumn(col) {
+		t.PrimaryKey = nil
+	}
+	keptIndexes := make([]*Index, 0, len(t.SecondaryIndexes))
+	for _, idx := range t.SecondaryIndexes {
+		if idx.removeColumn(col) {
+			keptIndexes = append(keptIndexes, idx)
+		}
+	}
+	t.SecondaryIndexes = keptIndexes
+	return nil
+}
+
+// removeColumn removes col from the index, if present. It returns false if the
+// index no longer has any columns.
+func (idx *Index) removeColumn(col *Column) bool {
+	for n := 0; n < len(idx.Columns); n++ {
+		if idx.Columns[n] == col {
+			idx.Columns = append(idx.Columns[:n], idx.Columns[n+1:]...)
+			idx.SubParts = append(idx.SubParts[:n], idx.SubParts[n+1:]...)
+			n--
+		}
+	}
+	return len(idx.Columns) > 0
+}
+
+func (t *Table) applyModifyColumn(clause ModifyColumn) error {
+	pos := t.columnPosition(clause.OldColumn.Name)
+	if pos < 0 {
+		return fmt.Errorf("Unable to modify column %s: column does not exist", EscapeIdentifier(clause.OldColumn.Name))
+	}
+	if clause.NewColumn.Name != clause.OldColumn.Name {
+		return fmt.Errorf("Unable to modify column %s: column name cannot be changed by MODIFY COLUMN", EscapeIdentifier(clause.OldColumn.Name))
+	}
+
+	// Modify the column in-place, so that indexes and foreign keys continue to
+	// point to it
+	col := t.Columns[pos]
+	*col = *clause.NewColumn
+	if !clause.PositionFirst && clause.PositionAfter == nil {
+		return nil
+	}
+	if clause.PositionAfter != nil && clause.PositionAfter.Name == col.Name {
+		return fmt.Errorf("Unable to position column %s after itself", EscapeIdentifier(col.Name))
+	}
+	t.Columns = append(t.Columns[:pos], t.Columns[pos+1:]...)
+	return t.insertColumn(col, clause.PositionFirst, clause.PositionAfter)
+}
+
+func (t *Table) applyRenameColumn(clause RenameColumn) error {
+	pos := t.columnPosition(clause.OldColumn.Name)
+	if pos < 0 {
+		return fmt.Errorf("Unable to rename column %s: column does not exist", EscapeIdentifier(clause.OldColumn.Name))
+	}
+	if t.columnPosition(clause.NewName) >= 0 {
+		return fmt.Errorf("Unable to rename column %s to %s: column already exists", EscapeIdentifier(clause.OldColumn.Name), EscapeIdentifier(clause.NewName))
+	}
+	t.Columns[pos].Name = clause.NewName
+	return nil
+}
+
+func (t *Table) applyAddIndex(clause AddIndex) error {
+	idx := t.remapIndex(clause.Index)
+	colsByName := t.ColumnsByName()
+	for _, col := range idx.Columns {
+		if colsByName[col.Name] != col {
+			return fmt.Errorf("Unable to add index %s: column %s does not exist", EscapeIdentifier(idx.Name), EscapeIdentifier(col.Name))
+		}
+	}
+	if idx.PrimaryKey {
+		if t.PrimaryKey != nil {
+			return errors.New("Unable to add primary key: table already has a primary key")
+		}
+		t.PrimaryKey = idx
+		return nil
+	}
+	if _, exists := t.SecondaryIndexesByName()[idx.Name]; exists {
+		return fmt.Errorf("Unable to add index %s: index already exists", EscapeIdentifier(idx.Name))
+	}
+	t.SecondaryIndexes = append(t.SecondaryIndexes, idx)
+	return nil
+}
+
+func (t *Table) applyDropIndex(clause DropIndex) error {
+	if clause.Index.PrimaryKey {
+		if t.PrimaryKey == nil {
+			return errors.New("Unable to drop primary key: table has no primary key")
+		}
+		t.PrimaryKey = nil
+		return nil
+	}
+	for n, idx := range t.SecondaryIndexes {
+		if idx.Name == clause.Index.Name {
+			t.SecondaryIndexes = append(t.SecondaryIndexes[:n], t.SecondaryIndexes[n+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("Unable to drop index %s: index does not exist", EscapeIdentifier(clause.Index.Name))
+}
+
+func (t *Table) applyAddForeignKey(clause AddForeignKey) error {
+	fk := t.remapForeignKey(clause.ForeignKey)
+	colsByName := t.ColumnsByName()
+	for _, col := range fk.Columns {
+		if colsByName[col.Name] != col {
+			return fmt.Errorf("Unable to add foreign key %s: column %s does not exist", EscapeIdentifier(fk.Name), EscapeIdentifier(col.Name))
+		}
+	}
+	if _, exists := t.foreignKeysByName()[fk.Name]; exists {
+		return fmt.Errorf("Unable to add foreign key %s: foreign key already exists", EscapeIdentifier(fk.Name))
+	}
+	t.ForeignKeys = append(t.ForeignKeys, fk)
+	return nil
+}
+
+func (t *Table) applyDropForeignKey(clause DropForeignKey) error {
+	for n, fk := range t.ForeignKeys {
+		if fk.Name == clause.ForeignKey.Name {
+			t.ForeignKeys = append(t.ForeignKeys[:n], t.ForeignKeys[n+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("Unable to drop foreign key %s: foreign key does not exist", EscapeIdentifier(clause.ForeignKey.Name))
+}
@@ -0,0 +1,194 @@
+package tengo
+
+import (
+	"testing"
+)
+
+func TestTableApplyRoundTrip(t *testing.T) {
+	cases := []struct {
+		name   string
+		from   func() Table
+		mutate func(to *Table)
+	}{
+		{"add column at end", func() Table { return aTable(1) }, func(to *Table) {
+			to.Columns = append(to.Columns, &Column{Name: "age", TypeInDB: "int(10) unsigned", Nullable: true, Default: ColumnDefaultNull})
+		}},
+		{"add columns first and in middle", func() Table { return aTable(1) }, func(to *Table) {
+			first := &Column{Name: "net_worth", TypeInDB: "decimal(9,2)", Nullable: true, Default: ColumnDefaultNull}
+			middle := &Column{Name: "age", TypeInDB: "int(10) unsigned", Default: ColumnDefaultValue("0")}
+			cols := []*Column{first}
+			cols = append(cols, to.Columns[0:3]...)
+			cols = append(cols, middle)
+			to.Columns = append(cols, to.Columns[3:]...)
+		}},
+		{"drop indexed column", func() Table { return aTable(1) }, func(to *Table) {
+			to.Columns = append(to.Columns[0:4], to.Columns[5:]...)
+			to.SecondaryIndexes = to.SecondaryIndexes[1:]
+		}},
+		{"drop column used in multi-column index", func() Table { return aTable(1) }, func(to *Table) {
+			to.Columns = append(to.Columns[0:1], to.Columns[2:]...)
+			to.SecondaryIndexes[1].Columns = to.SecondaryIndexes[1].Columns[0:1]
+			to.SecondaryIndexes[1].SubParts = to.SecondaryIndexes[1].SubParts[0:1]
+		}},
+		{"reposition and modify columns", func() Table { return aTable(1) }, func(to *Table) {
+			moved := to.Columns[3]
+			to.Columns = append(to.Columns[:3], to.Columns[4:]...)
+			to.Columns = append([]*Column{moved}, to.Columns...)
+			to.Columns[2].TypeInDB = "varchar(100)"
+			to.Columns[6].Comment = "is alive"
+		}},
+		{"drop column and move another into its place", func() Table { return aTable(1) }, func(to *Table) {
+			last := to.Columns[6]
+			to.Columns = append(to.Columns[0:3], last, to.Columns[4], to.Columns[5])
+			to.Columns = append(to.Columns, &Column{Name: "age", TypeInDB: "int(10) unsigned", Nullable: true, Default: ColumnDefaultNull})
+		}},
+		{"add, drop, and reorder indexes", func() Table { return aTable(1) }, func(to *Table) {
+			to.SecondaryIndexes[0], to.SecondaryIndexes[1] = to.SecondaryIndexes[1], to.SecondaryIndexes[0]
+			to.SecondaryIndexes = append(to.SecondaryIndexes, &Index{
+				Name:     "idx_alive",
+				Columns:  []*Column{to.Columns[5], to.Columns[3]},
+				SubParts: []uint16{0, 0},
+			})
+		}},
+		{"change primary key", func() Table { return aTable(1) }, func(to *Table) {
+			to.Columns[0].AutoIncrement = false
+			to.PrimaryKey = primaryKey(to.Columns[4], to.Columns[0])
+		}},
+		{"drop primary key and auto-increment", func() Table { return aTable(55) }, func(to *Table) {
+			to.Columns[0].AutoIncrement = false
+			to.PrimaryKey = nil
+			to.NextAutoIncrement = 0
+		}},
+		{"change table options", func() Table { return aTable(1) }, func(to *Table) {
+			to.NextAutoIncrement = 1234
+			to.CharSet = "utf8mb4"
+			to.Collation = "utf8mb4_unicode_ci"
+			to.CollationIsDefault = false
+			to.Comment = "it's a table"
+			to.Engine = "MyISAM"
+			to.CreateOptions = "ROW_FORMAT=DYNAMIC"
+		}},
+		{"change foreign keys", func() Table { return foreignKeyTable() }, func(to *Table) {
+			to.ForeignKeys[0].Name = "customer_fk_renamed"
+			to.ForeignKeys[1].DeleteRule = "RESTRICT"
+			to.ForeignKeys = append(to.ForeignKeys, &ForeignKey{
+				Name:                  "warranty_fk",
+				Columns:               to.Columns[0:1],
+				ReferencedTableName:   "other",
+				ReferencedColumnNames: []string{"id"},
+				UpdateRule:            "RESTRICT",
+				DeleteRule:            "CASCADE",
+			})
+		}},
+		{"drop foreign key and its column", func() Table { return foreignKeyTable() }, func(to *Table) {
+			to.Columns = append(to.Columns[0:1], to.Columns[2:]...)
+			to.SecondaryIndexes = to.SecondaryIndexes[1:]
+			to.ForeignKeys = to.ForeignKeys[1:]
+		}},
+		{"composite primary key table", func() Table { return supportedTable() }, func(to *Table) {
+			to.PrimaryKey = primaryKey(to.Columns[1], to.Columns[0])
+			to.Columns[2].TypeInDB = "mediumtext"
+		}},
+	}
+
+	for _, c := range cases {
+		from, to := c.from(), c.from()
+		from.CreateStatement = from.GeneratedCreateStatement(FlavorUnknown)
+		origCreate := from.CreateStatement
+		c.mutate(&to)
+		to.CreateStatement = to.GeneratedCreateStatement(FlavorUnknown)
+		clauses, supported := from.Diff(&to)
+		if !supported {
+			t.Errorf("%s: Diff unexpectedly unsupported", c.name)
+			continue
+		}
+		result, err := from.Apply(FlavorUnknown, clauses...)
+		if err != nil {
+			t.Errorf("%s: Unexpected error from Apply: %s", c.name, err)
+			continue
+		}
+		if result.CreateStatement != to.CreateStatement {
+			t.Errorf("%s: Apply(from, Diff(from, to)) did not yield to.\nExpected:\n%s\nFound:\n%s", c.name, to.CreateStatement, result.CreateStatement)
+		}
+		if from.GeneratedCreateStatement(FlavorUnknown) != origCreate {
+			t.Errorf("%s: Apply unexpectedly modified the original table", c.name)
+		}
+
+		// Applying a reverse diff should yield the original table
+		clauses, _ = to.Diff(&from)
+		if result, err = to.Apply(FlavorUnknown, clauses...); err != nil {
+			t.Errorf("%s: Unexpected error from Apply of reverse diff: %s", c.name, err)
+		} else if result.CreateStatement != origCreate {
+			t.Errorf("%s: Apply(to, Diff(to, from)) did not yield from.\nExpected:\n%s\nFound:\n%s", c.name, origCreate, result.CreateStatement)
+		}
+	}
+}
+
+func TestTableApplyRenameColumn(t *testing.T) {
+	from := aTable(1)
+	result, err := from.Apply(FlavorUnknown, RenameColumn{OldColumn: from.Columns[4], NewName: "social_security_number"})
+	if err != nil {
+		t.Fatalf("Unexpected error from Apply: %s", err)
+	}
+	if result.Columns[4].Name != "social_security_number" || result.SecondaryIndexes[0].Columns[0] != result.Columns[4] {
+		t.Errorf("Column not renamed as expected:\n%s", result.CreateStatement)
+	}
+	if from.Columns[4].Name != "ssn" {
+		t.Error("Apply unexpectedly modified the original table")
+	}
+	if _, err := from.Apply(FlavorUnknown, RenameColumn{OldColumn: from.Columns[4], NewName: "alive"}); err == nil {
+		t.Error("Expected error renaming column to an existing name, but err was nil")
+	}
+}
+
+func TestTableApplyErrors(t *testing.T) {
+	from := aTable(1)
+	missingCol := &Column{Name: "nope", TypeInDB: "int(11)", Default: ColumnDefaultNull}
+	fkTable := foreignKeyTable()
+	cases := []struct {
+		table  *Table
+		clause TableAlterClause
+	}{
+		{&from, AddColumn{Column: from.Columns[1]}},
+		{&from, AddColumn{Column: missingCol, PositionAfter: missingCol}},
+		{&from, DropColumn{Column: missingCol}},
+		{&from, ModifyColumn{OldColumn: missingCol, NewColumn: missingCol}},
+		{&from, ModifyColumn{OldColumn: from.Columns[1], NewColumn: missingCol}},
+		{&from, AddIndex{Index: from.PrimaryKey}},
+		{&from, AddIndex{Index: from.SecondaryIndexes[0]}},
+		{&from, AddIndex{Index: &Index{Name: "idx_nope", Columns: []*Column{missingCol}, SubParts: []uint16{0}}}},
+		{&from, DropIndex{Index: &Index{Name: "idx_nope"}}},
+		{&from, DropForeignKey{ForeignKey: fkTable.ForeignKeys[0]}},
+		{&fkTable, AddForeignKey{ForeignKey: fkTable.ForeignKeys[0]}},
+		{&fkTable, DropColumn{Column: fkTable.Columns[1]}},
+		{&from, RenameColumn{OldColumn: missingCol, NewName: "x"}},
+	}
+	for n, c := range cases {
+		if _, err := c.table.Apply(FlavorUnknown, c.clause); err == nil {
+			t.Errorf("Case %d: Expected error applying %T, but err was nil", n, c.clause)
+		}
+	}
+
+	unsupported := unsupportedTable()
+	if _, err := unsupported.Apply(FlavorUnknown, ChangeComment{NewComment: "hi"}); err == nil {
+		t.Error("Expected error applying clause to unsupported table, but err was nil")
+	}
+}
+
+func TestTableApplyChangeCharSet(t *testing.T) {
+	from := aTable(1)
+	result, err := from.Apply(FlavorMySQL80, ChangeCharSet{CharSet: "utf8mb4"})
+	if err != nil {
+		t.Fatalf("Unexpected error from Apply: %s", err)
+	}
+	if result.Collation != "utf8mb4_0900_ai_ci" || !result.CollationIsDefault {
+		t.Errorf("Unexpected collation after Apply: %s / isDefault=%t", result.Collation, result.CollationIsDefault)
+	}
+	result, err = from.Apply(FlavorMySQL57, ChangeCharSet{CharSet: "latin1", Collation: "latin1_bin"})
+	if err != nil {
+		t.Fatalf("Unexpected error from Apply: %s", err)
+	}
+	if result.Collation != "latin1_bin" || result.CollationIsDefault {
+		t.Errorf("Unexpected collation after Apply: %s / isDefault=%t", result.Collation, result.CollationIsDefault)
+	}
+}
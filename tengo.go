@@ -19,6 +19,8 @@ const (
 	ObjectTypeTable    ObjectType = "table"
 	ObjectTypeProc     ObjectType = "procedure"
 	ObjectTypeFunc     ObjectType = "function"
+	ObjectTypeUser     ObjectType = "user"
+	ObjectTypeRole     ObjectType = "role"
 )
 
 // Caps returns the object type as an uppercase string.
@@ -0,0 +1,10 @@
+SET foreign_key_checks=0;
+SET sql_log_bin=0;
+
+use testing
+
+CREATE TABLE nearly_full (
+	id tinyint unsigned NOT NULL AUTO_INCREMENT,
+	name varchar(30) NOT NULL,
+	PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1 AUTO_INCREMENT=250;
@@ -0,0 +1,204 @@
+package tengo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// defaultThrottlePollInterval is the interval between replica lag checks used
+// if Throttler.PollInterval is not set.
+const defaultThrottlePollInterval = time.Second
+
+// Throttler pauses execution of DDL whenever any of a set of replicas is
+// lagging behind its source by more than a threshold.
+type Throttler struct {
+	Replicas     []*Instance
+	MaxLag       time.Duration // pause while any replica's lag exceeds this
+	Timeout      time.Duration // max time to wait before a single statement; 0 means no limit
+	PollInterval time.Duration // time between lag checks; defaults to 1 second if 0
+
+	// HeartbeatTable, if non-empty, is the "schema.table" name of a heartbeat
+	// table in the format used by pt-heartbeat. Lag is then computed from the
+	// most recent ts value in the table, instead of using Seconds_Behind_Master,
+	// which is unreliable with multi-tier replication or when the replica's IO
+	// thread is behind. The clocks of the source and replicas should be in sync.
+	// By default pt-heartbeat writes ts in the local time of the host it runs
+	// on, which is compared against NOW(6) in the replica session's time zone,
+	// so these time zones must match. If pt-heartbeat is run with --utc, set
+	// HeartbeatUTC to compare against UTC_TIMESTAMP(6) instead.
+	HeartbeatTable string
+	HeartbeatUTC   bool
+
+	// Progress, if non-nil, is called after each check of replica lag.
+	Progress func(ThrottleProgress)
+
+	// lagFunc, if non-nil, overrides replicaLag. This permits testing of the
+	// wait logic without actual replicas.
+	lagFunc func(ctx context.Context, replica *Instance) (time.Duration, bool, error)
+}
+
+// ThrottleProgress describes the state of a Throttler after checking lag.
+type ThrottleProgress struct {
+	Statement int           // 1-based index of the statement about to be run, or 0 if not running statements
+	Total     int           // total number of statements, or 0 if not running statements
+	Replica   *Instance     // replica with the greatest lag
+	Lag       time.Duration // lag of Replica; only meaningful if LagKnown is true
+	LagKnown  bool          // false if lag of Replica could not be determined, e.g. replication stopped
+	Waited    time.Duration // how long the throttler has been waiting so far
+	Throttled bool          // true if the throttler will continue waiting
+}
+
+// NewThrottler returns a Throttler for the supplied replicas and lag threshold,
+// with no timeout.
+func NewThrottler(replicas []*Instance, maxLag time.Duration) *Throttler {
+	return &Throttler{
+		Replicas: replicas,
+		MaxLag:   maxLag,
+	}
+}
+
+// Lag returns the replica with the greatest lag, along with that lag. The bool
+// return value is false if any replica's lag could not be determined, such as
+// due to replication being stopped, in which case the returned replica is the
+// one with unknown lag. An error is returned if any replica could not be
+// queried or is not actually a replica.
+func (th *Throttler) Lag(ctx context.Context) (*Instance, time.Duration, bool, error) {
+	var maxReplica *Instance
+	var maxLag time.Duration
+	lagFunc := th.lagFunc
+	if lagFunc == nil {
+		lagFunc = th.replicaLag
+	}
+	for _, replica := range th.Replicas {
+		lag, known, err := lagFunc(ctx, replica)
+		if err != nil {
+			return nil, 0, false, err
+		} else if !known {
+			return replica, 0, false, nil
+		}
+		if maxReplica == nil || lag > maxLag {
+			maxReplica, maxLag = replica, lag
+		}
+	}
+	return maxReplica, maxLag, true, nil
+}
+
+// replicaLag returns the lag of a single replica, using either the heartbeat
+// table or the replica's status.
+func (th *Throttler) replicaLag(ctx context.Context, replica *Instance) (time.Duration, bool, error) {
+	source, err := replica.ReplicationSourceContext(ctx)
+	if err != nil {
+		return 0, false, err
+	} else if source == nil {
+		return 0, false, fmt.Errorf("Throttler: %s is not a replica", replica)
+	}
+	if th.HeartbeatTable == "" {
+		return source.Lag, source.LagKnown, nil
+	}
+
+	// Even with a heartbeat table, a stopped replica should be considered to
+	// have unknown lag, since the heartbeat will not advance
+	if !source.IORunning || !source.SQLRunning {
+		return 0, false, nil
+	}
+	parts := strings.SplitN(th.HeartbeatTable, ".", 2)
+	if len(parts) != 2 {
+		return 0, false, fmt.Errorf("Throttler: heartbeat table %q must be in format schema.table", th.HeartbeatTable)
+	}
+	db, err := replica.ConnectContext(ctx, "", "")
+	if err != nil {
+		return 0, false, err
+	}
+	now := "NOW(6)"
+	if th.HeartbeatUTC {
+		now = "UTC_TIMESTAMP(6)"
+	}
+	var lagMicros sql.NullInt64
+	query := fmt.Sprintf("SELECT TIMESTAMPDIFF(MICROSECOND, MAX(ts), %s) FROM %s.%s", now, EscapeIdentifier(parts[0]), EscapeIdentifier(parts[1]))
+	if err := db.QueryRowContext(ctx, query).Scan(&lagMicros); err != nil {
+		return 0, false, fmt.Errorf("Throttler: unable to query heartbeat table on %s: %s", replica, err)
+	}
+	if !lagMicros.Valid {
+		return 0, false, nil
+	}
+	if lagMicros.Int64 < 0 { // clock skew between source and replica
+		lagMicros.Int64 = 0
+	}
+	return time.Duration(lagMicros.Int64) * time.Microsecond, true, nil
+}
+
+// Wait blocks until all replicas have lag below th.MaxLag. An error is
+// returned if th.Timeout elapses first, ctx is canceled, or lag cannot be
+// queried.
+func (th *Throttler) Wait(ctx context.Context) error {
+	return th.wait(ctx, 0, 0)
+}
+
+func (th *Throttler) wait(ctx context.Context, statement, total int) error {
+	interval := th.PollInterval
+	if interval <= 0 {
+		interval = defaultThrottlePollInterval
+	}
+	var timeout <-chan time.Time
+	if th.Timeout > 0 {
+		timer := time.NewTimer(th.Timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	start := time.Now()
+	for {
+		replica, lag, known, err := th.Lag(ctx)
+		if err != nil {
+			return err
+		}
+		throttled := !known || lag > th.MaxLag
+		if th.Progress != nil {
+			th.Progress(ThrottleProgress{
+				Statement: statement,
+				Total:     total,
+				Replica:   replica,
+				Lag:       lag,
+				LagKnown:  known,
+				Waited:    time.Since(start),
+				Throttled: throttled,
+			})
+		}
+		if !throttled {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeout:
+			if !known {
+				return fmt.Errorf("Throttler: timed out after %s waiting for replication lag of %s to be known", th.Timeout, replica)
+			}
+			return fmt.Errorf("Throttler: timed out after %s waiting for replication lag of %s to drop below %s (currently %s)", th.Timeout, replica, th.MaxLag, lag)
+		case <-time.After(interval):
+		}
+	}
+}
+
+// ExecStatements runs each of the supplied statements on instance, using
+// schema as the default database, in order. Before each statement, it waits
+// for replica lag to drop below the threshold, as per Wait. Execution stops
+// at the first error.
+func (th *Throttler) ExecStatements(ctx context.Context, instance *Instance, schema string, statements []string) error {
+	db, err := instance.ConnectContext(ctx, schema, "")
+	if err != nil {
+		return err
+	}
+	defer instance.InvalidateSchemaCache(schema)
+	for n, statement := range statements {
+		if err := th.wait(ctx, n+1, len(statements)); err != nil {
+			return err
+		}
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package tengo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestThrottlerNoReplicas(t *testing.T) {
+	th := NewThrottler(nil, time.Second)
+	var calls int
+	th.Progress = func(p ThrottleProgress) {
+		calls++
+		if p.Throttled || p.Replica != nil || !p.LagKnown {
+			t.Errorf("Unexpected progress value %+v", p)
+		}
+	}
+	if err := th.Wait(context.Background()); err != nil {
+		t.Errorf("Unexpected error from Wait: %s", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 call to progress callback, instead found %d", calls)
+	}
+}
+
+// scriptedLag returns a lag function for use in Throttler.lagFunc, which
+// returns the supplied lags for replica r2 in order, repeating the final one
+// indefinitely. Lag for any other replica is always 0. A negative lag is
+// treated as unknown.
+func scriptedLag(r2 *Instance, lags ...time.Duration) func(context.Context, *Instance) (time.Duration, bool, error) {
+	var n int
+	return func(ctx context.Context, replica *Instance) (time.Duration, bool, error) {
+		if replica != r2 {
+			return 0, true, nil
+		}
+		lag := lags[n]
+		if n < len(lags)-1 {
+			n++
+		}
+		return lag, lag >= 0, nil
+	}
+}
+
+func TestThrottlerWait(t *testing.T) {
+	r1, r2 := &Instance{BaseDSN: "r1"}, &Instance{BaseDSN: "r2"}
+	th := NewThrottler([]*Instance{r1, r2}, time.Second)
+	th.PollInterval = time.Millisecond
+	var progress []ThrottleProgress
+	th.Progress = func(p ThrottleProgress) {
+		progress = append(progress, p)
+	}
+
+	// Wait until lag drops below threshold, after an initial unknown lag
+	th.lagFunc = scriptedLag(r2, -1, 5*time.Second, 3*time.Second, 500*time.Millisecond, 10*time.Second)
+	if err := th.Wait(context.Background()); err != nil {
+		t.Fatalf("Unexpected error from Wait: %s", err)
+	}
+	if len(progress) != 4 {
+		t.Fatalf("Expected 4 calls to progress callback, instead found %d: %+v", len(progress), progress)
+	}
+	if p := progress[0]; p.LagKnown || !p.Throttled || p.Replica != r2 {
+		t.Errorf("Unexpected first progress value %+v", p)
+	}
+	if p := progress[2]; !p.LagKnown || !p.Throttled || p.Replica != r2 || p.Lag != 3*time.Second {
+		t.Errorf("Unexpected third progress value %+v", p)
+	}
+	if p := progress[3]; !p.LagKnown || p.Throttled || p.Replica != r2 || p.Lag != 500*time.Millisecond {
+		t.Errorf("Unexpected final progress value %+v", p)
+	}
+
+	// Time out if lag never drops, or is never known
+	th.Progress = nil
+	th.Timeout = 20 * time.Millisecond
+	th.lagFunc = scriptedLag(r2, 5*time.Second)
+	if err := th.Wait(context.Background()); err == nil || !strings.Contains(err.Error(), "timed out") || !strings.Contains(err.Error(), "drop below") {
+		t.Errorf("Expected timeout error from Wait, instead found %v", err)
+	}
+	th.lagFunc = scriptedLag(r2, -1)
+	if err := th.Wait(context.Background()); err == nil || !strings.Contains(err.Error(), "to be known") {
+		t.Errorf("Expected timeout error from Wait, instead found %v", err)
+	}
+
+	// Context cancellation stops waiting, even without a timeout
+	th.Timeout = 0
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := th.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected context deadline error from Wait, instead found %v", err)
+	}
+
+	// Errors from checking lag are returned immediately
+	expectErr := errors.New("lag check failed")
+	th.lagFunc = func(context.Context, *Instance) (time.Duration, bool, error) {
+		return 0, false, expectErr
+	}
+	if err := th.Wait(context.Background()); err != expectErr {
+		t.Errorf("Expected error %v from Wait, instead found %v", expectErr, err)
+	}
+}
+
+func (s TengoIntegrationSuite) TestThrottler(t *testing.T) {
+	// A standalone instance is not a replica, so it cannot be throttled on
+	th := NewThrottler([]*Instance{s.d.Instance}, time.Second)
+	if err := th.Wait(context.Background()); err == nil {
+		t.Error("Expected error from Wait using non-replica, but err was nil")
+	}
+
+	var progress []ThrottleProgress
+	th = NewThrottler(nil, time.Second)
+	th.Progress = func(p ThrottleProgress) {
+		progress = append(progress, p)
+	}
+	statements := []string{
+		"ALTER TABLE actor ADD COLUMN age int unsigned",
+		"ALTER TABLE actor_in_film COMMENT 'hello world'",
+	}
+	if err := th.ExecStatements(context.Background(), s.d.Instance, "testing", statements); err != nil {
+		t.Fatalf("Unexpected error from ExecStatements: %s", err)
+	}
+	if len(progress) != 2 || progress[1].Statement != 2 || progress[1].Total != 2 {
+		t.Errorf("Unexpected progress values: %+v", progress)
+	}
+	schema := s.GetSchema(t, "testing")
+	if schema.Table("actor").ColumnsByName()["age"] == nil || schema.Table("actor_in_film").Comment != "hello world" {
+		t.Error("Statements run by ExecStatements do not appear to have taken effect")
+	}
+
+	// Execution should stop at the first error
+	statements = []string{
+		"ALTER TABLE doesnt_exist ENGINE=InnoDB",
+		"ALTER TABLE actor DROP COLUMN age",
+	}
+	if err := th.ExecStatements(context.Background(), s.d.Instance, "testing", statements); err == nil {
+		t.Error("Expected error from ExecStatements, but err was nil")
+	}
+	if schema := s.GetSchema(t, "testing"); schema.Table("actor").ColumnsByName()["age"] == nil {
+		t.Error("Expected ExecStatements to stop at first error, but later statement was run")
+	}
+}
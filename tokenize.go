@@ -0,0 +1,215 @@
+package tengo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// sqlTokenType enumerates the kinds of tokens returned by tokenizeSQL.
+type sqlTokenType int
+
+const (
+	sqlTokenWord       sqlTokenType = iota // keyword, unquoted identifier, number, or bit/hex literal
+	sqlTokenIdentifier                     // backtick-quoted identifier, with quotes removed
+	sqlTokenString                         // single- or double-quoted string literal, with quotes removed and escapes processed
+	sqlTokenSymbol                         // any other single character, such as parens, commas, or operators
+)
+
+// sqlToken is a single lexical token of a SQL statement.
+type sqlToken struct {
+	typ sqlTokenType
+	val string
+}
+
+// isKeyword returns true if the token is an unquoted word matching kw, case-
+// insensitively.
+func (tok sqlToken) isKeyword(kw string) bool {
+	return tok.typ == sqlTokenWord && strings.EqualFold(tok.val, kw)
+}
+
+// isSymbol returns true if the token is the supplied symbol.
+func (tok sqlToken) isSymbol(sym string) bool {
+	return tok.typ == sqlTokenSymbol && tok.val == sym
+}
+
+func (tok sqlToken) String() string {
+	switch tok.typ {
+	case sqlTokenIdentifier:
+		return EscapeIdentifier(tok.val)
+	case sqlTokenString:
+		return fmt.Sprintf("'%s'", EscapeValueForCreateTable(tok.val))
+	default:
+		return tok.val
+	}
+}
+
+// isSQLWordByte returns true if b may be part of an unquoted word.
+func isSQLWordByte(b byte) bool {
+	return b == '_' || b == '$' || b >= 0x80 || (b >= '0' && b <= '9') || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+// tokenizeSQL splits a SQL statement into tokens. Whitespace and comments are
+// discarded, with the exception of version-gated comments (/*!NNNNN ... */)
+// whose contents are tokenized normally.
+func tokenizeSQL(input string) ([]sqlToken, error) {
+	var tokens []sqlToken
+	var versionCommentDepth int
+	for i := 0; i < len(input); {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '#' || (c == '-' && strings.HasPrefix(input[i:], "--") && (i+2 == len(input) || input[i+2] <= ' ')):
+			if end := strings.IndexByte(input[i:], '\n'); end >= 0 {
+				i += end + 1
+			} else {
+				i = len(input)
+			}
+		case strings.HasPrefix(input[i:], "/*!"):
+			i += 3
+			for i < len(input) && input[i] >= '0' && input[i] <= '9' {
+				i++
+			}
+			versionCommentDepth++
+		case versionCommentDepth > 0 && strings.HasPrefix(input[i:], "*/"):
+			i += 2
+			versionCommentDepth--
+		case strings.HasPrefix(input[i:], "/*"):
+			end := strings.Index(input[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("Unterminated comment in SQL statement")
+			}
+			i += end + 4
+		case c == '`':
+			val, n, err := scanQuoted(input[i:], '`', false)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, sqlToken{typ: sqlTokenIdentifier, val: val})
+			i += n
+		case c == '\'' || c == '"':
+			val, n, err := scanQuoted(input[i:], c, true)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, sqlToken{typ: sqlTokenString, val: val})
+			i += n
+		case isSQLWordByte(c):
+			start := i
+			for i < len(input) && isSQLWordByte(input[i]) {
+				i++
+			}
+			word := input[start:i]
+			if i < len(input) && input[i] == '\'' && (strings.EqualFold(word, "b") || strings.EqualFold(word, "x")) {
+				// bit-value or hex literal, such as b'101' or x'ff'
+				end := strings.IndexByte(input[i+1:], '\'')
+				if end < 0 {
+					return nil, fmt.Errorf("Unterminated %s literal in SQL statement", word)
+				}
+				i += end + 2
+				word = input[start:i]
+			} else if c >= '0' && c <= '9' {
+				// numeric literal with decimal point and/or exponent, which may be
+				// signed, such as 1.5e-3
+				for i < len(input) {
+					if input[i] == '.' || isSQLWordByte(input[i]) {
+						i++
+					} else if (input[i] == '-' || input[i] == '+') && (input[i-1] == 'e' || input[i-1] == 'E') &&
+						i+1 < len(input) && input[i+1] >= '0' && input[i+1] <= '9' && strings.Trim(input[start:i-1], "0123456789.") == "" {
+						i++
+					} else {
+						break
+					}
+				}
+				word = input[start:i]
+			}
+			tokens = append(tokens, sqlToken{typ: sqlTokenWord, val: word})
+		default:
+			tokens = append(tokens, sqlToken{typ: sqlTokenSymbol, val: string(c)})
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+// scanQuoted reads a quoted string or identifier from the beginning of input,
+// which must begin with the quote character. It returns the unquoted value and
+// the number of bytes consumed. A doubled quote character is treated as a
+// literal quote. If backslashEscapes is true, MySQL's backslash escape
+// sequences are also processed.
+func scanQuoted(input string, quote byte, backslashEscapes bool) (string, int, error) {
+	var b strings.Builder
+	for i := 1; i < len(input); i++ {
+		c := input[i]
+		if c == quote {
+			if i+1 < len(input) && input[i+1] == quote {
+				b.WriteByte(quote)
+				i++
+				continue
+			}
+			return b.String(), i + 1, nil
+		}
+		if c == '\\' && backslashEscapes && i+1 < len(input) {
+			i++
+			switch input[i] {
+			case '0':
+				b.WriteByte(0)
+			case 'b':
+				b.WriteByte('\b')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'Z':
+				b.WriteByte(26)
+			case '%', '_':
+				b.WriteByte('\\')
+				b.WriteByte(input[i])
+			default:
+				b.WriteByte(input[i])
+			}
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return "", 0, fmt.Errorf("Unterminated quoted string in SQL statement")
+}
+
+// doubleQuotedStrings returns the values of any double-quoted string literals
+// in input, which are interpreted as identifiers instead if sql_mode includes
+// ANSI_QUOTES. Comments are skipped. An error is returned if input contains an
+// unterminated quote or comment.
+func doubleQuotedStrings(input string) ([]string, error) {
+	var result []string
+	for i := 0; i < len(input); {
+		c := input[i]
+		switch {
+		case c == '#' || (c == '-' && strings.HasPrefix(input[i:], "--") && (i+2 == len(input) || input[i+2] <= ' ')):
+			if end := strings.IndexByte(input[i:], '\n'); end >= 0 {
+				i += end + 1
+			} else {
+				i = len(input)
+			}
+		case strings.HasPrefix(input[i:], "/*") && !strings.HasPrefix(input[i:], "/*!"):
+			end := strings.Index(input[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("Unterminated comment in SQL statement")
+			}
+			i += end + 4
+		case c == '`' || c == '\'' || c == '"':
+			val, n, err := scanQuoted(input[i:], c, c != '`')
+			if err != nil {
+				return nil, err
+			}
+			if c == '"' {
+				result = append(result, val)
+			}
+			i += n
+		default:
+			i++
+		}
+	}
+	return result, nil
+}
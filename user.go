@@ -0,0 +1,592 @@
+package tengo
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// User represents a user account or role on a database server, along with
+// its privileges.
+type User struct {
+	Name                  string
+	Host                  string // empty for MariaDB roles, which have no host
+	IsRole                bool
+	AuthPlugin            string
+	AuthString            string // password hash or other plugin-specific value
+	PasswordExpired       bool
+	PasswordLifetime      int // days until password expires; 0 means never, -1 means server default
+	Locked                bool
+	MaxQueriesPerHour     int
+	MaxUpdatesPerHour     int
+	MaxConnectionsPerHour int
+	MaxUserConnections    int
+	Grants                []*Grant     // privileges, at most one Grant per target, sorted by target
+	Roles                 []*RoleGrant // roles granted to this account, sorted by role
+	DefaultRoles          []string     // roles activated at login, in format returned by User.Account
+}
+
+// Grant represents the privileges that an account holds at a single level:
+// globally, or on a specific schema, table, routine, or proxied account.
+type Grant struct {
+	Target      string   // for example "*.*", "`db`.*", "`db`.`tbl`", or "PROCEDURE `db`.`proc`"
+	Privileges  []string // uppercase and sorted; column privileges are one per column, e.g. "SELECT (`col`)"
+	GrantOption bool
+}
+
+// RoleGrant represents a role granted to an account.
+type RoleGrant struct {
+	Role        string // in format returned by User.Account
+	AdminOption bool
+}
+
+// reservedAccounts are internal accounts created automatically by the server,
+// which are excluded from introspection.
+var reservedAccounts = map[string]bool{
+	"mysql.sys@localhost":        true,
+	"mysql.session@localhost":    true,
+	"mysql.infoschema@localhost": true,
+	"mariadb.sys@localhost":      true,
+}
+
+// Account returns the user's name and host in the format used by SQL
+// statements, for example `name`@`host`. MariaDB roles, which have no host,
+// are returned as just the escaped name.
+func (u *User) Account() string {
+	return formatAccount(u.Name, u.Host)
+}
+
+// ObjectKey returns a value representing the type and account of the user.
+func (u *User) ObjectKey() ObjectKey {
+	key := ObjectKey{Type: ObjectTypeUser, Name: u.Name}
+	if u.IsRole {
+		key.Type = ObjectTypeRole
+	}
+	if u.Host != "" {
+		key.Name = fmt.Sprintf("%s@%s", u.Name, u.Host)
+	}
+	return key
+}
+
+// formatAccount returns an escaped account string for the supplied name and
+// host.
+func formatAccount(name, host string) string {
+	if host == "" {
+		return EscapeIdentifier(name)
+	}
+	return fmt.Sprintf("%s@%s", EscapeIdentifier(name), EscapeIdentifier(host))
+}
+
+// GrantsByTarget returns a mapping of grant target to Grant.
+func (u *User) GrantsByTarget() map[string]*Grant {
+	result := make(map[string]*Grant, len(u.Grants))
+	for _, g := range u.Grants {
+		result[g.Target] = g
+	}
+	return result
+}
+
+// CreateStatements returns the statements necessary to create the user or role,
+// grant its privileges and roles, and set its default roles. The supplied
+// flavor is used to determine which syntax is available for account options;
+// options unsupported by the flavor are omitted.
+func (u *User) CreateStatements(flavor Flavor) []string {
+	acct := u.Account()
+	var stmts []string
+	if u.IsRole {
+		stmts = append(stmts, fmt.Sprintf("CREATE ROLE %s", acct))
+	} else {
+		stmt := fmt.Sprintf("CREATE USER %s%s", acct, u.authClause(flavor))
+		limits := u.limitsClause(nil)
+		if accountOptionsInCreateUser(flavor) {
+			stmt += limits
+			limits = ""
+		}
+		if accountLockSupported(flavor) {
+			if u.PasswordLifetime >= 0 {
+				stmt += passwordLifetimeClause(u.PasswordLifetime)
+			}
+			if u.Locked {
+				stmt += " ACCOUNT LOCK"
+			}
+		}
+		stmts = append(stmts, stmt)
+		if limits != "" { // older flavors only support resource limits via GRANT
+			stmts = append(stmts, fmt.Sprintf("GRANT USAGE ON *.* TO %s%s", acct, limits))
+		}
+		if u.PasswordExpired && accountLockSupported(flavor) {
+			stmts = append(stmts, fmt.Sprintf("ALTER USER %s PASSWORD EXPIRE", acct))
+		}
+	}
+	for _, g := range u.Grants {
+		stmts = append(stmts, g.grantStatement(acct, g.Privileges, g.GrantOption))
+	}
+	for _, rg := range u.Roles {
+		stmts = append(stmts, rg.grantStatement(acct))
+	}
+	if len(u.DefaultRoles) > 0 {
+		stmts = append(stmts, defaultRoleStatement(acct, u.DefaultRoles, flavor))
+	}
+	return stmts
+}
+
+// DropStatement returns a DROP USER or DROP ROLE statement for the user.
+func (u *User) DropStatement() string {
+	if u.IsRole {
+		return fmt.Sprintf("DROP ROLE %s", u.Account())
+	}
+	return fmt.Sprintf("DROP USER %s", u.Account())
+}
+
+// authClause returns an IDENTIFIED clause for the user's auth plugin and auth
+// string, or an empty string if neither is set.
+func (u *User) authClause(flavor Flavor) string {
+	nativePlugin := (u.AuthPlugin == "" || u.AuthPlugin == "mysql_native_password")
+	if u.AuthString == "" && nativePlugin {
+		return ""
+	}
+	// MySQL 5.6 and earlier do not permit supplying a hash with IDENTIFIED WITH
+	if nativePlugin && (u.AuthPlugin == "" || !accountOptionsInCreateUser(flavor)) {
+		return fmt.Sprintf(" IDENTIFIED BY PASSWORD %s", quoteAuthString(u.AuthString))
+	}
+	if u.AuthString == "" {
+		return fmt.Sprintf(" IDENTIFIED WITH %s", u.AuthPlugin)
+	}
+	return fmt.Sprintf(" IDENTIFIED WITH %s AS %s", u.AuthPlugin, quoteAuthString(u.AuthString))
+}
+
+// limitsClause returns a WITH clause for the user's resource limits. If from
+// is nil, only non-zero limits are included; otherwise, only limits differing
+// from those of from are included.
+func (u *User) limitsClause(from *User) string {
+	if from == nil {
+		from = &User{}
+	}
+	limits := []struct {
+		name     string
+		from, to int
+	}{
+		{"MAX_QUERIES_PER_HOUR", from.MaxQueriesPerHour, u.MaxQueriesPerHour},
+		{"MAX_UPDATES_PER_HOUR", from.MaxUpdatesPerHour, u.MaxUpdatesPerHour},
+		{"MAX_CONNECTIONS_PER_HOUR", from.MaxConnectionsPerHour, u.MaxConnectionsPerHour},
+		{"MAX_USER_CONNECTIONS", from.MaxUserConnections, u.MaxUserConnections},
+	}
+	var clauses []string
+	for _, limit := range limits {
+		if limit.from != limit.to {
+			clauses = append(clauses, fmt.Sprintf("%s %d", limit.name, limit.to))
+		}
+	}
+	if len(clauses) == 0 {
+		return ""
+	}
+	return " WITH " + strings.Join(clauses, " ")
+}
+
+// passwordLifetimeClause returns a PASSWORD EXPIRE clause for the supplied
+// lifetime in days, using the same conventions as User.PasswordLifetime.
+func passwordLifetimeClause(days int) string {
+	switch {
+	case days < 0:
+		return " PASSWORD EXPIRE DEFAULT"
+	case days == 0:
+		return " PASSWORD EXPIRE NEVER"
+	default:
+		return fmt.Sprintf(" PASSWORD EXPIRE INTERVAL %d DAY", days)
+	}
+}
+
+// quoteAuthString returns auth as a string literal, or as a hex literal if it
+// contains binary data, as is the case with caching_sha2_password hashes.
+func quoteAuthString(auth string) string {
+	for n := 0; n < len(auth); n++ {
+		if auth[n] < 0x20 || auth[n] >= 0x7f {
+			return "0x" + hex.EncodeToString([]byte(auth))
+		}
+	}
+	return fmt.Sprintf("'%s'", EscapeValueForCreateTable(auth))
+}
+
+// accountOptionsInCreateUser returns true if flavor supports ALTER USER, and
+// resource limits in CREATE USER, as opposed to requiring GRANT for these.
+func accountOptionsInCreateUser(flavor Flavor) bool {
+	return !flavor.Known() || flavor.MySQLishMinVersion(5, 7) || flavor.VendorMinVersion(VendorMariaDB, 10, 2)
+}
+
+// accountLockSupported returns true if flavor supports ACCOUNT LOCK and
+// PASSWORD EXPIRE clauses in CREATE USER and ALTER USER.
+func accountLockSupported(flavor Flavor) bool {
+	return !flavor.Known() || flavor.MySQLishMinVersion(5, 7) || flavor.VendorMinVersion(VendorMariaDB, 10, 4)
+}
+
+// defaultRoleStatement returns a SET DEFAULT ROLE statement for acct. MariaDB
+// only permits a single default role, so only the first is used with that
+// flavor.
+func defaultRoleStatement(acct string, roles []string, flavor Flavor) string {
+	roleList := "NONE"
+	if len(roles) > 0 {
+		roleList = strings.Join(roles, ", ")
+	}
+	if flavor.Vendor == VendorMariaDB {
+		if len(roles) > 0 {
+			roleList = roles[0]
+		}
+		return fmt.Sprintf("SET DEFAULT ROLE %s FOR %s", roleList, acct)
+	}
+	return fmt.Sprintf("SET DEFAULT ROLE %s TO %s", roleList, acct)
+}
+
+// grantStatement returns a GRANT statement giving acct the supplied privileges
+// on g's target.
+func (g *Grant) grantStatement(acct string, privs []string, grantOption bool) string {
+	privList := "USAGE"
+	if len(privs) > 0 {
+		privList = strings.Join(privs, ", ")
+	}
+	var withClause string
+	if grantOption {
+		withClause = " WITH GRANT OPTION"
+	}
+	return fmt.Sprintf("GRANT %s ON %s TO %s%s", privList, g.Target, acct, withClause)
+}
+
+// revokeStatement returns a REVOKE statement removing the supplied privileges
+// on g's target from acct.
+func (g *Grant) revokeStatement(acct string, privs []string) string {
+	return fmt.Sprintf("REVOKE %s ON %s FROM %s", strings.Join(privs, ", "), g.Target, acct)
+}
+
+// grantStatement returns a GRANT statement giving the role to acct.
+func (rg *RoleGrant) grantStatement(acct string) string {
+	var withClause string
+	if rg.AdminOption {
+		withClause = " WITH ADMIN OPTION"
+	}
+	return fmt.Sprintf("GRANT %s TO %s%s", rg.Role, acct, withClause)
+}
+
+// Users returns all user accounts and roles on the instance, excluding
+// internal accounts reserved by the server. The connecting user must have
+// SELECT privileges on the mysql schema. In MySQL 8, roles are identified by
+// being granted to another account or being used as a default role; a role
+// that does not meet either criteria is indistinguishable from a locked user
+// with an expired password, and is treated as a user.
+func (instance *Instance) Users() ([]*User, error) {
+	return instance.UsersContext(context.Background())
+}
+
+// UsersContext is like Users, but the supplied context is used for all
+// queries.
+func (instance *Instance) UsersContext(ctx context.Context) ([]*User, error) {
+	db, err := instance.ConnectContext(ctx, "mysql", "")
+	if err != nil {
+		return nil, err
+	}
+
+	// Column names in mysql.user vary substantially between flavors and
+	// versions, so obtain all columns and use whichever are present
+	rows, err := queryRowMaps(ctx, db, "SELECT * FROM mysql.user")
+	if err != nil {
+		return nil, err
+	}
+	var users []*User
+	usersByAccount := make(map[string]*User, len(rows))
+	for _, row := range rows {
+		u := &User{PasswordLifetime: -1}
+		u.Name, _ = rowMapValue(row, "user")
+		u.Host, _ = rowMapValue(row, "host")
+		if isRole, _ := rowMapValue(row, "is_role"); isRole == "Y" {
+			u.IsRole = true
+		}
+		if reservedAccounts[fmt.Sprintf("%s@%s", u.Name, u.Host)] {
+			continue
+		}
+		u.AuthPlugin, _ = rowMapValue(row, "plugin")
+		if u.AuthString, _ = rowMapValue(row, "authentication_string"); u.AuthString == "" {
+			u.AuthString, _ = rowMapValue(row, "password")
+		}
+		if expired, _ := rowMapValue(row, "password_expired"); expired == "Y" {
+			u.PasswordExpired = true
+		}
+		if lifetime, ok := rowMapValue(row, "password_lifetime"); ok {
+			u.PasswordLifetime, _ = strconv.Atoi(lifetime)
+		}
+		if locked, _ := rowMapValue(row, "account_locked"); locked == "Y" {
+			u.Locked = true
+		}
+		limits := map[string]*int{
+			"max_questions":        &u.MaxQueriesPerHour,
+			"max_updates":          &u.MaxUpdatesPerHour,
+			"max_connections":      &u.MaxConnectionsPerHour,
+			"max_user_connections": &u.MaxUserConnections,
+		}
+		for col, dest := range limits {
+			value, _ := rowMapValue(row, col)
+			*dest, _ = strconv.Atoi(value)
+		}
+		users = append(users, u)
+		usersByAccount[u.Account()] = u
+	}
+
+	if instance.flavorContext(ctx).MySQLishMinVersion(8, 0) {
+		var roleAccounts []struct {
+			User string `db:"user"`
+			Host string `db:"host"`
+		}
+		query := `
+			SELECT from_user AS user, from_host AS host FROM mysql.role_edges
+			UNION
+			SELECT default_role_user AS user, default_role_host AS host FROM mysql.default_roles`
+		if err := db.SelectContext(ctx, &roleAccounts, query); err != nil {
+			return nil, err
+		}
+		for _, ra := range roleAccounts {
+			if u := usersByAccount[formatAccount(ra.User, ra.Host)]; u != nil {
+				u.IsRole = true
+			}
+		}
+		var defaultRoles []struct {
+			User     string `db:"user"`
+			Host     string `db:"host"`
+			RoleUser string `db:"default_role_user"`
+			RoleHost string `db:"default_role_host"`
+		}
+		query = "SELECT user, host, default_role_user, default_role_host FROM mysql.default_roles"
+		if err := db.SelectContext(ctx, &defaultRoles, query); err != nil {
+			return nil, err
+		}
+		for _, dr := range defaultRoles {
+			if u := usersByAccount[formatAccount(dr.User, dr.Host)]; u != nil {
+				u.DefaultRoles = append(u.DefaultRoles, formatAccount(dr.RoleUser, dr.RoleHost))
+			}
+		}
+	}
+
+	for _, u := range users {
+		var lines []string
+		if err := db.SelectContext(ctx, &lines, fmt.Sprintf("SHOW GRANTS FOR %s", u.Account())); err != nil {
+			return nil, err
+		}
+		for _, line := range lines {
+			if err := u.applyShowGrants(line); err != nil {
+				return nil, err
+			}
+		}
+		sort.Strings(u.DefaultRoles)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if users[i].Name == users[j].Name {
+			return users[i].Host < users[j].Host
+		}
+		return users[i].Name < users[j].Name
+	})
+	return users, nil
+}
+
+// applyShowGrants parses a single line of SHOW GRANTS output, adding its
+// privileges, roles, or default roles to the user.
+func (u *User) applyShowGrants(line string) error {
+	tokens, err := tokenizeSQL(line)
+	if err != nil {
+		return err
+	} else if len(tokens) == 0 {
+		return nil
+	}
+	if len(tokens) > 4 && tokens[0].isKeyword("SET") && tokens[1].isKeyword("DEFAULT") && tokens[2].isKeyword("ROLE") {
+		// MariaDB includes its default role in SHOW GRANTS output
+		if role, _, ok := parseAccountTokens(tokens[3:]); ok && !strings.EqualFold(role, "`NONE`") {
+			u.DefaultRoles = []string{role}
+		}
+		return nil
+	}
+	if !tokens[0].isKeyword("GRANT") {
+		return fmt.Errorf("Unable to parse SHOW GRANTS output for %s: %s", u.Account(), line)
+	}
+
+	// Locate the ON and TO keywords; role grants have no ON clause
+	onPos, toPos, depth := -1, -1, 0
+	for n, tok := range tokens {
+		if tok.isSymbol("(") {
+			depth++
+		} else if tok.isSymbol(")") {
+			depth--
+		} else if depth == 0 && tok.isKeyword("ON") && onPos < 0 {
+			onPos = n
+		} else if depth == 0 && tok.isKeyword("TO") {
+			toPos = n
+			break
+		}
+	}
+	if toPos < 0 {
+		return fmt.Errorf("Unable to parse SHOW GRANTS output for %s: %s", u.Account(), line)
+	}
+	var grantOption, adminOption bool
+	for n := toPos + 1; n < len(tokens)-1; n++ {
+		if tokens[n+1].isKeyword("OPTION") {
+			grantOption = grantOption || tokens[n].isKeyword("GRANT")
+			adminOption = adminOption || tokens[n].isKeyword("ADMIN")
+		}
+	}
+
+	if onPos < 0 {
+		for pos := 1; pos < toPos; {
+			role, consumed, ok := parseAccountTokens(tokens[pos:toPos])
+			if !ok {
+				return fmt.Errorf("Unable to parse SHOW GRANTS output for %s: %s", u.Account(), line)
+			}
+			u.Roles = append(u.Roles, &RoleGrant{Role: role, AdminOption: adminOption})
+			pos += consumed + 1 // skip comma
+		}
+		sort.Slice(u.Roles, func(i, j int) bool {
+			return u.Roles[i].Role < u.Roles[j].Role
+		})
+		return nil
+	}
+
+	target := formatGrantTarget(tokens[onPos+1 : toPos])
+	privs := parsePrivileges(tokens[1:onPos])
+	if len(privs) == 0 && !grantOption {
+		return nil
+	}
+	g := u.GrantsByTarget()[target]
+	if g == nil {
+		g = &Grant{Target: target}
+		u.Grants = append(u.Grants, g)
+		sort.Slice(u.Grants, func(i, j int) bool {
+			return u.Grants[i].Target < u.Grants[j].Target
+		})
+	}
+	g.Privileges = append(g.Privileges, privs...)
+	sort.Strings(g.Privileges)
+	g.GrantOption = g.GrantOption || grantOption
+	return nil
+}
+
+// parsePrivileges converts the privilege list tokens of a GRANT statement into
+// a slice of privilege strings. Column-level privileges are split into one
+// string per column. USAGE is omitted, since it indicates no privileges.
+func parsePrivileges(tokens []sqlToken) []string {
+	var privs, words []string
+	flush := func() {
+		if len(words) > 0 {
+			priv := strings.Join(words, " ")
+			if priv == "ALL" {
+				priv = "ALL PRIVILEGES"
+			}
+			if priv != "USAGE" {
+				privs = append(privs, priv)
+			}
+			words = nil
+		}
+	}
+	for n := 0; n < len(tokens); n++ {
+		tok := tokens[n]
+		if tok.isSymbol(",") {
+			flush()
+		} else if tok.isSymbol("(") {
+			priv := strings.Join(words, " ")
+			for n++; n < len(tokens) && !tokens[n].isSymbol(")"); n++ {
+				if !tokens[n].isSymbol(",") {
+					privs = append(privs, fmt.Sprintf("%s (%s)", priv, EscapeIdentifier(tokens[n].val)))
+				}
+			}
+			words = nil
+		} else {
+			words = append(words, strings.ToUpper(tok.val))
+		}
+	}
+	flush()
+	sort.Strings(privs)
+	return privs
+}
+
+// formatGrantTarget converts the tokens between ON and TO of a GRANT statement
+// into a canonical target string, with all identifiers backtick-escaped.
+func formatGrantTarget(tokens []sqlToken) string {
+	var b strings.Builder
+	for n, tok := range tokens {
+		if n == 0 && (tok.isKeyword("PROCEDURE") || tok.isKeyword("FUNCTION")) {
+			b.WriteString(strings.ToUpper(tok.val))
+			b.WriteByte(' ')
+		} else if n == 0 && tok.isKeyword("TABLE") {
+			continue
+		} else if tok.typ == sqlTokenSymbol {
+			b.WriteString(tok.val)
+		} else {
+			b.WriteString(EscapeIdentifier(tok.val))
+		}
+	}
+	return b.String()
+}
+
+// parseAccountTokens parses an account name, with optional host, from the
+// beginning of tokens. It returns the formatted account and the number of
+// tokens consumed.
+func parseAccountTokens(tokens []sqlToken) (string, int, bool) {
+	if len(tokens) == 0 || tokens[0].typ == sqlTokenSymbol {
+		return "", 0, false
+	}
+	if len(tokens) >= 3 && tokens[1].isSymbol("@") && tokens[2].typ != sqlTokenSymbol {
+		return formatAccount(tokens[0].val, tokens[2].val), 3, true
+	}
+	return formatAccount(tokens[0].val, ""), 1, true
+}
+
+// MissingDefiner describes an object whose definer account does not exist on
+// an instance. Such objects can be created by a user with sufficient
+// privileges, but will fail at execution time.
+type MissingDefiner struct {
+	SchemaName string
+	Object     ObjectKey
+	Definer    string // in "user@host" format
+}
+
+// MissingDefiners returns all routines in the supplied schemas whose definer
+// does not exist as an account on the instance. Definers must match an
+// account exactly; host wildcards are not considered. MariaDB roles, which have
+// no host, are matched by name alone. This is useful for
+// validating that schemas introspected from one environment may be safely
+// copied to another, or for deciding whether to use
+// StatementModifiers.RewriteDefiner or StripDefiner. The connecting user must
+// have SELECT privileges on mysql.user.
+func (instance *Instance) MissingDefiners(schemas ...*Schema) ([]MissingDefiner, error) {
+	return instance.MissingDefinersContext(context.Background(), schemas...)
+}
+
+// MissingDefinersContext is like MissingDefiners, but the supplied context is
+// used for all queries.
+func (instance *Instance) MissingDefinersContext(ctx context.Context, schemas ...*Schema) ([]MissingDefiner, error) {
+	db, err := instance.ConnectContext(ctx, "mysql", "")
+	if err != nil {
+		return nil, err
+	}
+	var accounts []string
+	if err := db.SelectContext(ctx, &accounts, "SELECT CONCAT(user, '@', host) FROM mysql.user"); err != nil {
+		return nil, err
+	}
+	exists := make(map[string]bool, len(accounts))
+	for _, acct := range accounts {
+		exists[acct] = true
+		// MariaDB roles have a blank host, and a routine with a role as its
+		// definer reports the definer as just the role name
+		if strings.HasSuffix(acct, "@") {
+			exists[acct[0:len(acct)-1]] = true
+		}
+	}
+	var missing []MissingDefiner
+	for _, s := range schemas {
+		for _, r := range s.Routines {
+			if r.Definer != "" && !exists[r.Definer] {
+				missing = append(missing, MissingDefiner{
+					SchemaName: s.Name,
+					Object:     ObjectKey{Type: r.Type, Name: r.Name},
+					Definer:    r.Definer,
+				})
+			}
+		}
+	}
+	return missing, nil
+}
@@ -0,0 +1,240 @@
+package tengo
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUserApplyShowGrants(t *testing.T) {
+	u := &User{Name: "app", Host: "%"}
+	lines := []string{
+		"GRANT USAGE ON *.* TO `app`@`%`",
+		"GRANT SELECT, INSERT, UPDATE, DELETE, CREATE TEMPORARY TABLES ON `shop`.* TO `app`@`%` WITH GRANT OPTION",
+		"GRANT SELECT (`id`, `name`), UPDATE (`name`) ON `shop`.`customers` TO `app`@`%`",
+		"GRANT EXECUTE, ALTER ROUTINE ON PROCEDURE `shop`.`checkout` TO `app`@`%`",
+		"GRANT `reader`@`%`,`writer`@`%` TO `app`@`%` WITH ADMIN OPTION",
+		"GRANT REPLICATION CLIENT ON *.* TO 'app'@'%' IDENTIFIED BY PASSWORD '*ABCDEF' WITH MAX_QUERIES_PER_HOUR 10",
+	}
+	for _, line := range lines {
+		if err := u.applyShowGrants(line); err != nil {
+			t.Fatalf("Unexpected error from applyShowGrants(%q): %s", line, err)
+		}
+	}
+	expectedGrants := []*Grant{
+		{Target: "*.*", Privileges: []string{"REPLICATION CLIENT"}},
+		{Target: "PROCEDURE `shop`.`checkout`", Privileges: []string{"ALTER ROUTINE", "EXECUTE"}},
+		{Target: "`shop`.*", Privileges: []string{"CREATE TEMPORARY TABLES", "DELETE", "INSERT", "SELECT", "UPDATE"}, GrantOption: true},
+		{Target: "`shop`.`customers`", Privileges: []string{"SELECT (`id`)", "SELECT (`name`)", "UPDATE (`name`)"}},
+	}
+	if !reflect.DeepEqual(u.Grants, expectedGrants) {
+		for _, g := range u.Grants {
+			t.Logf("Found grant %+v", *g)
+		}
+		t.Error("Grants did not match expectations")
+	}
+	expectedRoles := []*RoleGrant{
+		{Role: "`reader`@`%`", AdminOption: true},
+		{Role: "`writer`@`%`", AdminOption: true},
+	}
+	if !reflect.DeepEqual(u.Roles, expectedRoles) {
+		t.Errorf("Roles did not match expectations: %+v", u.Roles)
+	}
+
+	// MariaDB roles and default role
+	u = &User{Name: "app", Host: "localhost"}
+	lines = []string{
+		"GRANT `analyst` TO `app`@`localhost`",
+		"GRANT ALL ON `reports`.* TO 'app'@'localhost'",
+		"SET DEFAULT ROLE `analyst` FOR `app`@`localhost`",
+	}
+	for _, line := range lines {
+		if err := u.applyShowGrants(line); err != nil {
+			t.Fatalf("Unexpected error from applyShowGrants(%q): %s", line, err)
+		}
+	}
+	if len(u.Roles) != 1 || u.Roles[0].Role != "`analyst`" || u.Roles[0].AdminOption {
+		t.Errorf("Roles did not match expectations: %+v", u.Roles)
+	}
+	if len(u.Grants) != 1 || u.Grants[0].Privileges[0] != "ALL PRIVILEGES" {
+		t.Errorf("Grants did not match expectations: %+v", u.Grants)
+	}
+	if len(u.DefaultRoles) != 1 || u.DefaultRoles[0] != "`analyst`" {
+		t.Errorf("Default roles did not match expectations: %v", u.DefaultRoles)
+	}
+
+	badLines := []string{
+		"REVOKE SELECT ON `mysql`.* FROM `app`@`%`",
+		"GRANT SELECT ON *.*",
+	}
+	for _, line := range badLines {
+		if err := u.applyShowGrants(line); err == nil {
+			t.Errorf("Expected error from applyShowGrants(%q), but err was nil", line)
+		}
+	}
+}
+
+func TestUserCreateStatements(t *testing.T) {
+	u := &User{
+		Name:              "app",
+		Host:              "10.%",
+		AuthPlugin:        "mysql_native_password",
+		AuthString:        "*2470C0C06DEE42FD1618BB99005ADCA2EC9D1E19",
+		PasswordExpired:   true,
+		PasswordLifetime:  90,
+		Locked:            true,
+		MaxQueriesPerHour: 100,
+		Grants: []*Grant{
+			{Target: "`shop`.*", Privileges: []string{"INSERT", "SELECT"}, GrantOption: true},
+		},
+		Roles:        []*RoleGrant{{Role: "`reader`@`%`"}},
+		DefaultRoles: []string{"`reader`@`%`"},
+	}
+	expected := []string{
+		"CREATE USER `app`@`10.%` IDENTIFIED WITH mysql_native_password AS '*2470C0C06DEE42FD1618BB99005ADCA2EC9D1E19' WITH MAX_QUERIES_PER_HOUR 100 PASSWORD EXPIRE INTERVAL 90 DAY ACCOUNT LOCK",
+		"ALTER USER `app`@`10.%` PASSWORD EXPIRE",
+		"GRANT INSERT, SELECT ON `shop`.* TO `app`@`10.%` WITH GRANT OPTION",
+		"GRANT `reader`@`%` TO `app`@`10.%`",
+		"SET DEFAULT ROLE `reader`@`%` TO `app`@`10.%`",
+	}
+	if actual := u.CreateStatements(FlavorMySQL80); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Unexpected CreateStatements result for MySQL 8.0:\n%s", strings.Join(actual, "\n"))
+	}
+
+	// Older flavors lack various account options
+	u.Roles, u.DefaultRoles = nil, nil
+	expected = []string{
+		"CREATE USER `app`@`10.%` IDENTIFIED BY PASSWORD '*2470C0C06DEE42FD1618BB99005ADCA2EC9D1E19'",
+		"GRANT USAGE ON *.* TO `app`@`10.%` WITH MAX_QUERIES_PER_HOUR 100",
+		"GRANT INSERT, SELECT ON `shop`.* TO `app`@`10.%` WITH GRANT OPTION",
+	}
+	if actual := u.CreateStatements(FlavorMySQL56); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Unexpected CreateStatements result for MySQL 5.6:\n%s", strings.Join(actual, "\n"))
+	}
+
+	role := &User{Name: "reader", IsRole: true, Grants: []*Grant{{Target: "*.*", Privileges: []string{"SELECT"}}}}
+	expected = []string{
+		"CREATE ROLE `reader`",
+		"GRANT SELECT ON *.* TO `reader`",
+	}
+	if actual := role.CreateStatements(FlavorMariaDB103); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Unexpected CreateStatements result for role:\n%s", strings.Join(actual, "\n"))
+	}
+	if role.DropStatement() != "DROP ROLE `reader`" {
+		t.Errorf("Unexpected DropStatement result for role: %s", role.DropStatement())
+	}
+	if key := role.ObjectKey(); key.Type != ObjectTypeRole || key.Name != "reader" {
+		t.Errorf("Unexpected ObjectKey result for role: %s", key)
+	}
+}
+
+func TestQuoteAuthString(t *testing.T) {
+	cases := map[string]string{
+		"":              "''",
+		"*ABC":          "'*ABC'",
+		"it's":          "'it''s'",
+		"$A$005$\x01\n": "0x24412430303524010a",
+	}
+	for input, expected := range cases {
+		if actual := quoteAuthString(input); actual != expected {
+			t.Errorf("Expected quoteAuthString(%q) to return %s, instead found %s", input, expected, actual)
+		}
+	}
+}
+
+func (s TengoIntegrationSuite) TestInstanceUsers(t *testing.T) {
+	db, err := s.d.Connect("", "")
+	if err != nil {
+		t.Fatalf("Unable to connect to DockerizedInstance: %s", err)
+	}
+	setup := []string{
+		"CREATE USER 'tengo_user'@'%' IDENTIFIED BY 'hunter2' WITH MAX_USER_CONNECTIONS 5",
+		"GRANT SELECT, INSERT ON testing.* TO 'tengo_user'@'%' WITH GRANT OPTION",
+		"GRANT SELECT (id) ON testing.actor TO 'tengo_user'@'%'",
+	}
+	if !accountOptionsInCreateUser(s.d.Flavor()) {
+		setup[0] = "GRANT USAGE ON *.* TO 'tengo_user'@'%' IDENTIFIED BY 'hunter2' WITH MAX_USER_CONNECTIONS 5"
+	}
+	defer db.Exec("DROP USER 'tengo_user'@'%'")
+	for _, stmt := range setup {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Unexpected error from %s: %s", stmt, err)
+		}
+	}
+
+	users, err := s.d.Users()
+	if err != nil {
+		t.Fatalf("Unexpected error from Users: %s", err)
+	}
+	var u *User
+	for _, candidate := range users {
+		if reservedAccounts[candidate.ObjectKey().Name] {
+			t.Errorf("Expected reserved account %s to be excluded", candidate.Account())
+		}
+		if candidate.Name == "tengo_user" {
+			u = candidate
+		}
+	}
+	if u == nil {
+		t.Fatal("Users did not return newly-created user")
+	}
+	if u.MaxUserConnections != 5 || u.AuthString == "" || u.IsRole {
+		t.Errorf("Unexpected user attributes: %+v", *u)
+	}
+	grants := u.GrantsByTarget()
+	if g := grants["`testing`.*"]; g == nil || !g.GrantOption || !reflect.DeepEqual(g.Privileges, []string{"INSERT", "SELECT"}) {
+		t.Errorf("Unexpected schema-level grant: %+v", g)
+	}
+	if g := grants["`testing`.`actor`"]; g == nil || !reflect.DeepEqual(g.Privileges, []string{"SELECT (`id`)"}) {
+		t.Errorf("Unexpected column-level grant: %+v", g)
+	}
+
+	// Diff against a modified copy, then apply and confirm the result matches
+	modified := *u
+	modified.MaxUserConnections = 10
+	modified.Grants = []*Grant{{Target: "`testing`.*", Privileges: []string{"SELECT", "UPDATE"}}}
+	ud := &UserDiff{From: u, To: &modified}
+	if _, err := ud.Statements(StatementModifiers{Flavor: s.d.Flavor()}); !IsForbiddenDiff(err) {
+		t.Errorf("Expected forbidden diff error, instead found %v", err)
+	}
+	stmts, err := ud.Statements(StatementModifiers{Flavor: s.d.Flavor(), AllowUnsafe: true})
+	if err != nil {
+		t.Fatalf("Unexpected error from Statements: %s", err)
+	}
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Unexpected error from %s: %s", stmt, err)
+		}
+	}
+	users, err = s.d.Users()
+	if err != nil {
+		t.Fatalf("Unexpected error from Users: %s", err)
+	}
+	for _, ud := range NewUserDiffs(users, []*User{&modified}) {
+		if ud.DiffType() != DiffTypeDrop {
+			stmt, _ := ud.Statement(StatementModifiers{Flavor: s.d.Flavor()})
+			t.Errorf("Expected no differences after applying diff, instead found:\n%s", stmt)
+		}
+	}
+}
+
+func (s TengoIntegrationSuite) TestInstanceMissingDefiners(t *testing.T) {
+	schema := s.GetSchema(t, "testing")
+	if len(schema.Routines) == 0 {
+		t.Fatal("Expected testing schema to contain routines")
+	}
+	missing, err := s.d.MissingDefiners(schema)
+	if err != nil {
+		t.Fatalf("Unexpected error from MissingDefiners: %s", err)
+	} else if len(missing) != 0 {
+		t.Errorf("Expected no missing definers, instead found %+v", missing)
+	}
+
+	schema.Routines[0].Definer = "doesnt_exist@%"
+	missing, err = s.d.MissingDefiners(schema)
+	if err != nil {
+		t.Fatalf("Unexpected error from MissingDefiners: %s", err)
+	} else if len(missing) != 1 || missing[0].Definer != "doesnt_exist@%" || missing[0].SchemaName != "testing" || missing[0].Object.Name != schema.Routines[0].Name {
+		t.Errorf("Unexpected result from MissingDefiners: %+v", missing)
+	}
+}
@@ -165,3 +165,19 @@ func paramMap(dsn string) map[string]string {
 	}
 	return result
 }
+
+// stringsDifference returns the elements of a that are not present in b,
+// preserving the order of a.
+func stringsDifference(a, b []string) []string {
+	inB := make(map[string]bool, len(b))
+	for _, str := range b {
+		inB[str] = true
+	}
+	var result []string
+	for _, str := range a {
+		if !inB[str] {
+			result = append(result, str)
+		}
+	}
+	return result
+}
@@ -0,0 +1,270 @@
+package tengo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Variables maps lowercased server system variable names to their values, as
+// returned by SHOW VARIABLES. Typed accessor methods are provided, since all
+// values are represented as strings by the server.
+type Variables map[string]string
+
+// Get returns the value of the named variable. The bool return value is false
+// if the variable is not present, for example due to it not existing in the
+// server's flavor or version.
+func (vars Variables) Get(name string) (string, bool) {
+	value, ok := vars[strings.ToLower(name)]
+	return value, ok
+}
+
+// Bool returns the value of the named variable as a boolean. Values ON, YES,
+// TRUE, and 1 are considered true, and values OFF, NO, FALSE, and 0 are
+// considered false, case-insensitively. The second return value is false if
+// the variable is not present or does not have one of these values.
+func (vars Variables) Bool(name string) (value bool, ok bool) {
+	str, ok := vars.Get(name)
+	if !ok {
+		return false, false
+	}
+	switch strings.ToUpper(str) {
+	case "ON", "YES", "TRUE", "1":
+		return true, true
+	case "OFF", "NO", "FALSE", "0":
+		return false, true
+	}
+	return false, false
+}
+
+// Int returns the value of the named variable as an integer. The second
+// return value is false if the variable is not present or is not an integer.
+func (vars Variables) Int(name string) (int64, bool) {
+	str, ok := vars.Get(name)
+	if !ok {
+		return 0, false
+	}
+	value, err := strconv.ParseInt(str, 10, 64)
+	return value, err == nil
+}
+
+// Uint returns the value of the named variable as an unsigned integer, which
+// is necessary for variables whose values may exceed the range of int64. The
+// second return value is false if the variable is not present or is not an
+// unsigned integer.
+func (vars Variables) Uint(name string) (uint64, bool) {
+	str, ok := vars.Get(name)
+	if !ok {
+		return 0, false
+	}
+	value, err := strconv.ParseUint(str, 10, 64)
+	return value, err == nil
+}
+
+// GlobalVariables returns all global system variables of the instance.
+func (instance *Instance) GlobalVariables() (Variables, error) {
+	return instance.GlobalVariablesContext(context.Background())
+}
+
+// GlobalVariablesContext is like GlobalVariables, but the supplied context is
+// used for the query.
+func (instance *Instance) GlobalVariablesContext(ctx context.Context) (Variables, error) {
+	return instance.queryVariables(ctx, "SHOW GLOBAL VARIABLES")
+}
+
+// SessionVariables returns all system variables of a new session on the
+// instance, which reflect the instance's default connection parameters. For
+// example, if the instance was created with a DSN setting sql_mode, the
+// returned sql_mode will be that value rather than the global one.
+func (instance *Instance) SessionVariables() (Variables, error) {
+	return instance.SessionVariablesContext(context.Background())
+}
+
+// SessionVariablesContext is like SessionVariables, but the supplied context
+// is used for the query.
+func (instance *Instance) SessionVariablesContext(ctx context.Context) (Variables, error) {
+	return instance.queryVariables(ctx, "SHOW SESSION VARIABLES")
+}
+
+func (instance *Instance) queryVariables(ctx context.Context, query string) (Variables, error) {
+	db, err := instance.ConnectContext(ctx, "", "")
+	if err != nil {
+		return nil, err
+	}
+	var rawVars []struct {
+		Name  string         `db:"Variable_name"`
+		Value sql.NullString `db:"Value"`
+	}
+	if err := db.SelectContext(ctx, &rawVars, query); err != nil {
+		return nil, err
+	}
+	vars := make(Variables, len(rawVars))
+	for _, rawVar := range rawVars {
+		vars[strings.ToLower(rawVar.Name)] = rawVar.Value.String
+	}
+	return vars, nil
+}
+
+// ddlVariables contains system variables which affect the behavior of DDL, or
+// the way in which schemas are introspected. Differences in these variables
+// between instances may cause the same DDL to yield different results.
+var ddlVariables = map[string]bool{
+	"character_set_server":               true,
+	"collation_server":                   true,
+	"default_collation_for_utf8mb4":      true,
+	"default_storage_engine":             true,
+	"default_tmp_storage_engine":         true,
+	"explicit_defaults_for_timestamp":    true,
+	"foreign_key_checks":                 true,
+	"innodb_default_row_format":          true,
+	"innodb_file_format":                 true,
+	"innodb_file_per_table":              true,
+	"innodb_large_prefix":                true,
+	"innodb_page_size":                   true,
+	"innodb_strict_mode":                 true,
+	"lower_case_table_names":             true,
+	"show_create_table_verbosity":        true,
+	"sql_generate_invisible_primary_key": true,
+	"sql_mode":                           true,
+	"sql_require_primary_key":            true,
+}
+
+// IsDDLVariable returns true if the named system variable affects the
+// behavior of DDL, such as default collations, row formats, or table name
+// case-sensitivity.
+func IsDDLVariable(name string) bool {
+	return ddlVariables[strings.ToLower(name)]
+}
+
+// instanceSpecificVariables contains system variables whose values inherently
+// differ between instances, such as identifiers, paths, and counters. These
+// are skipped by CompareVariables unless explicitly requested.
+var instanceSpecificVariables = map[string]bool{
+	"gtid_executed":      true,
+	"gtid_purged":        true,
+	"hostname":           true,
+	"log_bin_basename":   true,
+	"log_bin_index":      true,
+	"log_error":          true,
+	"pid_file":           true,
+	"port":               true,
+	"pseudo_thread_id":   true,
+	"rand_seed1":         true,
+	"rand_seed2":         true,
+	"relay_log_basename": true,
+	"relay_log_index":    true,
+	"report_host":        true,
+	"report_port":        true,
+	"server_id":          true,
+	"server_uuid":        true,
+	"socket":             true,
+	"timestamp":          true,
+	"warning_count":      true,
+	"error_count":        true,
+	"last_insert_id":     true,
+	"identity":           true,
+	"insert_id":          true,
+	"gtid_next":          true,
+	"gtid_owned":         true,
+}
+
+// VariableCompareOptions controls which variables are compared by
+// CompareVariables.
+type VariableCompareOptions struct {
+	Include                 *regexp.Regexp // if non-nil, only compare variables whose names match this
+	Exclude                 *regexp.Regexp // if non-nil, skip variables whose names match this
+	OnlyDDL                 bool           // if true, only compare variables for which IsDDLVariable returns true
+	IgnoreMissing           bool           // if true, skip variables only present on one side, e.g. due to version differences
+	IncludeInstanceSpecific bool           // if true, compare variables which inherently differ between instances, such as server_uuid
+}
+
+// VariableDiff represents a difference in the value of a single system
+// variable between two sets of variables.
+type VariableDiff struct {
+	Name       string
+	From       string
+	To         string
+	InFrom     bool // false if the variable is not present on the From side
+	InTo       bool // false if the variable is not present on the To side
+	AffectsDDL bool // true if the variable affects DDL behavior; see IsDDLVariable
+}
+
+func (vd VariableDiff) String() string {
+	fromValue, toValue := "'"+vd.From+"'", "'"+vd.To+"'"
+	if !vd.InFrom {
+		fromValue = "(not present)"
+	}
+	if !vd.InTo {
+		toValue = "(not present)"
+	}
+	var suffix string
+	if vd.AffectsDDL {
+		suffix = " [affects DDL]"
+	}
+	return fmt.Sprintf("%s: %s vs %s%s", vd.Name, fromValue, toValue, suffix)
+}
+
+// CompareVariables returns the differences between two sets of variables,
+// sorted by variable name, subject to the filters in opts.
+func CompareVariables(from, to Variables, opts VariableCompareOptions) []VariableDiff {
+	names := make(map[string]bool, len(from))
+	for name := range from {
+		names[name] = true
+	}
+	for name := range to {
+		names[name] = true
+	}
+	var diffs []VariableDiff
+	for name := range names {
+		if (opts.Include != nil && !opts.Include.MatchString(name)) || (opts.Exclude != nil && opts.Exclude.MatchString(name)) {
+			continue
+		} else if opts.OnlyDDL && !ddlVariables[name] {
+			continue
+		} else if !opts.IncludeInstanceSpecific && instanceSpecificVariables[name] {
+			continue
+		}
+		fromValue, inFrom := from[name]
+		toValue, inTo := to[name]
+		if inFrom && inTo && fromValue == toValue {
+			continue
+		} else if (!inFrom || !inTo) && opts.IgnoreMissing {
+			continue
+		}
+		diffs = append(diffs, VariableDiff{
+			Name:       name,
+			From:       fromValue,
+			To:         toValue,
+			InFrom:     inFrom,
+			InTo:       inTo,
+			AffectsDDL: ddlVariables[name],
+		})
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Name < diffs[j].Name
+	})
+	return diffs
+}
+
+// CompareGlobalVariables returns the differences between the global system
+// variables of instance and other, subject to the filters in opts.
+func (instance *Instance) CompareGlobalVariables(other *Instance, opts VariableCompareOptions) ([]VariableDiff, error) {
+	return instance.CompareGlobalVariablesContext(context.Background(), other, opts)
+}
+
+// CompareGlobalVariablesContext is like CompareGlobalVariables, but the
+// supplied context is used for all queries.
+func (instance *Instance) CompareGlobalVariablesContext(ctx context.Context, other *Instance, opts VariableCompareOptions) ([]VariableDiff, error) {
+	from, err := instance.GlobalVariablesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	to, err := other.GlobalVariablesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return CompareVariables(from, to, opts), nil
+}
@@ -0,0 +1,119 @@
+package tengo
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestVariablesAccessors(t *testing.T) {
+	vars := Variables{
+		"innodb_large_prefix":    "ON",
+		"lower_case_table_names": "2",
+		"max_binlog_cache_size":  "18446744073709551615",
+		"sql_mode":               "STRICT_TRANS_TABLES",
+	}
+	if value, ok := vars.Get("SQL_MODE"); !ok || value != "STRICT_TRANS_TABLES" {
+		t.Errorf("Unexpected result from Get: %q, %t", value, ok)
+	}
+	if value, ok := vars.Bool("innodb_large_prefix"); !ok || !value {
+		t.Errorf("Unexpected result from Bool: %t, %t", value, ok)
+	}
+	if _, ok := vars.Bool("sql_mode"); ok {
+		t.Error("Expected Bool to fail for non-boolean value")
+	}
+	if value, ok := vars.Int("lower_case_table_names"); !ok || value != 2 {
+		t.Errorf("Unexpected result from Int: %d, %t", value, ok)
+	}
+	if _, ok := vars.Int("max_binlog_cache_size"); ok {
+		t.Error("Expected Int to fail for value exceeding int64 range")
+	}
+	if value, ok := vars.Uint("max_binlog_cache_size"); !ok || value != 18446744073709551615 {
+		t.Errorf("Unexpected result from Uint: %d, %t", value, ok)
+	}
+	if _, ok := vars.Int("nonexistent"); ok {
+		t.Error("Expected Int to fail for nonexistent variable")
+	}
+}
+
+func TestCompareVariables(t *testing.T) {
+	from := Variables{
+		"innodb_large_prefix":    "ON",
+		"innodb_file_format":     "Barracuda",
+		"lower_case_table_names": "0",
+		"server_uuid":            "abc",
+		"wait_timeout":           "28800",
+		"max_connections":        "151",
+	}
+	to := Variables{
+		"innodb_file_format":            "Barracuda",
+		"lower_case_table_names":        "1",
+		"server_uuid":                   "def",
+		"wait_timeout":                  "600",
+		"max_connections":               "151",
+		"default_collation_for_utf8mb4": "utf8mb4_0900_ai_ci",
+	}
+
+	diffs := CompareVariables(from, to, VariableCompareOptions{})
+	expected := []VariableDiff{
+		{Name: "default_collation_for_utf8mb4", To: "utf8mb4_0900_ai_ci", InTo: true, AffectsDDL: true},
+		{Name: "innodb_large_prefix", From: "ON", InFrom: true, AffectsDDL: true},
+		{Name: "lower_case_table_names", From: "0", To: "1", InFrom: true, InTo: true, AffectsDDL: true},
+		{Name: "wait_timeout", From: "28800", To: "600", InFrom: true, InTo: true},
+	}
+	if !reflect.DeepEqual(diffs, expected) {
+		t.Errorf("Unexpected result from CompareVariables: %+v", diffs)
+	}
+	if str := diffs[1].String(); str != "innodb_large_prefix: 'ON' vs (not present) [affects DDL]" {
+		t.Errorf("Unexpected String result: %s", str)
+	}
+
+	cases := []struct {
+		opts     VariableCompareOptions
+		expected []string
+	}{
+		{VariableCompareOptions{OnlyDDL: true, IgnoreMissing: true}, []string{"lower_case_table_names"}},
+		{VariableCompareOptions{IncludeInstanceSpecific: true, IgnoreMissing: true}, []string{"lower_case_table_names", "server_uuid", "wait_timeout"}},
+		{VariableCompareOptions{Include: regexp.MustCompile("^innodb_")}, []string{"innodb_large_prefix"}},
+		{VariableCompareOptions{Exclude: regexp.MustCompile("_timeout$|^default_")}, []string{"innodb_large_prefix", "lower_case_table_names"}},
+	}
+	for _, c := range cases {
+		var actual []string
+		for _, diff := range CompareVariables(from, to, c.opts) {
+			actual = append(actual, diff.Name)
+		}
+		if !reflect.DeepEqual(actual, c.expected) {
+			t.Errorf("CompareVariables with options %+v: expected %v, found %v", c.opts, c.expected, actual)
+		}
+	}
+}
+
+func (s TengoIntegrationSuite) TestInstanceVariables(t *testing.T) {
+	global, err := s.d.GlobalVariables()
+	if err != nil {
+		t.Fatalf("Unexpected error from GlobalVariables: %s", err)
+	}
+	session, err := s.d.SessionVariables()
+	if err != nil {
+		t.Fatalf("Unexpected error from SessionVariables: %s", err)
+	}
+	for _, vars := range []Variables{global, session} {
+		if _, ok := vars.Int("lower_case_table_names"); !ok {
+			t.Error("Expected lower_case_table_names to be present and an integer")
+		}
+		if _, ok := vars.Get("collation_server"); !ok {
+			t.Error("Expected collation_server to be present")
+		}
+	}
+	if _, ok := global.Bool("innodb_file_per_table"); !ok {
+		t.Error("Expected innodb_file_per_table to be present and a boolean")
+	}
+
+	diffs, err := s.d.CompareGlobalVariables(s.d.Instance, VariableCompareOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error from CompareGlobalVariables: %s", err)
+	}
+	for _, diff := range diffs {
+		t.Errorf("Unexpected difference when comparing instance to itself: %s", diff)
+	}
+}